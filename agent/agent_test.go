@@ -0,0 +1,215 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore serves ListRaw from a fixed map and counts the calls
+type countingStore struct {
+	store.NullStore
+	services map[string][]store.RawSecret
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingStore) ListRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[service]++
+	rawSecrets, ok := s.services[service]
+	if !ok {
+		return nil, store.ErrSecretNotFound
+	}
+	return rawSecrets, nil
+}
+
+func startTestServer(t *testing.T, s store.Store, ttl time.Duration, rules []Rule) *Client {
+	t.Helper()
+	// keep the socket path short, as they are limited to around 100 bytes
+	dir, err := os.MkdirTemp("", "chamber")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- NewServer(s, ttl, rules).Serve(ctx, l)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return NewClient(socket)
+}
+
+func TestAgentListRaw(t *testing.T) {
+	s := &countingStore{
+		services: map[string][]store.RawSecret{
+			"app/web":        {{Key: "/app/web/db_host", Value: "db"}},
+			"app/web:stable": {{Key: "/app/web/db_host", Value: "stable-db"}},
+		},
+		calls: map[string]int{},
+	}
+	client := startTestServer(t, s, time.Minute, nil)
+	ctx := context.Background()
+
+	rawSecrets, err := client.ListRaw(ctx, "app/web")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.RawSecret{{Key: "/app/web/db_host", Value: "db"}}, rawSecrets)
+
+	rawSecrets, err = client.ListRaw(ctx, "app/web:stable")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.RawSecret{{Key: "/app/web/db_host", Value: "stable-db"}}, rawSecrets)
+
+	// served from the cache the second time
+	_, err = client.ListRaw(ctx, "app/web")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.calls["app/web"])
+
+	_, err = client.ListRaw(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrSecretNotFound)
+}
+
+func TestAgentTTL(t *testing.T) {
+	s := &countingStore{
+		services: map[string][]store.RawSecret{"app": {{Key: "/app/key", Value: "value"}}},
+		calls:    map[string]int{},
+	}
+	client := startTestServer(t, s, time.Nanosecond, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ListRaw(context.Background(), "app")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, s.calls["app"])
+}
+
+func TestAgentRules(t *testing.T) {
+	s := &countingStore{
+		services: map[string][]store.RawSecret{
+			"app":   {{Key: "/app/key", Value: "value"}},
+			"other": {{Key: "/other/key", Value: "value"}},
+		},
+		calls: map[string]int{},
+	}
+	uid := uint32(os.Getuid())
+	client := startTestServer(t, s, time.Minute, []Rule{{UID: &uid, Service: "app"}})
+
+	_, err := client.ListRaw(context.Background(), "app")
+	assert.NoError(t, err)
+
+	_, err = client.ListRaw(context.Background(), "other")
+	assert.ErrorContains(t, err, "not allowed to read service other")
+	assert.Equal(t, 0, s.calls["other"])
+}
+
+func TestParseRule(t *testing.T) {
+	uid := uint32(1000)
+	gid := uint32(20)
+	cases := []struct {
+		in       string
+		expected Rule
+		wantErr  bool
+	}{
+		{in: "*:*", expected: Rule{Service: "*"}},
+		{in: "uid=1000:app/*", expected: Rule{UID: &uid, Service: "app/*"}},
+		{in: "gid=20:global", expected: Rule{GID: &gid, Service: "global"}},
+		{in: "uid=1000", wantErr: true},
+		{in: "pid=1:app", wantErr: true},
+		{in: "uid=me:app", wantErr: true},
+		{in: "*:[app", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			rule, err := ParseRule(tc.in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, rule)
+		})
+	}
+}
+
+func TestRuleAllows(t *testing.T) {
+	uid := uint32(1000)
+	rule := Rule{UID: &uid, Service: "app/*"}
+
+	assert.True(t, rule.Allows(Peer{UID: 1000}, "app/web"))
+	assert.True(t, rule.Allows(Peer{UID: 1000}, "app/web:stable"))
+	assert.False(t, rule.Allows(Peer{UID: 1001}, "app/web"))
+	assert.False(t, rule.Allows(Peer{UID: 1000}, "app/web/nested"))
+	assert.True(t, Rule{Service: "*"}.Allows(Peer{UID: 1}, "app/web/nested"))
+}
+
+func TestAgentServices(t *testing.T) {
+	s := &countingStore{
+		services: map[string][]store.RawSecret{
+			"app":   {{Key: "/app/key", Value: "value"}},
+			"other": {{Key: "/other/key", Value: "value"}},
+		},
+		calls: map[string]int{},
+	}
+	uid := uint32(os.Getuid())
+	client := startTestServer(t, s, time.Minute, []Rule{{UID: &uid, Service: "app*"}})
+
+	// services are normalized before the rules are checked and cached
+	for _, service := range []string{"app", "APP"} {
+		rawSecrets, err := client.ListRaw(context.Background(), service)
+		require.NoError(t, err)
+		assert.Equal(t, []store.RawSecret{{Key: "/app/key", Value: "value"}}, rawSecrets)
+	}
+	assert.Equal(t, 1, s.calls["app"])
+	assert.Equal(t, 0, s.calls["APP"])
+
+	for _, service := range []string{"app$", "app:a:b"} {
+		_, err := client.ListRaw(context.Background(), service)
+		assert.ErrorContains(t, err, "invalid service", service)
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	for _, service := range []string{"app", "app/web", "app/web:prod", "my-app.v2/web_1"} {
+		assert.NoError(t, validateService(service), service)
+	}
+	for _, service := range []string{"", "/app", "app/", "app$", "app:a:b", "app/../other", "app/.", "..:prod"} {
+		assert.Error(t, validateService(service), service)
+	}
+}
+
+func TestAgentCacheBound(t *testing.T) {
+	s := &countingStore{services: map[string][]store.RawSecret{}, calls: map[string]int{}}
+	for i := 0; i <= maxCachedServices; i++ {
+		s.services[fmt.Sprintf("app%d", i)] = []store.RawSecret{}
+	}
+	server := NewServer(s, time.Minute, nil)
+
+	for i := 0; i <= maxCachedServices; i++ {
+		_, err := server.listRaw(context.Background(), fmt.Sprintf("app%d", i))
+		require.NoError(t, err)
+	}
+	assert.Len(t, server.cache, maxCachedServices)
+	assert.NotContains(t, server.cache, "app0")
+	assert.Contains(t, server.cache, fmt.Sprintf("app%d", maxCachedServices))
+}
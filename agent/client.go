@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/segmentio/chamber/v3/store"
+)
+
+var _ store.Store = &Client{}
+
+// errReadOnly is returned by every Client method other than ListRaw.
+var errReadOnly = fmt.Errorf("%w for chamber agent; only listing raw secrets is supported", store.ErrNotImplemented)
+
+// Client is a store which reads secrets from a chamber agent. It only supports
+// ListRaw, which is all that's needed to load services into an environment.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient creates a new Client connecting to the agent listening on socket.
+// An empty socket means $CHAMBER_AGENT_SOCKET, or else DefaultSocket.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		if fromEnv, ok := os.LookupEnv(SocketEnvVar); ok {
+			socket = fromEnv
+		} else {
+			socket = DefaultSocket
+		}
+	}
+
+	dialer := &net.Dialer{}
+	return &Client{
+		socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// ListRaw lists all secrets keys and values for a given service, as cached by
+// the agent.
+func (c *Client) ListRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	// the host is ignored, since we always dial the socket
+	u := "http://chamber-agent" + secretsPath + (&url.URL{Path: service}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach chamber agent at %s: %w", c.socket, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, store.ErrSecretNotFound
+	default:
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return nil, fmt.Errorf("chamber agent returned %s", resp.Status)
+		}
+		return nil, fmt.Errorf("chamber agent: %s", errResp.Error)
+	}
+
+	var listResp listResponse
+	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, fmt.Errorf("failed to decode chamber agent response: %w", err)
+	}
+	rawSecrets := make([]store.RawSecret, len(listResp.Secrets))
+	for i, s := range listResp.Secrets {
+		rawSecrets[i] = store.RawSecret{Key: s.Key, Value: s.Value}
+	}
+	return rawSecrets, nil
+}
+
+// Supports reports that the agent supports labels, which it passes on to its
+// store, and no other optional features.
+func (c *Client) Supports(capability store.Capability) bool {
+	return capability == store.CapabilityLabels
+}
+
+func (c *Client) Config(ctx context.Context) (store.StoreConfig, error) {
+	return store.StoreConfig{
+		Version: store.LatestStoreConfigVersion,
+	}, nil
+}
+
+func (c *Client) SetConfig(ctx context.Context, config store.StoreConfig) error {
+	return errReadOnly
+}
+
+func (c *Client) Write(ctx context.Context, id store.SecretId, value string) error {
+	return errReadOnly
+}
+
+func (c *Client) WriteWithTags(ctx context.Context, id store.SecretId, value string, tags map[string]string) error {
+	return errReadOnly
+}
+
+func (c *Client) Read(ctx context.Context, id store.SecretId, version int) (store.Secret, error) {
+	return store.Secret{}, errReadOnly
+}
+
+func (c *Client) WriteTags(ctx context.Context, id store.SecretId, tags map[string]string, deleteOtherTags bool) error {
+	return errReadOnly
+}
+
+func (c *Client) ReadTags(ctx context.Context, id store.SecretId) (map[string]string, error) {
+	return nil, errReadOnly
+}
+
+func (c *Client) List(ctx context.Context, service string, includeValues bool) ([]store.Secret, error) {
+	return nil, errReadOnly
+}
+
+func (c *Client) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
+	return nil, errReadOnly
+}
+
+func (c *Client) History(ctx context.Context, id store.SecretId) ([]store.ChangeEvent, error) {
+	return nil, errReadOnly
+}
+
+func (c *Client) Delete(ctx context.Context, id store.SecretId) error {
+	return errReadOnly
+}
+
+func (c *Client) DeleteTags(ctx context.Context, id store.SecretId, tagKeys []string) error {
+	return errReadOnly
+}
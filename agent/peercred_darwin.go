@@ -0,0 +1,36 @@
+//go:build darwin
+
+package agent
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the credentials of the process on the other end of
+// conn, using LOCAL_PEERCRED. The peer's PID isn't available this way.
+func peerCredentials(conn *net.UnixConn) (Peer, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return Peer{}, err
+	}
+
+	var xucred *unix.Xucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		xucred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	})
+	if err != nil {
+		return Peer{}, err
+	}
+	if credErr != nil {
+		return Peer{}, credErr
+	}
+
+	peer := Peer{UID: xucred.Uid}
+	if xucred.Ngroups > 0 {
+		peer.GID = xucred.Groups[0]
+	}
+	return peer, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package agent
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredentials is unsupported on this platform, so every request is denied.
+func peerCredentials(conn *net.UnixConn) (Peer, error) {
+	return Peer{}, errors.New("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,304 @@
+// Package agent implements a long-running daemon that serves secrets from a
+// store over a local Unix socket, and a client for it. The agent authenticates
+// to the backend once and caches each service for a while, so that many short
+// lived processes on a host don't each have to call AWS.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/utils"
+)
+
+const (
+	// DefaultSocket is the default path of the agent's Unix socket.
+	DefaultSocket = "/run/chamber.sock"
+
+	// SocketEnvVar is the name of the environment variable overriding the
+	// socket a client connects to.
+	SocketEnvVar = "CHAMBER_AGENT_SOCKET"
+
+	// DefaultTTL is the default time a service stays cached.
+	DefaultTTL = 5 * time.Minute
+
+	secretsPath = "/v1/secrets/"
+
+	// maxCachedServices is the most services cached at once, so that peers
+	// can't grow the cache without bound by asking for many services
+	maxCachedServices = 1024
+)
+
+// validServiceFormat matches a service, with an optional label, as accepted
+// by the CLI
+var validServiceFormat = regexp.MustCompile(`^[\w\-\.]+(\/[\w\-\.]+)*(\:[\w\-\.]+)?$`)
+
+// validateService returns an error if service isn't a valid service name.
+// `.` and `..` are rejected as path segments, so that a service can't match a
+// rule's pattern while naming another.
+func validateService(service string) error {
+	if !validServiceFormat.MatchString(service) {
+		return fmt.Errorf("invalid service %s: must match %s", service, validServiceFormat)
+	}
+	name, _, _ := strings.Cut(service, ":")
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("invalid service %s: must not contain . or .. segments", service)
+		}
+	}
+	return nil
+}
+
+// Peer holds the credentials of a process connected to the agent.
+type Peer struct {
+	UID uint32
+	GID uint32
+	PID int32
+}
+
+// Rule allows matching peers to read matching services.
+type Rule struct {
+	// UID, if set, must equal the peer's user ID.
+	UID *uint32
+	// GID, if set, must equal the peer's primary group ID.
+	GID *uint32
+	// Service is a path.Match pattern for the service, without any label. The
+	// pattern `*` matches every service, including nested ones.
+	Service string
+}
+
+// ParseRule parses a rule of the form `<who>:<service pattern>`, where who is
+// `uid=N`, `gid=N` or `*` for anyone.
+func ParseRule(s string) (Rule, error) {
+	who, pattern, found := strings.Cut(s, ":")
+	if !found || pattern == "" {
+		return Rule{}, fmt.Errorf("rule `%s` must be in the form <who>:<service pattern>", s)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Rule{}, fmt.Errorf("invalid service pattern in rule `%s`: %w", s, err)
+	}
+
+	rule := Rule{Service: pattern}
+	if who == "*" {
+		return rule, nil
+	}
+	kind, idStr, found := strings.Cut(who, "=")
+	if !found {
+		return Rule{}, fmt.Errorf("rule `%s` must start with uid=N, gid=N or *", s)
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid ID in rule `%s`: %w", s, err)
+	}
+	id32 := uint32(id)
+	switch kind {
+	case "uid":
+		rule.UID = &id32
+	case "gid":
+		rule.GID = &id32
+	default:
+		return Rule{}, fmt.Errorf("rule `%s` must start with uid=N, gid=N or *", s)
+	}
+	return rule, nil
+}
+
+// Allows returns whether r allows peer to read service.
+func (r Rule) Allows(peer Peer, service string) bool {
+	if r.UID != nil && *r.UID != peer.UID {
+		return false
+	}
+	if r.GID != nil && *r.GID != peer.GID {
+		return false
+	}
+	if r.Service == "*" {
+		return true
+	}
+	service, _, _ = strings.Cut(service, ":")
+	ok, _ := path.Match(r.Service, service)
+	return ok
+}
+
+// cacheEntry is the cached contents of a service
+type cacheEntry struct {
+	secrets []store.RawSecret
+	expires time.Time
+}
+
+// Server serves secrets from a store over HTTP, to peers allowed by its rules.
+type Server struct {
+	store store.Store
+	ttl   time.Duration
+	rules []Rule
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewServer creates a new Server. If no rules are given, only processes
+// running as the same user as the agent are allowed, to every service.
+func NewServer(s store.Store, ttl time.Duration, rules []Rule) *Server {
+	if len(rules) == 0 {
+		uid := uint32(os.Getuid())
+		rules = []Rule{{UID: &uid, Service: "*"}}
+	}
+	return &Server{
+		store: s,
+		ttl:   ttl,
+		rules: rules,
+		cache: map[string]cacheEntry{},
+	}
+}
+
+type peerContextKey struct{}
+
+// Serve accepts connections on l until ctx is done. l must be a Unix socket
+// listener, so that peer credentials can be checked.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+secretsPath+"{service...}", s.handleSecrets)
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			uc, ok := c.(*net.UnixConn)
+			if !ok {
+				return ctx
+			}
+			peer, err := peerCredentials(uc)
+			if err != nil {
+				slog.Debug(fmt.Sprintf("agent: failed to read peer credentials: %s", err))
+				return ctx
+			}
+			return context.WithValue(ctx, peerContextKey{}, peer)
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
+	service := utils.NormalizeService(r.PathValue("service"))
+	if err := validateService(service); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	peer, ok := r.Context().Value(peerContextKey{}).(Peer)
+	if !ok || !s.allowed(peer, service) {
+		slog.Debug(fmt.Sprintf("agent: denied service %s to uid %d gid %d pid %d", service, peer.UID, peer.GID, peer.PID))
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: fmt.Sprintf("not allowed to read service %s", service)})
+		return
+	}
+
+	rawSecrets, err := s.listRaw(r.Context(), service)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrSecretNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	resp := listResponse{Secrets: make([]secret, len(rawSecrets))}
+	for i, rawSecret := range rawSecrets {
+		resp.Secrets[i] = secret{Key: rawSecret.Key, Value: rawSecret.Value}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) allowed(peer Peer, service string) bool {
+	for _, rule := range s.rules {
+		if rule.Allows(peer, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// listRaw returns the secrets for service, from the cache if they're fresh
+// enough. Errors are never cached.
+func (s *Server) listRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[service]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.secrets, nil
+	}
+
+	rawSecrets, err := s.store.ListRaw(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[service]; !ok && len(s.cache) >= maxCachedServices {
+		s.evict()
+	}
+	s.cache[service] = cacheEntry{secrets: rawSecrets, expires: time.Now().Add(s.ttl)}
+	return rawSecrets, nil
+}
+
+// evict makes room in the cache, by dropping every expired entry or, if none
+// have expired, the one expiring soonest. s.mu must be held.
+func (s *Server) evict() {
+	now := time.Now()
+	var oldest string
+	var oldestExpires time.Time
+	for service, entry := range s.cache {
+		if !now.Before(entry.expires) {
+			delete(s.cache, service)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(oldestExpires) {
+			oldest, oldestExpires = service, entry.expires
+		}
+	}
+	if len(s.cache) >= maxCachedServices {
+		delete(s.cache, oldest)
+	}
+}
+
+type listResponse struct {
+	Secrets []secret `json:"secrets"`
+}
+
+type secret struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,367 @@
+// Package client is the supported way to use chamber from Go. It sets up a
+// secret store the same way the chamber CLI does, from explicit options rather
+// than flags, and loads services into environment variables.
+//
+//	c, err := client.New(ctx, client.WithBackend(client.SSMBackend), client.WithRegion("us-east-1"))
+//	if err != nil {
+//		return err
+//	}
+//	env, err := c.LoadEnv(ctx, "app", "app/web")
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/segmentio/chamber/v3/environ"
+	"github.com/segmentio/chamber/v3/store"
+)
+
+const (
+	NullBackend           = store.NullBackend
+	SSMBackend            = store.SSMBackend
+	SecretsManagerBackend = store.SecretsManagerBackend
+	S3Backend             = store.S3Backend
+	S3KMSBackend          = store.S3KMSBackend
+
+	// DefaultNumRetries is the default number of retries for AWS requests.
+	DefaultNumRetries = 10
+)
+
+// ensure Client confirms to Store interface
+var _ store.Store = &Client{}
+
+// Client is a store built from options, with helpers for loading secrets.
+type Client struct {
+	store.Store
+
+	// closers are the stores opened for the client which hold resources,
+	// e.g. plugin processes, however deep they are in the stack
+	closers []io.Closer
+}
+
+type options struct {
+	backend      string
+	config       store.BackendConfig
+	keyProviders []store.KeyProvider
+	encryption   store.EncryptionOptions
+	audit        *store.AuditConfig
+	tracing      *store.TracingOptions
+	routes       []route
+	replicas     []route
+}
+
+// route is a route's prefix or a replica's name, and the options for its
+// backend
+type route struct {
+	name string
+	opts []Option
+}
+
+// Option configures New.
+type Option func(*options)
+
+// WithBackend sets the backend, compared case-insensitively: one registered
+// with store.Register, or else a chamber-backend-<name> plugin on $PATH.
+// Defaults to SSMBackend.
+func WithBackend(backend string) Option {
+	return func(o *options) {
+		o.backend = strings.ToUpper(backend)
+	}
+}
+
+// WithRegion sets the AWS region, taking precedence over $CHAMBER_AWS_REGION
+// and the SDK's own lookup.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.config.Region = region
+	}
+}
+
+// WithRetries sets the maximum number of attempts for AWS requests. Defaults
+// to DefaultNumRetries.
+func WithRetries(numRetries int) Option {
+	return func(o *options) {
+		o.config.NumRetries = numRetries
+	}
+}
+
+// WithRetryMode sets the AWS SDK retry mode. Defaults to
+// store.DefaultRetryMode.
+func WithRetryMode(retryMode aws.RetryMode) Option {
+	return func(o *options) {
+		o.config.RetryMode = retryMode
+	}
+}
+
+// WithKMSAlias sets the KMS key alias used to encrypt written secrets, for the
+// SSM and S3-KMS backends. The `alias/` prefix is added if missing.
+func WithKMSAlias(alias string) Option {
+	return func(o *options) {
+		o.config.KMSKeyAlias = alias
+	}
+}
+
+// WithBucket sets the bucket for the S3 and S3-KMS backends, which require it.
+func WithBucket(bucket string) Option {
+	return func(o *options) {
+		o.config.Bucket = bucket
+	}
+}
+
+// WithEndpoint sets a custom endpoint for the backend's AWS service, e.g. for
+// a VPC endpoint or local testing.
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.config.Endpoint = endpoint
+	}
+}
+
+// WithBackendOption sets a backend specific option, e.g. for a plugin.
+func WithBackendOption(key, value string) Option {
+	return func(o *options) {
+		if o.config.Options == nil {
+			o.config.Options = map[string]string{}
+		}
+		o.config.Options[key] = value
+	}
+}
+
+// WithRole assumes an IAM role, e.g. in another account, using the
+// credentials found as usual.
+func WithRole(roleARN string) Option {
+	return func(o *options) {
+		o.config.RoleARN = roleARN
+	}
+}
+
+// WithReplica mirrors every write and delete to another store, named name in
+// errors. See store.ReplicatingStore. The replica's backend settings start as
+// copies of the main backend's, so e.g. WithRegion alone replicates to the
+// same backend in another region; the KMS key alias is only copied if the
+// backend is the same. Only WithBackend, WithRegion, WithRole, WithKMSAlias,
+// WithBucket, WithEndpoint and WithBackendOption apply. It can't be used with
+// WithRoute.
+func WithReplica(name string, opts ...Option) Option {
+	return func(o *options) {
+		o.replicas = append(o.replicas, route{name: name, opts: opts})
+	}
+}
+
+// WithRoute sends the services under prefix to the backend set in opts, by
+// the longest matching prefix, instead of to the main backend. See
+// store.RoutingStore. Only WithBackend, WithRegion, WithRole, WithKMSAlias,
+// WithBucket, WithEndpoint and WithBackendOption apply to a route; its region,
+// role and retry settings default to the main backend's. It can't be used with
+// WithReplica.
+func WithRoute(prefix string, opts ...Option) Option {
+	return func(o *options) {
+		o.routes = append(o.routes, route{name: prefix, opts: opts})
+	}
+}
+
+// WithEncryption encrypts values client-side before they reach the backend,
+// with data keys wrapped by each of providers. See store.EncryptingStore.
+func WithEncryption(providers ...store.KeyProvider) Option {
+	return func(o *options) {
+		o.keyProviders = append(o.keyProviders, providers...)
+	}
+}
+
+// WithEncryptionOptions configures the encryption set up by WithEncryption,
+// e.g. to read values written before it was.
+func WithEncryptionOptions(encryption store.EncryptionOptions) Option {
+	return func(o *options) {
+		o.encryption = encryption
+	}
+}
+
+// WithAudit records every operation which reads values or changes secrets to
+// the sinks in config. See store.AuditingStore. If config has no Identity,
+// the caller's STS ARN is recorded; if it has no Backend, the backend is.
+func WithAudit(config store.AuditConfig) Option {
+	return func(o *options) {
+		o.audit = &config
+	}
+}
+
+// WithTracing records a span and metrics for every store operation and AWS
+// API call, with the global OpenTelemetry providers. See store.TracingStore
+// and tracing.Start.
+func WithTracing(tracing store.TracingOptions) Option {
+	return func(o *options) {
+		o.tracing = &tracing
+	}
+}
+
+// New creates a Client for the backend set in opts. Close it once done, to
+// stop any plugin processes it started.
+func New(ctx context.Context, opts ...Option) (_ *Client, err error) {
+	o := options{
+		backend: SSMBackend,
+		config: store.BackendConfig{
+			NumRetries: DefaultNumRetries,
+			RetryMode:  store.DefaultRetryMode,
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.routes) > 0 && len(o.replicas) > 0 {
+		// a replica is a single backend, so the routed services would be
+		// mirrored somewhere other than where they're read from
+		return nil, errors.New("routes and replicas can't be used together")
+	}
+
+	c := &Client{}
+	defer func() {
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+	s, err := c.open(ctx, o.backend, o.config)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.routes) > 0 {
+		if s, err = c.openRoutes(ctx, o, s); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.replicas) > 0 {
+		if s, err = c.openReplicas(ctx, o, s); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.keyProviders) > 0 {
+		if s, err = store.NewEncryptingStoreWithOptions(s, o.encryption, o.keyProviders...); err != nil {
+			return nil, err
+		}
+	}
+	if o.audit != nil {
+		audit := *o.audit
+		if audit.Backend == "" {
+			audit.Backend = strings.ToLower(o.backend)
+		}
+		if audit.Identity == nil {
+			audit.Identity = func(ctx context.Context) (string, error) {
+				cfg, err := store.NewConfig(ctx, o.config.Region, o.config.NumRetries, o.config.RetryMode)
+				if err != nil {
+					return "", err
+				}
+				return store.STSIdentity(ctx, cfg)
+			}
+		}
+		s = store.NewAuditingStore(s, audit)
+	}
+	if o.tracing != nil {
+		s = store.NewTracingStore(s, strings.ToLower(o.backend), *o.tracing)
+	}
+	c.Store = s
+	return c, nil
+}
+
+// Close closes every store opened for c which holds resources, such as the
+// process of a plugin backend. c can't be used afterwards.
+func (c *Client) Close() error {
+	var errs []error
+	for _, closer := range c.closers {
+		errs = append(errs, closer.Close())
+	}
+	c.closers = nil
+	return errors.Join(errs...)
+}
+
+// open opens a store, keeping it to close if it needs closing
+func (c *Client) open(ctx context.Context, backend string, cfg store.BackendConfig) (store.Store, error) {
+	s, err := store.Open(ctx, backend, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := s.(io.Closer); ok {
+		c.closers = append(c.closers, closer)
+	}
+	return s, nil
+}
+
+// openRoutes opens the store for each route in o, returning a store routing
+// between them and fallback
+func (c *Client) openRoutes(ctx context.Context, o options, fallback store.Store) (store.Store, error) {
+	routes := make([]store.Route, 0, len(o.routes))
+	for _, r := range o.routes {
+		ro := options{
+			backend: o.backend,
+			config: store.BackendConfig{
+				Region:     o.config.Region,
+				NumRetries: o.config.NumRetries,
+				RetryMode:  o.config.RetryMode,
+				RoleARN:    o.config.RoleARN,
+			},
+		}
+		for _, opt := range r.opts {
+			opt(&ro)
+		}
+		s, err := c.open(ctx, ro.backend, ro.config)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", r.name, err)
+		}
+		routes = append(routes, store.Route{Prefix: r.name, Store: s})
+	}
+	return store.NewRoutingStore(routes, fallback)
+}
+
+// openReplicas opens the store for each replica in o, returning a store
+// mirroring primary to them
+func (c *Client) openReplicas(ctx context.Context, o options, primary store.Store) (store.Store, error) {
+	replicas := make([]store.Replica, 0, len(o.replicas))
+	for _, r := range o.replicas {
+		ro := options{backend: o.backend, config: o.config}
+		ro.config.Options = maps.Clone(o.config.Options)
+		if replicaBackend(o.backend, r.opts) != o.backend {
+			// the main backend's key alias means nothing to another
+			// backend, and the S3 and Secrets Manager backends reject one
+			ro.config.KMSKeyAlias = ""
+		}
+		for _, opt := range r.opts {
+			opt(&ro)
+		}
+		s, err := c.open(ctx, ro.backend, ro.config)
+		if err != nil {
+			return nil, fmt.Errorf("replica %s: %w", r.name, err)
+		}
+		replicas = append(replicas, store.Replica{Name: r.name, Store: s})
+	}
+	return store.NewReplicatingStore(primary, replicas...), nil
+}
+
+// replicaBackend returns the backend set in opts, or else backend
+func replicaBackend(backend string, opts []Option) string {
+	o := options{backend: backend}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.backend
+}
+
+// LoadEnv loads the secrets of each service, in order, into a map of
+// environment variable names to values. Secret keys are named as by
+// `chamber exec`, and later services take precedence over earlier ones.
+func (c *Client) LoadEnv(ctx context.Context, services ...string) (map[string]string, error) {
+	return c.LoadEnvWithOptions(ctx, environ.LoadOptions{OnCollision: environ.CollisionLastWins}, services...)
+}
+
+// LoadEnvWithOptions is like LoadEnv, but selects, names and merges secrets as
+// set in opts.
+func (c *Client) LoadEnvWithOptions(ctx context.Context, opts environ.LoadOptions, services ...string) (map[string]string, error) {
+	var env environ.Environ
+	if _, err := env.LoadServices(ctx, c.Store, services, opts); err != nil {
+		return nil, err
+	}
+	return env.Map(), nil
+}
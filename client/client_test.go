@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servicesStore serves ListRaw from a fixed map
+type servicesStore struct {
+	store.NullStore
+	services map[string][]store.RawSecret
+}
+
+func (s *servicesStore) ListRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	rawSecrets, ok := s.services[service]
+	if !ok {
+		return nil, store.ErrSecretNotFound
+	}
+	return rawSecrets, nil
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("null backend", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"))
+		require.NoError(t, err)
+		assert.IsType(t, &store.NullStore{}, c.Store)
+	})
+
+	t.Run("defaults to SSM", func(t *testing.T) {
+		c, err := New(ctx, WithRegion("us-east-1"))
+		require.NoError(t, err)
+		s, ok := c.Store.(*store.SSMStore)
+		require.True(t, ok)
+		assert.Equal(t, store.DefaultKeyID, s.KMSKey())
+	})
+
+	t.Run("SSM with KMS alias", func(t *testing.T) {
+		c, err := New(ctx, WithRegion("us-east-1"), WithKMSAlias("mykey"))
+		require.NoError(t, err)
+		assert.Equal(t, "alias/mykey", c.Store.(*store.SSMStore).KMSKey())
+	})
+
+	t.Run("S3-KMS", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("s3-kms"), WithRegion("us-east-1"), WithBucket("bucket"))
+		require.NoError(t, err)
+		assert.IsType(t, &store.S3KMSStore{}, c.Store)
+	})
+
+	t.Run("S3 requires a bucket", func(t *testing.T) {
+		_, err := New(ctx, WithBackend(S3Backend))
+		assert.EqualError(t, err, "Must set bucket for s3 backend")
+	})
+
+	t.Run("S3 rejects a KMS alias", func(t *testing.T) {
+		_, err := New(ctx, WithBackend(S3Backend), WithBucket("bucket"), WithKMSAlias("mykey"))
+		assert.Error(t, err)
+	})
+
+	t.Run("with encryption", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"), WithEncryption(&store.AgeKeyProvider{Recipient: "age1test"}))
+		require.NoError(t, err)
+		assert.IsType(t, &store.EncryptingStore{}, c.Store)
+	})
+
+	t.Run("with audit", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"), WithAudit(store.AuditConfig{}))
+		require.NoError(t, err)
+		assert.IsType(t, &store.AuditingStore{}, c.Store)
+	})
+
+	t.Run("with tracing", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"), WithTracing(store.TracingOptions{}))
+		require.NoError(t, err)
+		assert.IsType(t, &store.TracingStore{}, c.Store)
+	})
+
+	t.Run("with routes", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"),
+			WithRoute("payments", WithBackend(S3KMSBackend), WithRegion("us-east-1"), WithBucket("payments")),
+		)
+		require.NoError(t, err)
+		s, ok := c.Store.(*store.RoutingStore)
+		require.True(t, ok)
+		assert.IsType(t, &store.S3KMSStore{}, s.Route("payments/app"))
+		assert.IsType(t, &store.NullStore{}, s.Route("app"))
+
+		_, err = New(ctx, WithBackend("null"), WithRoute("payments", WithBackend(S3Backend)))
+		assert.EqualError(t, err, "route payments: Must set bucket for s3 backend")
+	})
+
+	t.Run("with replicas", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"), WithReplica("dr", WithRegion("us-west-2")))
+		require.NoError(t, err)
+		assert.IsType(t, &store.ReplicatingStore{}, c.Store)
+
+		_, err = New(ctx, WithBackend("null"), WithReplica("dr", WithBackend(S3Backend)))
+		assert.EqualError(t, err, "replica dr: Must set bucket for s3 backend")
+
+		// the main backend's key alias isn't copied to another backend
+		_, err = New(ctx, WithBackend(S3KMSBackend), WithBucket("secrets"), WithKMSAlias("chamber"), WithRegion("us-east-1"),
+			WithReplica("dr", WithBackend(S3Backend), WithBucket("dr-secrets")))
+		assert.NoError(t, err)
+	})
+
+	t.Run("routes and replicas", func(t *testing.T) {
+		_, err := New(ctx, WithBackend("null"),
+			WithRoute("payments", WithBackend("null")),
+			WithReplica("dr", WithRegion("us-west-2")))
+		assert.EqualError(t, err, "routes and replicas can't be used together")
+	})
+
+	t.Run("invalid backend", func(t *testing.T) {
+		_, err := New(ctx, WithBackend("vault"))
+		assert.EqualError(t, err, "invalid backend `VAULT`")
+	})
+}
+
+// closingStore counts how often it's closed, like a plugin store stopping its
+// process
+type closingStore struct {
+	store.NullStore
+	closed *int
+}
+
+func (s *closingStore) Close() error {
+	*s.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	closed := 0
+	store.Register("closing-test", func(ctx context.Context, cfg store.BackendConfig) (store.Store, error) {
+		return &closingStore{closed: &closed}, nil
+	})
+
+	t.Run("closes every store in the stack", func(t *testing.T) {
+		for _, opt := range []Option{
+			WithRoute("payments", WithBackend("closing-test")),
+			WithReplica("dr", WithBackend("closing-test")),
+		} {
+			closed = 0
+			c, err := New(ctx, WithBackend("closing-test"), opt,
+				WithAudit(store.AuditConfig{}),
+				WithTracing(store.TracingOptions{}))
+			require.NoError(t, err)
+			assert.Equal(t, 0, closed)
+			require.NoError(t, c.Close())
+			assert.Equal(t, 2, closed)
+			require.NoError(t, c.Close())
+			assert.Equal(t, 2, closed)
+		}
+	})
+
+	t.Run("closes the stores opened when New fails", func(t *testing.T) {
+		closed = 0
+		_, err := New(ctx, WithBackend("closing-test"), WithReplica("dr", WithBackend(S3Backend)))
+		require.Error(t, err)
+		assert.Equal(t, 1, closed)
+	})
+
+	t.Run("stores without resources", func(t *testing.T) {
+		c, err := New(ctx, WithBackend("null"))
+		require.NoError(t, err)
+		assert.NoError(t, c.Close())
+	})
+}
+
+func TestLoadEnv(t *testing.T) {
+	c := &Client{Store: &servicesStore{services: map[string][]store.RawSecret{
+		"app": {
+			{Key: "/app/db-host", Value: "db"},
+			{Key: "/app/log_level", Value: "info"},
+		},
+		"app/web": {
+			{Key: "/app/web/log_level", Value: "debug"},
+		},
+	}}}
+
+	env, err := c.LoadEnv(context.Background(), "app", "app/web")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_HOST":   "db",
+		"LOG_LEVEL": "debug",
+	}, env)
+
+	_, err = c.LoadEnv(context.Background(), "missing")
+	assert.ErrorIs(t, err, store.ErrSecretNotFound)
+}
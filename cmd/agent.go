@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/chamber/v3/agent"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocket string
+	agentTTL    time.Duration
+	agentAllow  []string
+
+	// agentCmd represents the agent command
+	agentCmd = &cobra.Command{
+		Use:   "agent",
+		Short: "Run a daemon serving cached secrets over a local Unix socket",
+		Long: `Run a daemon serving cached secrets over a local Unix socket.
+
+The agent authenticates to the backend once, and caches the secrets of each
+service it's asked for. Processes on the same host can then use
+'chamber exec --agent' to load secrets from it without calling AWS themselves.
+
+Access is controlled by the credentials of the connecting process. Each --allow
+rule has the form <who>:<service pattern>, where who is uid=N, gid=N or * for
+anyone. The pattern '*' allows every service. Without any rules, only the
+user running the agent is allowed.`,
+		Args: cobra.NoArgs,
+		RunE: agentRun,
+		Example: `
+	$ chamber agent --socket /run/chamber.sock --allow uid=1001:app/* --allow gid=200:global
+`,
+	}
+)
+
+func init() {
+	agentCmd.Flags().StringVar(&agentSocket, "socket", agent.DefaultSocket, "path of the Unix socket to listen on")
+	agentCmd.Flags().DurationVar(&agentTTL, "ttl", agent.DefaultTTL, "how long to cache the secrets of each service")
+	agentCmd.Flags().StringArrayVar(&agentAllow, "allow", nil, "allow matching processes to read matching services, as <who>:<service pattern>; may be repeated")
+	RootCmd.AddCommand(agentCmd)
+}
+
+func agentRun(cmd *cobra.Command, args []string) error {
+	rules := make([]agent.Rule, len(agentAllow))
+	for i, s := range agentAllow {
+		rule, err := agent.ParseRule(s)
+		if err != nil {
+			return fmt.Errorf("Failed to parse --allow: %w", err)
+		}
+		rules[i] = rule
+	}
+
+	trackCommand("agent", nil)
+
+	secretStore, err := getSecretStore(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("Failed to get secret store: %w", err)
+	}
+
+	if err := removeStaleSocket(agentSocket); err != nil {
+		return fmt.Errorf("Failed to remove existing socket: %w", err)
+	}
+	l, err := net.Listen("unix", agentSocket)
+	if err != nil {
+		return fmt.Errorf("Failed to listen on socket: %w", err)
+	}
+	defer os.Remove(agentSocket)
+	// anyone may connect; requests are authorized by peer credentials
+	if err := os.Chmod(agentSocket, 0666); err != nil {
+		return fmt.Errorf("Failed to set socket permissions: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "chamber agent listening on %s\n", agentSocket)
+	return agent.NewServer(secretStore, agentTTL, rules).Serve(ctx, l)
+}
+
+// removeStaleSocket removes a socket left behind by a previous agent, which
+// would make listening fail. Anything else at path is left alone, since the
+// agent often runs as root.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and isn't a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("removes a socket", func(t *testing.T) {
+		path := filepath.Join(dir, "agent.sock")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		// keep the socket file when closing, as a crashed agent would
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, l.Close())
+
+		require.NoError(t, removeStaleSocket(path))
+		_, err = os.Lstat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("nothing to remove", func(t *testing.T) {
+		assert.NoError(t, removeStaleSocket(filepath.Join(dir, "missing.sock")))
+	})
+
+	t.Run("leaves other files alone", func(t *testing.T) {
+		path := filepath.Join(dir, "passwd")
+		require.NoError(t, os.WriteFile(path, []byte("root:x:0:0"), 0644))
+		assert.EqualError(t, removeStaleSocket(path), path+" exists and isn't a socket")
+		assert.FileExists(t, path)
+
+		link := filepath.Join(dir, "link.sock")
+		require.NoError(t, os.Symlink(path, link))
+		assert.Error(t, removeStaleSocket(link))
+		assert.FileExists(t, link)
+	})
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+)
+
+const (
+	AuditLogEnvVar     = "CHAMBER_AUDIT_LOG"
+	AuditSyslogEnvVar  = "CHAMBER_AUDIT_SYSLOG"
+	AuditWebhookEnvVar = "CHAMBER_AUDIT_WEBHOOK"
+	AuditSegmentEnvVar = "CHAMBER_AUDIT_SEGMENT"
+)
+
+var (
+	auditLogFlag     string
+	auditSyslogFlag  bool
+	auditWebhookFlag string
+	auditSegmentFlag bool
+
+	// commandPath is the command being run, e.g. "chamber write", for the
+	// audit log
+	commandPath string
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&auditLogFlag, "audit-log", "", "append an audit record of each operation to this file, as JSON lines; AKA $CHAMBER_AUDIT_LOG")
+	RootCmd.PersistentFlags().BoolVar(&auditSyslogFlag, "audit-syslog", false, "send an audit record of each operation to syslog; AKA $CHAMBER_AUDIT_SYSLOG")
+	RootCmd.PersistentFlags().StringVar(&auditWebhookFlag, "audit-webhook", "", "POST an audit record of each operation to this URL, as JSON; AKA $CHAMBER_AUDIT_WEBHOOK")
+	RootCmd.PersistentFlags().BoolVar(&auditSegmentFlag, "audit-segment", false, "send an audit record of each operation with the usage analytics, if they're enabled; AKA $CHAMBER_AUDIT_SEGMENT")
+}
+
+// auditSinks returns the sinks for the audit log, if any were configured
+func auditSinks() ([]store.AuditSink, error) {
+	rootPflags := RootCmd.PersistentFlags()
+	var sinks []store.AuditSink
+
+	logFile := auditLogFlag
+	if logFileValue := os.Getenv(AuditLogEnvVar); !rootPflags.Changed("audit-log") && logFileValue != "" {
+		logFile = logFileValue
+	}
+	if logFile != "" {
+		sink, err := store.NewFileAuditSink(logFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open audit log: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	useSyslog, err := boolFromFlagOrEnv("audit-syslog", auditSyslogFlag, AuditSyslogEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if useSyslog {
+		sink, err := store.NewSyslogAuditSink("chamber")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect to syslog: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	webhook := auditWebhookFlag
+	if webhookValue := os.Getenv(AuditWebhookEnvVar); !rootPflags.Changed("audit-webhook") && webhookValue != "" {
+		webhook = webhookValue
+	}
+	if webhook != "" {
+		sinks = append(sinks, &store.WebhookAuditSink{URL: webhook})
+	}
+
+	useSegment, err := boolFromFlagOrEnv("audit-segment", auditSegmentFlag, AuditSegmentEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if useSegment && telemetryClient != nil {
+		sinks = append(sinks, store.AuditSinkFunc(segmentAuditSink))
+	}
+
+	return sinks, nil
+}
+
+// segmentAuditSink sends an audit event with the usage analytics, so names
+// and the identity, which holds the AWS account ID, are hashed unless
+// configured otherwise
+func segmentAuditSink(ctx context.Context, event store.AuditEvent) error {
+	telemetryClient.Track("Audit Event", telemetry.Properties{
+		"chamber-version": chamberVersion,
+		"identity":        event.Identity,
+		"command":         event.Command,
+		"operation":       event.Operation,
+		"backend":         event.Backend,
+		"service":         event.Service,
+		"key":             event.Key,
+		"version":         event.Version,
+		"outcome":         event.Outcome,
+	})
+	return nil
+}
+
+func boolFromFlagOrEnv(flag string, flagValue bool, envVar string) (bool, error) {
+	envValue := os.Getenv(envVar)
+	if RootCmd.PersistentFlags().Changed(flag) || envValue == "" {
+		return flagValue, nil
+	}
+	value, err := strconv.ParseBool(envValue)
+	if err != nil {
+		return false, fmt.Errorf("Cannot parse $%s to a boolean.", envVar)
+	}
+	return value, nil
+}
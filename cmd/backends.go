@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/spf13/cobra"
+)
+
+// backendsCmd represents the backends command
+var backendsCmd = &cobra.Command{
+	Use:   "backends",
+	Short: "List the available backends and what each supports",
+	Long: `List the available backends and what each supports.
+
+Plugins found on $PATH are listed in lower case. Their capabilities are only
+known once they're started, so they're shown as '?'.`,
+	Args: cobra.NoArgs,
+	RunE: backendsRun,
+}
+
+func init() {
+	RootCmd.AddCommand(backendsCmd)
+}
+
+func backendsRun(cmd *cobra.Command, args []string) error {
+	trackCommand("backends", nil)
+
+	printBackends(os.Stdout, store.Backends(), store.Plugins())
+	return nil
+}
+
+// printBackends prints a capability matrix of the registered backends and
+// the plugins
+func printBackends(out io.Writer, backends []string, plugins []string) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, '\t', 0)
+	fmt.Fprint(w, "Backend")
+	for _, c := range store.AllCapabilities {
+		fmt.Fprintf(w, "\t%s", c)
+	}
+	fmt.Fprintln(w, "")
+
+	for _, name := range backends {
+		caps, known := store.BackendCapabilities(name)
+		fmt.Fprint(w, name)
+		for _, c := range store.AllCapabilities {
+			switch {
+			case !known:
+				fmt.Fprint(w, "\t?")
+			case caps.Supports(c):
+				fmt.Fprint(w, "\tyes")
+			default:
+				fmt.Fprint(w, "\tno")
+			}
+		}
+		fmt.Fprintln(w, "")
+	}
+	for _, name := range plugins {
+		fmt.Fprintf(w, "%s%s\n", name, strings.Repeat("\t?", len(store.AllCapabilities)))
+	}
+	w.Flush()
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintBackends(t *testing.T) {
+	var buf bytes.Buffer
+	printBackends(&buf, []string{"NULL", "S3", "SSM"}, []string{"vault"})
+
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		rows = append(rows, strings.Fields(line))
+	}
+	assert.Equal(t, [][]string{
+		{"Backend", "tags", "labels", "history", "list-services", "config", "write-with-tags", "policies", "types", "reencrypt"},
+		{"NULL", "no", "no", "no", "no", "no", "no", "no", "no", "no"},
+		{"S3", "no", "no", "yes", "no", "no", "no", "no", "no", "no"},
+		{"SSM", "yes", "yes", "yes", "yes", "yes", "yes", "yes", "yes", "yes"},
+		{"vault", "?", "?", "?", "?", "?", "?", "?", "?", "?"},
+	}, rows)
+}
@@ -3,8 +3,8 @@ package cmd
 import (
 	"fmt"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -39,18 +39,7 @@ func delete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate key: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "delete").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("delete", telemetry.Properties{"service": service, "key": key})
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
@@ -2,14 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/alessio/shellescape"
-	analytics "github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/chamber/v3/environ"
 	"github.com/segmentio/chamber/v3/utils"
 
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/spf13/cobra"
 )
 
@@ -20,9 +23,9 @@ const doubleQuoteSpecialChars = "\\\n\r\"$`"
 var (
 	// envCmd represents the env command
 	envCmd = &cobra.Command{
-		Use:   "env <service>",
+		Use:   "env <service...>",
 		Short: "Print the secrets from the parameter store in a format to export as environment variables",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MinimumNArgs(1),
 		RunE:  env,
 	}
 	preserveCase   bool
@@ -33,6 +36,13 @@ func init() {
 	envCmd.Flags().SortFlags = false
 	envCmd.Flags().BoolVarP(&preserveCase, "preserve-case", "p", false, "preserve variable name case")
 	envCmd.Flags().BoolVarP(&escapeSpecials, "escape-strings", "e", false, "escape special characters in values")
+	envCmd.Flags().BoolVar(&strict, "strict", false, `enable strict mode:
+only print secrets for which there is a corresponding env var with value
+<strict-value>, and fail if there are any env vars with that value missing
+from secrets`)
+	envCmd.Flags().StringVar(&strictValue, "strict-value", strictValueDefault, "value to expect in --strict mode")
+	addLoadOptionsFlags(envCmd)
+	addParentCollisionFlag(envCmd)
 	RootCmd.AddCommand(envCmd)
 }
 
@@ -56,41 +66,62 @@ func env(cmd *cobra.Command, args []string) error {
 // Returns a []string, with each string being a `key=value` pair,
 // and returns any errors encountered along the way.
 // Keys will be converted into valid shell variable names,
-// and converted to uppercase unless --preserve is passed.
-// Key ordering is non-deterministic and unstable, as returned
-// value from a given secret store is non-deterministic and unstable.
+// and converted to uppercase unless --preserve is passed;
+// --map targets and --prefix are used exactly as given.
+// Services are loaded in order, so when several services share
+// a key, the last service wins unless --on-collision says otherwise,
+// as with `chamber exec`.
+// Output is lexically sorted by variable name.
 func exportEnv(cmd *cobra.Command, args []string) ([]string, error) {
-	service := utils.NormalizeService(args[0])
-	if err := validateService(service); err != nil {
-		return nil, fmt.Errorf("Failed to validate service: %w", err)
+	services := make([]string, len(args))
+	for i, arg := range args {
+		service := utils.NormalizeService(arg)
+		if err := validateServiceWithLabel(service); err != nil {
+			return nil, fmt.Errorf("Failed to validate service: %w", err)
+		}
+		services[i] = service
 	}
 
-	secretStore, err := getSecretStore(cmd.Context())
+	opts, err := loadOptions()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get secret store: %w", err)
+		return nil, err
 	}
+	// name variables as they'll be printed, so collisions are detected on the
+	// final names
+	opts.Normalize = envVarName
 
-	rawSecrets, err := secretStore.ListRaw(cmd.Context(), service)
+	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
-		return nil, fmt.Errorf("Failed to list store contents: %w", err)
+		return nil, fmt.Errorf("Failed to get secret store: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "env").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("backend", backend),
-		})
+	trackCommand("env", telemetry.Properties{"services": services})
+
+	var e environ.Environ
+	var result environ.LoadResult
+	if strict {
+		slog.Debug("chamber: strict mode engaged")
+		// only the substituted variables are printed, so strict mode here
+		// always behaves as though --pristine were set
+		e = environ.Environ(os.Environ())
+		result, err = e.LoadServicesStrict(cmd.Context(), secretStore, strictValue, true, services, opts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// the inherited environment is only consulted when asked, since
+		// re-sourcing the output would otherwise always collide
+		if cmd.Flags().Changed("on-parent-collision") {
+			e = environ.Environ(os.Environ())
+		}
+		result, err = e.LoadServices(cmd.Context(), secretStore, services, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list store contents: %w", err)
+		}
 	}
+	reportLoad(result, environ.Collision.String)
 
-	params := make(map[string]string)
-	for _, rawSecret := range rawSecrets {
-		params[key(rawSecret.Key)] = rawSecret.Value
-	}
+	params := paramsFromSources(e, result)
 
 	out, err := buildEnvOutput(params)
 	if err != nil {
@@ -106,15 +137,21 @@ func exportEnv(cmd *cobra.Command, args []string) ([]string, error) {
 	return out, nil
 }
 
-// output will be returned lexically sorted by key name
+// envVarName turns a secret key into a shell variable name, uppercased unless
+// --preserve-case is set
+func envVarName(k string) string {
+	name := sanitizeKey(k)
+	if !preserveCase {
+		name = strings.ToUpper(name)
+	}
+	return name
+}
+
+// output will be returned lexically sorted by key name. Names are used as they
+// are, since they were already chosen while loading.
 func buildEnvOutput(params map[string]string) ([]string, error) {
 	out := []string{}
-	for _, key := range sortedKeys(params) {
-		name := sanitizeKey(key)
-		if !preserveCase {
-			name = strings.ToUpper(name)
-		}
-
+	for _, name := range sortedKeys(params) {
 		if err := validateShellName(name); err != nil {
 			return nil, err
 		}
@@ -122,12 +159,12 @@ func buildEnvOutput(params map[string]string) ([]string, error) {
 		// the default format prints all escape sequences as
 		// string literals, and wraps values in single quotes
 		// if they're unsafe or multi-line strings.
-		s := fmt.Sprintf(`%s=%s`, name, shellescape.Quote(params[key]))
+		s := fmt.Sprintf(`%s=%s`, name, shellescape.Quote(params[name]))
 		if escapeSpecials {
 			// this format collapses special characters like newlines
 			// or carriage returns. requires escape sequences to be interpolated
 			// by whatever parses our key="value" pairs.
-			s = fmt.Sprintf(`%s="%s"`, name, doubleQuoteEscape(params[key]))
+			s = fmt.Sprintf(`%s="%s"`, name, doubleQuoteEscape(params[name]))
 		}
 
 		// don't rely on printf to handle properly quoting or
@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"testing"
+
+	"github.com/segmentio/chamber/v3/environ"
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_validateShellName(t *testing.T) {
@@ -74,3 +80,37 @@ func Test_doubleQuoteEscape(t *testing.T) {
 		})
 	}
 }
+
+// rawSecretsStore serves ListRaw from a fixed map
+type rawSecretsStore struct {
+	store.NullStore
+	services map[string][]store.RawSecret
+}
+
+func (s *rawSecretsStore) ListRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	return s.services[service], nil
+}
+
+func Test_buildEnvOutput(t *testing.T) {
+	s := &rawSecretsStore{services: map[string][]store.RawSecret{
+		"app": {
+			{Key: "/app/db_host", Value: "db"},
+			{Key: "/app/log_level", Value: "debug"},
+		},
+	}}
+	opts := environ.LoadOptions{
+		Mapping:   &environ.KeyMapping{Prefix: "app_", Map: map[string]string{"db_host": "lower_name"}},
+		Normalize: envVarName,
+	}
+
+	var e environ.Environ
+	result, err := e.LoadServices(context.Background(), s, []string{"app"}, opts)
+	require.NoError(t, err)
+	out, err := buildEnvOutput(paramsFromSources(e, result))
+	require.NoError(t, err)
+	// mapped names and prefixes are printed exactly as given
+	assert.Equal(t, []string{"app_LOG_LEVEL=debug", "lower_name=db"}, out)
+
+	_, err = buildEnvOutput(map[string]string{"not-a-name": "value"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/chamber/v3/store"
+)
+
+// Exit codes, so that scripts can tell apart why chamber failed
+const (
+	ExitError           = 1
+	ExitNotFound        = 3
+	ExitAccessDenied    = 4
+	ExitKMSAccessDenied = 5
+	ExitThrottled       = 6
+	ExitConflict        = 7
+	ExitValidation      = 8
+	ExitQuotaExceeded   = 9
+	ExitNotImplemented  = 10
+	ExitDrifted         = 11
+)
+
+// errorClasses maps classified errors to their exit code and a hint on what
+// to do about them, in order of precedence
+var errorClasses = []struct {
+	err  error
+	code int
+	hint string
+}{
+	{store.ErrKMSAccessDenied, ExitKMSAccessDenied, "check that the KMS key exists, is enabled, and that your credentials may use it; the key is set with $CHAMBER_KMS_KEY_ALIAS or --kms-key-alias"},
+	{store.ErrAccessDenied, ExitAccessDenied, "check that your AWS credentials are allowed to access %s; 'aws sts get-caller-identity' shows who you are"},
+	{store.ErrThrottled, ExitThrottled, "requests were throttled even after retrying; try more --retries, --retry-mode adaptive, or a lower --concurrency"},
+	{store.ErrConflict, ExitConflict, "%s already exists or was changed concurrently; try again"},
+	{store.ErrValidation, ExitValidation, "the backend rejected the request for %s as invalid; check the service and key names, and the size of the value"},
+	{store.ErrQuotaExceeded, ExitQuotaExceeded, "an AWS quota was reached for %s; delete unused secrets or versions, or request a quota increase"},
+	{store.ErrSecretNotFound, ExitNotFound, ""},
+	{store.ErrLabelNotFound, ExitNotFound, "'chamber label ls' lists the labels on each version"},
+	{store.ErrNotImplemented, ExitNotImplemented, "'chamber backends' lists what each backend supports"},
+	{errReplicaDrifted, ExitDrifted, "run 'chamber replicate' without --check to bring the replica back in line"},
+	{store.ErrNotEncrypted, ExitError, "the value was written without client-side encryption; write it again, or read it with --allow-unencrypted"},
+}
+
+// exitCode returns the exit code for err, along with a hint on how to fix it
+// if there is one
+func exitCode(err error) (int, string) {
+	for _, class := range errorClasses {
+		if !errors.Is(err, class.err) {
+			continue
+		}
+		hint := class.hint
+		if strings.Contains(hint, "%s") {
+			resource := "the resource"
+			var storeErr *store.Error
+			if errors.As(err, &storeErr) && storeErr.Resource != "" {
+				resource = storeErr.Resource
+			}
+			hint = fmt.Sprintf(hint, resource)
+		}
+		return class.code, hint
+	}
+	return ExitError, ""
+}
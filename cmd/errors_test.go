@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	accessDenied := &store.Error{
+		Kind:     store.ErrAccessDenied,
+		Resource: "/app/",
+		Err:      &smithy.GenericAPIError{Code: "AccessDeniedException"},
+	}
+	code, hint := exitCode(fmt.Errorf("Failed to list store contents: %w", accessDenied))
+	assert.Equal(t, ExitAccessDenied, code)
+	assert.Contains(t, hint, "access /app/")
+
+	code, hint = exitCode(fmt.Errorf("Failed to read: %w", store.ErrSecretNotFound))
+	assert.Equal(t, ExitNotFound, code)
+	assert.Empty(t, hint)
+
+	code, hint = exitCode(fmt.Errorf("%w: this backend does not support tags", store.ErrNotImplemented))
+	assert.Equal(t, ExitNotImplemented, code)
+	assert.Contains(t, hint, "chamber backends")
+
+	code, _ = exitCode(errReplicaDrifted)
+	assert.Equal(t, ExitDrifted, code)
+
+	code, _ = exitCode(errors.New("boom"))
+	assert.Equal(t, ExitError, code)
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"strings"
 
-	analytics "github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/chamber/v3/agent"
 	"github.com/segmentio/chamber/v3/environ"
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/spf13/cobra"
 )
 
@@ -21,20 +23,36 @@ var strict bool
 // Value to expect in strict mode
 var strictValue string
 
+// When true, load secrets from a chamber agent instead of the backend
+var useAgent bool
+
+// Socket of the chamber agent to use
+var agentClientSocket string
+
+// When true, run the command as a child and mask secrets in its output
+var maskOutput bool
+
+// Secrets shorter than this aren't masked by --mask-output
+var maskMinLength int
+
 // Default value to expect in strict mode
 const strictValueDefault = "chamberme"
 
 // execCmd represents the exec command
 var execCmd = &cobra.Command{
-	Use:   "exec <service...> -- <command> [<arg...>]",
+	Use:   "exec [<service...>] -- <command> [<arg...>]",
 	Short: "Executes a command with secrets loaded into the environment",
 	Args: func(cmd *cobra.Command, args []string) error {
 		dashIx := cmd.ArgsLenAtDash()
 		if dashIx == -1 {
 			return errors.New("please separate services and command with '--'. See usage")
 		}
-		if err := cobra.MinimumNArgs(1)(cmd, args[:dashIx]); err != nil {
-			return fmt.Errorf("at least one service must be specified: %w", err)
+		services, err := execServices(args[:dashIx])
+		if err != nil {
+			return err
+		}
+		if err := cobra.MinimumNArgs(1)(cmd, services); err != nil {
+			return fmt.Errorf("at least one service must be specified, here or in the profile: %w", err)
 		}
 		if err := cobra.MinimumNArgs(1)(cmd, args[dashIx:]); err != nil {
 			return fmt.Errorf("must specify command to run. See usage: %w", err)
@@ -68,34 +86,56 @@ only inject secrets for which there is a corresponding env var with value
 <strict-value>, and fail if there are any env vars with that value missing
 from secrets`)
 	execCmd.Flags().StringVar(&strictValue, "strict-value", strictValueDefault, "value to expect in --strict mode")
+	execCmd.Flags().BoolVar(&useAgent, "agent", false, "load secrets from a running chamber agent instead of the backend")
+	execCmd.Flags().StringVar(&agentClientSocket, "agent-socket", "", "socket of the chamber agent to use with --agent; AKA $"+agent.SocketEnvVar+" (default "+agent.DefaultSocket+")")
+	execCmd.Flags().BoolVar(&maskOutput, "mask-output", false, "run the command as a child of chamber, masking secret values, and their base64 and URL-encoded forms, in its stdout and stderr")
+	execCmd.Flags().IntVar(&maskMinLength, "mask-min-length", 4, "secrets shorter than this aren't masked by --mask-output")
+	addLoadOptionsFlags(execCmd)
+	addParentCollisionFlag(execCmd)
 	RootCmd.AddCommand(execCmd)
 }
 
+// execServices returns the services given to exec, or else the profile's
+func execServices(services []string) ([]string, error) {
+	if len(services) > 0 {
+		return services, nil
+	}
+	profile, err := currentProfile()
+	if err != nil {
+		return nil, err
+	}
+	return profile.Services, nil
+}
+
 func execRun(cmd *cobra.Command, args []string) error {
 	dashIx := cmd.ArgsLenAtDash()
-	services, command, commandArgs := args[:dashIx], args[dashIx], args[dashIx+1:]
-
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "exec").
-				Set("chamber-version", chamberVersion).
-				Set("services", services).
-				Set("backend", backend),
-		})
+	command, commandArgs := args[dashIx], args[dashIx+1:]
+	services, err := execServices(args[:dashIx])
+	if err != nil {
+		return err
 	}
 
+	trackCommand("exec", telemetry.Properties{"services": services})
+
 	for _, service := range services {
 		if err := validateServiceWithLabel(service); err != nil {
 			return fmt.Errorf("Failed to validate service: %w", err)
 		}
 	}
 
-	secretStore, err := getSecretStore(cmd.Context())
+	opts, err := loadOptions()
 	if err != nil {
-		return fmt.Errorf("Failed to get secret store: %w", err)
+		return err
+	}
+
+	var secretStore store.Store
+	if useAgent {
+		secretStore = agent.NewClient(agentClientSocket)
+	} else {
+		secretStore, err = getSecretStore(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("Failed to get secret store: %w", err)
+		}
 	}
 
 	if pristine {
@@ -103,11 +143,11 @@ func execRun(cmd *cobra.Command, args []string) error {
 	}
 
 	var env environ.Environ
+	var result environ.LoadResult
 	if strict {
 		slog.Debug("chamber: strict mode engaged")
-		var err error
 		env = environ.Environ(os.Environ())
-		err = env.LoadStrict(cmd.Context(), secretStore, strictValue, pristine, services...)
+		result, err = env.LoadServicesStrict(cmd.Context(), secretStore, strictValue, pristine, services, opts)
 		if err != nil {
 			return err
 		}
@@ -115,21 +155,28 @@ func execRun(cmd *cobra.Command, args []string) error {
 		if !pristine {
 			env = environ.Environ(os.Environ())
 		}
-		for _, service := range services {
-			collisions := make([]string, 0)
-			// TODO: these interfaces should look the same as Strict*, so move pristine in there
-			err := env.Load(cmd.Context(), secretStore, service, &collisions)
-			if err != nil {
-				return fmt.Errorf("Failed to list store contents: %w", err)
-			}
-
-			for _, c := range collisions {
-				fmt.Fprintf(os.Stderr, "warning: service %s overwriting environment variable %s\n", service, c)
-			}
+		result, err = env.LoadServices(cmd.Context(), secretStore, services, opts)
+		if err != nil {
+			return fmt.Errorf("Failed to list store contents: %w", err)
 		}
 	}
+	reportLoad(result, environ.Collision.String)
 
 	slog.Debug(fmt.Sprintf("info: With environment %s\n", strings.Join(env, ",")))
 
+	if maskOutput {
+		envMap := env.Map()
+		secrets := make([]string, 0, len(result.Sources))
+		for name := range result.Sources {
+			secrets = append(secrets, envMap[name])
+		}
+		closeSecretStores()
+		stopTracing(nil)
+		stopTelemetry()
+		return execMasked(command, commandArgs, env, secrets)
+	}
+	closeSecretStores()
+	stopTracing(nil)
+	stopTelemetry()
 	return exec(command, commandArgs, env)
 }
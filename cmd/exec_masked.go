@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/segmentio/chamber/v3/utils"
+)
+
+// execMasked runs the given command as a child, rather than replacing chamber
+// with it, so that secrets can be masked in its stdout and stderr. chamber
+// exits with the child's exit status.
+func execMasked(command string, args []string, env []string, secrets []string) error {
+	patterns := utils.MaskPatterns(secrets, maskMinLength)
+	stdout := utils.NewMaskingWriter(os.Stdout, patterns)
+	stderr := utils.NewMaskingWriter(os.Stderr, patterns)
+
+	ecmd := osexec.Command(command, args...)
+	ecmd.Stdin = os.Stdin
+	ecmd.Stdout = stdout
+	ecmd.Stderr = stderr
+	ecmd.Env = env
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigChan)
+
+	if err := ecmd.Start(); err != nil {
+		return fmt.Errorf("Failed to start command: %w", err)
+	}
+
+	go func() {
+		for sig := range sigChan {
+			_ = ecmd.Process.Signal(sig)
+		}
+	}()
+
+	err := ecmd.Wait()
+	_ = stdout.Flush()
+	_ = stderr.Flush()
+	var exitErr *osexec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("Failed to wait for command termination: %w", err)
+	}
+
+	os.Exit(exitStatus(ecmd.ProcessState))
+	return nil // unreachable but Go doesn't know about it
+}
+
+// exitStatus returns the exit code of a finished process, or 128 plus the
+// signal number if a signal killed it, as a shell does
+func exitStatus(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
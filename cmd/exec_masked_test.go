@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	osexec "os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell and signals")
+	}
+
+	ecmd := osexec.Command("sh", "-c", "exit 3")
+	assert.Error(t, ecmd.Run())
+	assert.Equal(t, 3, exitStatus(ecmd.ProcessState))
+
+	ecmd = osexec.Command("sh", "-c", "true")
+	assert.NoError(t, ecmd.Run())
+	assert.Equal(t, 0, exitStatus(ecmd.ProcessState))
+
+	// SIGTERM is 15
+	ecmd = osexec.Command("sh", "-c", "kill -TERM $$")
+	assert.Error(t, ecmd.Run())
+	assert.Equal(t, 143, exitStatus(ecmd.ProcessState))
+}
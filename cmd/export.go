@@ -11,7 +11,8 @@ import (
 
 	yaml "github.com/goccy/go-yaml"
 	"github.com/magiconair/properties"
-	analytics "github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/chamber/v3/environ"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -33,6 +34,7 @@ func init() {
 	exportCmd.Flags().SortFlags = false
 	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "Output format (json, yaml, java-properties, csv, tsv, dotenv, tfvars)")
 	exportCmd.Flags().StringVarP(&exportOutput, "output-file", "o", "", "Output file (default is standard output)")
+	addLoadOptionsFlags(exportCmd)
 
 	RootCmd.AddCommand(exportCmd)
 }
@@ -40,41 +42,39 @@ func init() {
 func runExport(cmd *cobra.Command, args []string) error {
 	var err error
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "export").
-				Set("chamber-version", chamberVersion).
-				Set("services", args).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("export", telemetry.Properties{"services": args})
 
-	secretStore, err := getSecretStore(cmd.Context())
+	opts, err := loadOptions()
 	if err != nil {
 		return err
 	}
-	params := make(map[string]string)
-	for _, service := range args {
-		service = utils.NormalizeService(service)
+	// exported parameters keep their keys as they are stored
+	opts.Normalize = func(k string) string { return k }
+
+	services := make([]string, len(args))
+	for i, arg := range args {
+		service := utils.NormalizeService(arg)
 		if err := validateService(service); err != nil {
 			return fmt.Errorf("Failed to validate service %s: %w", service, err)
 		}
+		services[i] = service
+	}
 
-		rawSecrets, err := secretStore.ListRaw(cmd.Context(), service)
-		if err != nil {
-			return fmt.Errorf("Failed to list store contents for service %s: %w", service, err)
-		}
-		for _, rawSecret := range rawSecrets {
-			k := key(rawSecret.Key)
-			if _, ok := params[k]; ok {
-				fmt.Fprintf(os.Stderr, "warning: parameter %s specified more than once (overridden by service %s)\n", k, service)
-			}
-			params[k] = rawSecret.Value
-		}
+	secretStore, err := getSecretStore(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// exported parameters don't end up in chamber's environment, so only
+	// collisions between services matter
+	var e environ.Environ
+	result, err := e.LoadServices(cmd.Context(), secretStore, services, opts)
+	if err != nil {
+		return fmt.Errorf("Failed to list store contents: %w", err)
 	}
+	reportLoad(result, parameterCollision)
+
+	params := paramsFromSources(e, result)
 
 	file := os.Stdout
 	if exportOutput != "" {
@@ -114,6 +114,12 @@ func runExport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// parameterCollision words a collision between services in terms of the
+// parameters being exported, rather than environment variables
+func parameterCollision(c environ.Collision) string {
+	return fmt.Sprintf("parameter %s specified more than once (overridden by service %s)", c.Name, c.Service)
+}
+
 // this is fundamentally broken, in that there is no actual .env file
 // spec. some parsers support values spanned over multiple lines
 // as long as they're quoted, others only support character literals
@@ -124,7 +130,12 @@ func exportAsEnvFile(params map[string]string, w io.Writer) error {
 	// use top-level escapeSpecials variable to ensure that
 	// the dotenv format prints escaped values every time
 	escapeSpecials = true
-	out, err := buildEnvOutput(params)
+	// export keeps secret keys as they are, so name them as env would
+	vars := make(map[string]string, len(params))
+	for k, v := range params {
+		vars[envVarName(k)] = v
+	}
+	out, err := buildEnvOutput(vars)
 	if err != nil {
 		return err
 	}
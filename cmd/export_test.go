@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/segmentio/chamber/v3/environ"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,3 +41,12 @@ func TestExportDotenv(t *testing.T) {
 		})
 	}
 }
+
+func TestExportCollisions(t *testing.T) {
+	c := environ.Collision{Name: "db_host", Service: "app/web", Previous: "app", Policy: environ.CollisionWarn}
+	assert.Equal(t, "parameter db_host specified more than once (overridden by service app/web)", parameterCollision(c))
+
+	assert.NotNil(t, exportCmd.Flags().Lookup("on-collision"))
+	assert.Nil(t, exportCmd.Flags().Lookup("on-parent-collision"))
+	assert.NotNil(t, envCmd.Flags().Lookup("on-parent-collision"))
+}
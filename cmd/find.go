@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,11 +25,13 @@ var (
 	blankService   string
 	byValue        bool
 	includeSecrets bool
-	matches        []store.SecretId
+	streamFind     bool
 )
 
 func init() {
 	findCmd.Flags().BoolVarP(&byValue, "by-value", "v", false, "Find parameters by value")
+	findCmd.Flags().BoolVar(&streamFind, "stream", false, "Print matches as they're found, without aligning the columns")
+	addConcurrencyFlag(findCmd)
 	RootCmd.AddCommand(findCmd)
 }
 
@@ -43,39 +48,80 @@ func find(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
-	services, err := secretStore.ListServices(cmd.Context(), blankService, includeSecrets)
-	if err != nil {
-		return fmt.Errorf("Failed to list store contents: %w", err)
-	}
-
-	if byValue {
-		for _, service := range services {
-			allSecrets, err := secretStore.List(cmd.Context(), service, true)
-			if err == nil {
-				matches = append(matches, findValueMatch(allSecrets, findSecret)...)
-			}
-		}
-	} else {
-		matches = append(matches, findKeyMatch(services, findSecret)...)
-	}
-
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
+	// print the matches found so far, even if the search fails partway
+	defer w.Flush()
 	fmt.Fprint(w, "Service")
 	if byValue {
 		fmt.Fprint(w, "\tKey")
 	}
 	fmt.Fprintln(w, "")
 
-	for _, match := range matches {
-		fmt.Fprintf(w, "%s", match.Service)
-		if byValue {
-			fmt.Fprintf(w, "\t%s", match.Key)
+	// when streaming, matches are printed as they're found, rather than
+	// aligned after searching everything
+	printMatches := func(matches []store.SecretId) {
+		for _, match := range matches {
+			fmt.Fprintf(w, "%s", match.Service)
+			if byValue {
+				fmt.Fprintf(w, "\t%s", match.Key)
+			}
+			fmt.Fprintln(w, "")
+		}
+		if streamFind {
+			w.Flush()
 		}
-		fmt.Fprintln(w, "")
 	}
-	w.Flush()
 
-	return nil
+	if !byValue {
+		for name, err := range store.ListServicesIter(cmd.Context(), secretStore, blankService, includeSecrets) {
+			if err != nil {
+				return fmt.Errorf("Failed to list store contents: %w", err)
+			}
+			printMatches(findKeyMatch([]string{name}, findSecret))
+		}
+		return nil
+	}
+
+	services, err := secretStore.ListServices(cmd.Context(), blankService, includeSecrets)
+	if err != nil {
+		return fmt.Errorf("Failed to list store contents: %w", err)
+	}
+
+	return findByValue(cmd.Context(), secretStore, services, findSecret, printMatches)
+}
+
+// findByValue searches services in parallel for secrets with value, passing
+// the matches to printMatches in service order as soon as each service and
+// those before it are done. Services which can't be read don't stop the
+// search, but their errors are returned at the end.
+func findByValue(ctx context.Context, secretStore store.Store, services []string, value string, printMatches func([]store.SecretId)) error {
+	serviceMatches := make([][]store.SecretId, len(services))
+	errs := make([]error, len(services))
+	done := make([]chan struct{}, len(services))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	go utils.Parallel(len(services), concurrency, func(i int) {
+		defer close(done[i])
+		for secret, err := range store.ListIter(ctx, secretStore, services[i], true) {
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			serviceMatches[i] = append(serviceMatches[i], findValueMatch([]store.Secret{secret}, value)...)
+		}
+	})
+
+	var searchErrs []error
+	for i := range services {
+		<-done[i]
+		if errs[i] != nil {
+			searchErrs = append(searchErrs, fmt.Errorf("Failed to search service %s: %w", services[i], errs[i]))
+			continue
+		}
+		printMatches(serviceMatches[i])
+	}
+	return errors.Join(searchErrs...)
 }
 
 func findKeyMatch(services []string, searchTerm string) []store.SecretId {
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -224,3 +226,39 @@ func TestFindFunctions(t *testing.T) {
 	}
 
 }
+
+// searchStore lists fixed secrets for each service, and fails for the rest
+type searchStore struct {
+	store.NullStore
+	services map[string][]store.Secret
+}
+
+func (s *searchStore) List(ctx context.Context, service string, includeValues bool) ([]store.Secret, error) {
+	secrets, ok := s.services[service]
+	if !ok {
+		return nil, &store.Error{Kind: store.ErrAccessDenied, Resource: service, Err: errors.New("denied")}
+	}
+	return secrets, nil
+}
+
+func TestFindByValue(t *testing.T) {
+	value := "s3://this_bucket"
+	s := &searchStore{services: map[string][]store.Secret{
+		"service1": {{Value: &value, Meta: store.SecretMetadata{Key: "/service1/s3_bucket"}}},
+		"service3": {{Value: &value, Meta: store.SecretMetadata{Key: "/service3/s3_bucket"}}},
+	}}
+
+	var matches []store.SecretId
+	err := findByValue(context.Background(), s, []string{"service1", "service2", "service3"}, value, func(m []store.SecretId) {
+		matches = append(matches, m...)
+	})
+
+	// the other services are still searched, but the command fails
+	assert.Equal(t, []store.SecretId{
+		{Service: "service1", Key: "s3_bucket"},
+		{Service: "service3", Key: "s3_bucket"},
+	}, matches)
+	assert.ErrorContains(t, err, "Failed to search service service2")
+	code, _ := exitCode(err)
+	assert.Equal(t, ExitAccessDenied, code)
+}
@@ -5,8 +5,8 @@ import (
 	"os"
 	"text/tabwriter"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -34,23 +34,15 @@ func history(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate key: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "history").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("history", telemetry.Properties{"service": service, "key": key})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if err := store.RequireCapabilities(secretStore, store.CapabilityHistory); err != nil {
+		return fmt.Errorf("Failed to read history: %w", err)
+	}
 	secretId := store.SecretId{
 		Service: service,
 		Key:     key,
@@ -6,8 +6,8 @@ import (
 	"os"
 
 	yaml "github.com/goccy/go-yaml"
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -53,23 +53,14 @@ func importRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to decode input as json: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "import").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("import", telemetry.Properties{"service": service})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
 
+	values := make(map[store.SecretId]string, len(toBeImported))
 	for key, value := range toBeImported {
 		if normalizeKeys {
 			key = utils.NormalizeKey(key)
@@ -78,9 +69,11 @@ func importRun(cmd *cobra.Command, args []string) error {
 			Service: service,
 			Key:     key,
 		}
-		if err := secretStore.Write(cmd.Context(), secretId, value); err != nil {
-			return fmt.Errorf("Failed to write secret: %w", err)
-		}
+		values[secretId] = value
+		store.AddKnownSecrets(value)
+	}
+	if err := store.WriteMany(cmd.Context(), secretStore, values); err != nil {
+		return fmt.Errorf("Failed to write secret: %w", err)
 	}
 
 	fmt.Fprintf(os.Stdout, "Successfully imported %d secrets\n", len(toBeImported))
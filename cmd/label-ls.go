@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// labelLsCmd represents the label ls command
+	labelLsCmd = &cobra.Command{
+		Use:   "ls <service> [<key>]",
+		Short: "List the labels on the versions of a secret, or of every secret in a service",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE:  labelLs,
+	}
+)
+
+func init() {
+	labelCmd.AddCommand(labelLsCmd)
+}
+
+func labelLs(cmd *cobra.Command, args []string) error {
+	service := utils.NormalizeService(args[0])
+	if err := validateService(service); err != nil {
+		return fmt.Errorf("Failed to validate service: %w", err)
+	}
+
+	var keys []string
+	if len(args) == 2 {
+		key := utils.NormalizeKey(args[1])
+		if err := validateKey(key); err != nil {
+			return fmt.Errorf("Failed to validate key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	trackCommand("label ls", telemetry.Properties{"service": service})
+
+	secretStore, labeler, err := getLabeler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		for secret, err := range store.ListIter(cmd.Context(), secretStore, service, false) {
+			if err != nil {
+				return fmt.Errorf("Failed to list store contents: %w", err)
+			}
+			keys = append(keys, key(secret.Meta.Key))
+		}
+		sort.Strings(keys)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
+	fmt.Fprintln(w, "Key\tVersion\tLabels")
+	for _, k := range keys {
+		labels, err := labeler.ListLabels(cmd.Context(), store.SecretId{Service: service, Key: k})
+		if err != nil {
+			return fmt.Errorf("Failed to list labels: %w", err)
+		}
+		versions := make([]int, 0, len(labels))
+		for version := range labels {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", k, version, strings.Join(labels[version], ","))
+		}
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// labelPromoteCmd represents the label promote command
+	labelPromoteCmd = &cobra.Command{
+		Use:   "promote <service> <label>",
+		Short: "Label the latest version of every secret in a service",
+		Args:  cobra.ExactArgs(2),
+		RunE:  labelPromote,
+	}
+)
+
+func init() {
+	labelCmd.AddCommand(labelPromoteCmd)
+}
+
+func labelPromote(cmd *cobra.Command, args []string) error {
+	service := utils.NormalizeService(args[0])
+	if err := validateService(service); err != nil {
+		return fmt.Errorf("Failed to validate service: %w", err)
+	}
+
+	label := args[1]
+	if err := validateLabel(label); err != nil {
+		return fmt.Errorf("Failed to validate label: %w", err)
+	}
+
+	trackCommand("label promote", telemetry.Properties{"service": service})
+
+	secretStore, labeler, err := getLabeler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	promoted := 0
+	for secret, err := range store.ListIter(cmd.Context(), secretStore, service, false) {
+		if err != nil {
+			return fmt.Errorf("Failed to list store contents: %w", err)
+		}
+		secretId := store.SecretId{
+			Service: service,
+			Key:     key(secret.Meta.Key),
+		}
+		if err := labeler.LabelVersion(cmd.Context(), secretId, -1, []string{label}); err != nil {
+			return fmt.Errorf("Failed to label %s: %w", secretId.Key, err)
+		}
+		promoted++
+		fmt.Fprintf(os.Stdout, "Labeled %s version %d as %s\n", secretId.Key, secret.Meta.Version, label)
+	}
+
+	fmt.Fprintf(os.Stdout, "Promoted %d secrets in %s to %s\n", promoted, service, label)
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// labelRmCmd represents the label rm command
+	labelRmCmd = &cobra.Command{
+		Use:   "rm <service> <key> <label>...",
+		Short: "Remove labels from a secret",
+		Args:  cobra.MinimumNArgs(3),
+		RunE:  labelRm,
+	}
+)
+
+func init() {
+	labelCmd.AddCommand(labelRmCmd)
+}
+
+func labelRm(cmd *cobra.Command, args []string) error {
+	service := utils.NormalizeService(args[0])
+	if err := validateService(service); err != nil {
+		return fmt.Errorf("Failed to validate service: %w", err)
+	}
+
+	key := utils.NormalizeKey(args[1])
+	if err := validateKey(key); err != nil {
+		return fmt.Errorf("Failed to validate key: %w", err)
+	}
+
+	labels := args[2:]
+	for _, label := range labels {
+		if err := validateLabel(label); err != nil {
+			return fmt.Errorf("Failed to validate label: %w", err)
+		}
+	}
+
+	trackCommand("label rm", telemetry.Properties{"service": service, "key": key})
+
+	_, labeler, err := getLabeler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	secretId := store.SecretId{
+		Service: service,
+		Key:     key,
+	}
+
+	if err := labeler.Unlabel(cmd.Context(), secretId, labels); err != nil {
+		return fmt.Errorf("Failed to remove labels: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	labelVersion int
+
+	// labelSetCmd represents the label set command
+	labelSetCmd = &cobra.Command{
+		Use:   "set <service> <key> <label>...",
+		Short: "Label a version of a secret, moving the labels from any other version",
+		Args:  cobra.MinimumNArgs(3),
+		RunE:  labelSet,
+	}
+)
+
+func init() {
+	labelSetCmd.Flags().IntVarP(&labelVersion, "version", "v", -1, "The version number of the secret to label. Defaults to latest.")
+	labelCmd.AddCommand(labelSetCmd)
+}
+
+func labelSet(cmd *cobra.Command, args []string) error {
+	service := utils.NormalizeService(args[0])
+	if err := validateService(service); err != nil {
+		return fmt.Errorf("Failed to validate service: %w", err)
+	}
+
+	key := utils.NormalizeKey(args[1])
+	if err := validateKey(key); err != nil {
+		return fmt.Errorf("Failed to validate key: %w", err)
+	}
+
+	labels := args[2:]
+	for _, label := range labels {
+		if err := validateLabel(label); err != nil {
+			return fmt.Errorf("Failed to validate label: %w", err)
+		}
+	}
+
+	trackCommand("label set", telemetry.Properties{"service": service, "key": key})
+
+	_, labeler, err := getLabeler(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	secretId := store.SecretId{
+		Service: service,
+		Key:     key,
+	}
+
+	if err := labeler.LabelVersion(cmd.Context(), secretId, labelVersion, labels); err != nil {
+		return fmt.Errorf("Failed to set labels: %w", err)
+	}
+
+	version := "latest version"
+	if labelVersion != -1 {
+		version = fmt.Sprintf("version %d", labelVersion)
+	}
+	fmt.Fprintf(os.Stdout, "Labeled %s of %s/%s as %s\n", version, service, key, strings.Join(labels, ","))
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// labelCmd represents the label command
+	labelCmd = &cobra.Command{
+		Use:   "label <subcommand> ...",
+		Short: "work with labels on secret versions",
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(labelCmd)
+}
+
+// getLabeler returns the secret store, if it can change labels
+func getLabeler(ctx context.Context) (store.Store, store.Labeler, error) {
+	secretStore, err := getSecretStore(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get secret store: %w", err)
+	}
+	labeler, ok := secretStore.(store.Labeler)
+	if !ok || !store.Supports(secretStore, store.CapabilityLabels) {
+		return nil, nil, fmt.Errorf("%w: this backend does not support %s", store.ErrNotImplemented, store.CapabilityLabels)
+	}
+	return secretStore, labeler, nil
+}
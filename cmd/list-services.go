@@ -6,6 +6,7 @@ import (
 	"sort"
 	"text/tabwriter"
 
+	"github.com/segmentio/chamber/v3/store"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -18,11 +19,13 @@ var listServicesCmd = &cobra.Command{
 }
 
 var (
-	includeSecretName bool
+	includeSecretName  bool
+	streamListServices bool
 )
 
 func init() {
 	listServicesCmd.Flags().BoolVarP(&includeSecretName, "secrets", "s", false, "Include secret names in the list")
+	listServicesCmd.Flags().BoolVar(&streamListServices, "stream", false, "Print services a page at a time as they're listed, unsorted")
 	RootCmd.AddCommand(listServicesCmd)
 }
 
@@ -38,6 +41,22 @@ func listServices(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if err := store.RequireCapabilities(secretStore, store.CapabilityListServices); err != nil {
+		return fmt.Errorf("Failed to list services: %w", err)
+	}
+
+	if streamListServices {
+		// print services as they're listed, rather than after listing them all
+		fmt.Fprintln(os.Stdout, "Service")
+		for secret, err := range store.ListServicesIter(cmd.Context(), secretStore, service, includeSecretName) {
+			if err != nil {
+				return fmt.Errorf("Failed to list store contents: %w", err)
+			}
+			fmt.Fprintln(os.Stdout, secret)
+		}
+		return nil
+	}
+
 	secrets, err := secretStore.ListServices(cmd.Context(), service, includeSecretName)
 	if err != nil {
 		return fmt.Errorf("Failed to list store contents: %w", err)
@@ -7,8 +7,8 @@ import (
 	"strings"
 	"text/tabwriter"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -42,17 +42,7 @@ func list(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate service: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "list").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("list", telemetry.Properties{"service": service})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
@@ -63,9 +53,22 @@ func list(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to list store contents: %w", err)
 	}
 
+	// types, tiers and policies are only shown by backends which have them
+	showType, showTier := false, false
+	for _, secret := range secrets {
+		showType = showType || secret.Meta.Type != ""
+		showTier = showTier || secret.Meta.Tier != ""
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
 
 	fmt.Fprint(w, "Key\tVersion\tLastModified\tUser")
+	if showType {
+		fmt.Fprint(w, "\tType")
+	}
+	if showTier {
+		fmt.Fprint(w, "\tTier\tPolicies")
+	}
 	if withValues {
 		fmt.Fprint(w, "\tValue")
 	}
@@ -88,6 +91,12 @@ func list(cmd *cobra.Command, args []string) error {
 			secret.Meta.Version,
 			secret.Meta.Created.Local().Format(ShortTimeFormat),
 			secret.Meta.CreatedBy)
+		if showType {
+			fmt.Fprintf(w, "\t%s", secret.Meta.Type)
+		}
+		if showTier {
+			fmt.Fprintf(w, "\t%s\t%s", secret.Meta.Tier, formatPolicies(secret.Meta.Policies))
+		}
 		if withValues {
 			fmt.Fprintf(w, "\t%s", *secret.Value)
 		}
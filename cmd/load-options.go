@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/segmentio/chamber/v3/environ"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+// Flags controlling which secrets are used, how they are named, and how
+// collisions are resolved, shared by exec, env and export
+var (
+	mappingPrefix      string
+	mappingStripPrefix string
+	mappingInclude     []string
+	mappingExclude     []string
+	mappingMap         map[string]string
+
+	onCollision       string
+	onParentCollision string
+
+	// number of services fetched at once, also used by find
+	concurrency int
+)
+
+func addLoadOptionsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&mappingPrefix, "prefix", "", "prefix to add to every variable name, e.g. APP_")
+	cmd.Flags().StringVar(&mappingStripPrefix, "strip-prefix", "", "prefix to remove from secret keys before naming variables")
+	cmd.Flags().StringArrayVar(&mappingInclude, "include", nil, "only use secrets whose keys match this glob; may be repeated")
+	cmd.Flags().StringArrayVar(&mappingExclude, "exclude", nil, "skip secrets whose keys match this glob; may be repeated")
+	cmd.Flags().StringToStringVar(&mappingMap, "map", nil, "use an exact variable name for a secret key, as key=ENV_NAME; may be repeated")
+	cmd.Flags().StringVar(&onCollision, "on-collision", string(environ.CollisionWarn), "what to do when several services supply the same variable: warn, error, first-wins or last-wins")
+	addConcurrencyFlag(cmd)
+}
+
+// addParentCollisionFlag adds --on-parent-collision, for the commands whose
+// variables may clash with the inherited environment
+func addParentCollisionFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&onParentCollision, "on-parent-collision", string(environ.CollisionWarn), "what to do when a service supplies a variable already in the inherited environment: warn, error, first-wins or last-wins")
+}
+
+func addConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&concurrency, "concurrency", utils.DefaultConcurrency, "number of services to fetch at once; throttled requests are still retried according to --retries and --retry-mode")
+}
+
+// keyMapping builds the key mapping from the command line flags, or if none
+// were given, from the profile, returning nil if it has none either.
+func keyMapping() (*environ.KeyMapping, error) {
+	var mapping *environ.KeyMapping
+	if mappingPrefix == "" && mappingStripPrefix == "" && len(mappingInclude) == 0 && len(mappingExclude) == 0 && len(mappingMap) == 0 {
+		profile, err := currentProfile()
+		if err != nil {
+			return nil, err
+		}
+		if profile.KeyMapping == nil {
+			return nil, nil
+		}
+		mapping = &environ.KeyMapping{
+			Include:     profile.KeyMapping.Include,
+			Exclude:     profile.KeyMapping.Exclude,
+			StripPrefix: profile.KeyMapping.StripPrefix,
+			Prefix:      profile.KeyMapping.Prefix,
+			Map:         profile.KeyMapping.Map,
+		}
+	} else {
+		mapping = &environ.KeyMapping{
+			Include:     mappingInclude,
+			Exclude:     mappingExclude,
+			StripPrefix: mappingStripPrefix,
+			Prefix:      mappingPrefix,
+			Map:         mappingMap,
+		}
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("Failed to parse key mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// loadOptions builds the options for loading services from the command line
+// flags.
+func loadOptions() (environ.LoadOptions, error) {
+	mapping, err := keyMapping()
+	if err != nil {
+		return environ.LoadOptions{}, err
+	}
+	collisionPolicy, err := environ.ParseCollisionPolicy(onCollision)
+	if err != nil {
+		return environ.LoadOptions{}, fmt.Errorf("Failed to parse --on-collision: %w", err)
+	}
+	parentCollisionPolicy, err := environ.ParseCollisionPolicy(onParentCollision)
+	if err != nil {
+		return environ.LoadOptions{}, fmt.Errorf("Failed to parse --on-parent-collision: %w", err)
+	}
+
+	return environ.LoadOptions{
+		Mapping:           mapping,
+		OnCollision:       collisionPolicy,
+		OnParentCollision: parentCollisionPolicy,
+		Concurrency:       concurrency,
+	}, nil
+}
+
+// reportLoad prints warnings for collisions resolved with the warn policy, and
+// in verbose mode, which service supplied each variable. describe words each
+// collision.
+func reportLoad(result environ.LoadResult, describe func(environ.Collision) string) {
+	for _, c := range result.Collisions {
+		if c.Policy == environ.CollisionWarn {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", describe(c))
+		} else {
+			slog.Debug(fmt.Sprintf("chamber: %s (%s)", describe(c), c.Policy))
+		}
+	}
+
+	names := make([]string, 0, len(result.Sources))
+	for name := range result.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		slog.Debug(fmt.Sprintf("chamber: %s supplied by service %s", name, result.Sources[name]))
+	}
+}
+
+// paramsFromSources returns the variables in e that were supplied by a
+// service, leaving out anything inherited.
+func paramsFromSources(e environ.Environ, result environ.LoadResult) map[string]string {
+	all := e.Map()
+	params := make(map[string]string, len(result.Sources))
+	for name := range result.Sources {
+		params[name] = all[name]
+	}
+	return params
+}
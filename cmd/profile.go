@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/segmentio/chamber/v3/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileFlag string
+
+	// chamberConfig and loadedProfile are loaded once, by currentProfile
+	chamberConfig *config.Config
+	loadedProfile *config.Profile
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Inspect the profiles in chamber's config files",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles in chamber's config files",
+	Args:  cobra.NoArgs,
+	RunE:  profileListRun,
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [profile]",
+	Short: "Show the settings of a profile, by default the one in use",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  profileShowRun,
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "profile to use from chamber's config files; AKA $CHAMBER_PROFILE")
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
+	RootCmd.AddCommand(profileCmd)
+}
+
+// loadConfig loads chamber's config files, once
+func loadConfig() (*config.Config, error) {
+	if chamberConfig != nil {
+		return chamberConfig, nil
+	}
+	c, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load config: %w", err)
+	}
+	chamberConfig = c
+	return c, nil
+}
+
+// profileName returns the profile selected with --profile or $CHAMBER_PROFILE,
+// or "" for the config file's default
+func profileName() string {
+	if profileEnvVarValue := os.Getenv(config.ProfileEnvVar); !RootCmd.PersistentFlags().Changed("profile") && profileEnvVarValue != "" {
+		return profileEnvVarValue
+	}
+	return profileFlag
+}
+
+// currentProfile returns the profile in use, which is empty if there's no
+// config file. Flags and environment variables take precedence over it.
+func currentProfile() (config.Profile, error) {
+	if loadedProfile != nil {
+		return *loadedProfile, nil
+	}
+	c, err := loadConfig()
+	if err != nil {
+		return config.Profile{}, err
+	}
+	p, err := c.Profile(profileName())
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("Failed to load config: %w", err)
+	}
+	loadedProfile = &p
+	return p, nil
+}
+
+// stringSetting returns the value of flag if it was given, else of envVar if
+// it's set, else of the profile's setting if it's set, else flag's default
+func stringSetting(flag string, flagValue string, envVar string, profileValue string) string {
+	if RootCmd.PersistentFlags().Changed(flag) {
+		return flagValue
+	}
+	if envVar != "" {
+		if envValue := os.Getenv(envVar); envValue != "" {
+			return envValue
+		}
+	}
+	if profileValue != "" {
+		return profileValue
+	}
+	return flagValue
+}
+
+func profileListRun(cmd *cobra.Command, args []string) error {
+	trackCommand("profile list", nil)
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	printProfiles(os.Stdout, c, profileName())
+	return nil
+}
+
+func printProfiles(out io.Writer, c *config.Config, selected string) {
+	if selected == "" {
+		selected = c.DefaultProfile
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, '\t', 0)
+	fmt.Fprintln(w, "Profile\tBackend\tRegion\tSource")
+	for _, name := range c.ProfileNames() {
+		p := c.Profiles[name]
+		marker := ""
+		if name == selected {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", name, marker, valueOrDash(p.Backend), valueOrDash(p.Region), strings.Join(c.Sources[name], ", "))
+	}
+	w.Flush()
+}
+
+func profileShowRun(cmd *cobra.Command, args []string) error {
+	trackCommand("profile show", nil)
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name := profileName()
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return fmt.Errorf("No profile selected. Use --profile, $%s, or set `profile` in %s", config.ProfileEnvVar, config.UserFile())
+	}
+	p, err := c.Profile(name)
+	if err != nil {
+		return fmt.Errorf("Failed to load config: %w", err)
+	}
+	return printProfile(os.Stdout, name, p, c.Sources[name])
+}
+
+func printProfile(out io.Writer, name string, p config.Profile, sources []string) error {
+	contents, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("Failed to show profile: %w", err)
+	}
+	fmt.Fprintf(out, "# profile %s, from %s\n", name, strings.Join(sources, ", "))
+	if settings := strings.TrimSpace(string(contents)); settings != "{}" {
+		fmt.Fprintln(out, settings)
+	}
+	return nil
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/chamber/v3/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintProfiles(t *testing.T) {
+	c := &config.Config{
+		DefaultProfile: "dev",
+		Profiles: map[string]config.Profile{
+			"dev":  {Backend: "ssm", Region: "us-east-1"},
+			"prod": {Backend: "s3-kms"},
+		},
+		Sources: map[string][]string{
+			"dev":  {"/home/config.yaml"},
+			"prod": {"/home/config.yaml", "/repo/.chamber.yaml"},
+		},
+	}
+
+	rows := func(selected string) [][]string {
+		var buf bytes.Buffer
+		printProfiles(&buf, c, selected)
+		var rows [][]string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			rows = append(rows, strings.Fields(line))
+		}
+		return rows
+	}
+
+	assert.Equal(t, [][]string{
+		{"Profile", "Backend", "Region", "Source"},
+		{"dev*", "ssm", "us-east-1", "/home/config.yaml"},
+		{"prod", "s3-kms", "-", "/home/config.yaml,", "/repo/.chamber.yaml"},
+	}, rows(""))
+	assert.Equal(t, "prod*", rows("prod")[2][0])
+}
+
+func TestPrintProfile(t *testing.T) {
+	retries := 3
+	var buf bytes.Buffer
+	err := printProfile(&buf, "prod", config.Profile{
+		Backend:  "s3-kms",
+		Bucket:   "prod-secrets",
+		Retries:  &retries,
+		Services: []string{"app"},
+	}, []string{"/repo/.chamber.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, `# profile prod, from /repo/.chamber.yaml
+backend: s3-kms
+bucket: prod-secrets
+retries: 3
+services:
+- app
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, printProfile(&buf, "empty", config.Profile{}, []string{"/repo/.chamber.yaml"}))
+	assert.Equal(t, "# profile empty, from /repo/.chamber.yaml\n", buf.String())
+}
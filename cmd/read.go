@@ -5,8 +5,8 @@ import (
 	"os"
 	"text/tabwriter"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -41,18 +41,7 @@ func read(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate key: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "read").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("read", telemetry.Properties{"service": service, "key": key})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
@@ -74,14 +63,33 @@ func read(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// tier and policies are only shown by backends which have them
+	showTier := secret.Meta.Tier != ""
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
-	fmt.Fprintln(w, "Key\tValue\tVersion\tLastModified\tUser")
-	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+	fmt.Fprint(w, "Key\tValue\tVersion\tLastModified\tUser")
+	if showTier {
+		fmt.Fprint(w, "\tTier\tPolicies")
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s",
 		key,
 		*secret.Value,
 		secret.Meta.Version,
 		secret.Meta.Created.Local().Format(ShortTimeFormat),
 		secret.Meta.CreatedBy)
+	if showTier {
+		fmt.Fprintf(w, "\t%s\t%s", secret.Meta.Tier, formatPolicies(secret.Meta.Policies))
+	}
+	fmt.Fprintln(w, "")
 	w.Flush()
 	return nil
 }
+
+// formatPolicies describes policies, or returns "-" if there are none
+func formatPolicies(policies store.Policies) string {
+	if policies.IsZero() {
+		return "-"
+	}
+	return policies.String()
+}
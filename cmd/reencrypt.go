@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toKMSKey        string
+	reencryptDryRun bool
+
+	// reencryptCmd represents the reencrypt command
+	reencryptCmd = &cobra.Command{
+		Use:   "reencrypt <service>",
+		Short: "Rewrite every secret in a service under a different KMS key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  reencrypt,
+	}
+)
+
+func init() {
+	reencryptCmd.Flags().StringVar(&toKMSKey, "to-kms-key", "", "KMS key to move the secrets to, e.g. alias/new; the alias/ prefix is added to an alias if missing")
+	reencryptCmd.Flags().BoolVar(&reencryptDryRun, "dry-run", false, "list the secrets which would be moved, without changing anything")
+	_ = reencryptCmd.MarkFlagRequired("to-kms-key")
+	RootCmd.AddCommand(reencryptCmd)
+}
+
+func reencrypt(cmd *cobra.Command, args []string) error {
+	service := utils.NormalizeService(args[0])
+	if err := validateService(service); err != nil {
+		return fmt.Errorf("Failed to validate service: %w", err)
+	}
+	if toKMSKey == "" {
+		return errors.New("Must set --to-kms-key")
+	}
+	kmsKey := store.NormalizeKMSKey(toKMSKey)
+
+	trackCommand("reencrypt", telemetry.Properties{"service": service, "dry-run": reencryptDryRun})
+
+	secretStore, err := getSecretStore(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("Failed to get secret store: %w", err)
+	}
+	reencrypter, ok := secretStore.(store.Reencrypter)
+	if !ok || !store.Supports(secretStore, store.CapabilityReencrypt) {
+		return fmt.Errorf("%w: this backend does not support %s", store.ErrNotImplemented, store.CapabilityReencrypt)
+	}
+
+	var toMove []store.Secret
+	skipped := 0
+	for secret, err := range store.ListIter(cmd.Context(), secretStore, service, false) {
+		if err != nil {
+			return fmt.Errorf("Failed to list store contents: %w", err)
+		}
+		switch {
+		case secret.Meta.Type == store.TypeString || secret.Meta.Type == store.TypeStringList:
+			fmt.Fprintf(os.Stdout, "Skipping %s: %s parameters aren't encrypted\n", key(secret.Meta.Key), secret.Meta.Type)
+			skipped++
+		case secret.Meta.KMSKey == kmsKey:
+			skipped++
+		default:
+			toMove = append(toMove, secret)
+		}
+	}
+
+	for i, secret := range toMove {
+		secretId := store.SecretId{
+			Service: service,
+			Key:     key(secret.Meta.Key),
+		}
+		if reencryptDryRun {
+			fmt.Fprintf(os.Stdout, "[%d/%d] Would re-encrypt %s version %d from %s to %s\n", i+1, len(toMove), secretId.Key, secret.Meta.Version, secret.Meta.KMSKey, kmsKey)
+			continue
+		}
+		if err := reencrypter.Reencrypt(cmd.Context(), secretId, kmsKey); err != nil {
+			return fmt.Errorf("Failed to re-encrypt %s: %w", secretId.Key, err)
+		}
+		fmt.Fprintf(os.Stdout, "[%d/%d] Re-encrypted %s version %d from %s to %s\n", i+1, len(toMove), secretId.Key, secret.Meta.Version, secret.Meta.KMSKey, kmsKey)
+	}
+
+	if reencryptDryRun {
+		fmt.Fprintf(os.Stdout, "Would re-encrypt %d secrets in %s under %s; %d skipped\n", len(toMove), service, kmsKey, skipped)
+	} else {
+		fmt.Fprintf(os.Stdout, "Re-encrypted %d secrets in %s under %s; %d skipped\n", len(toMove), service, kmsKey, skipped)
+	}
+	return nil
+}
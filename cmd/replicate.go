@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/segmentio/chamber/v3/client"
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/segmentio/chamber/v3/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toRegion          string
+	toProfile         string
+	toRole            string
+	replicateDryRun   bool
+	replicatePrune    bool
+	replicateCheck    bool
+	errReplicaDrifted = errors.New("replica has drifted")
+
+	// replicateCmd represents the replicate command
+	replicateCmd = &cobra.Command{
+		Use:   "replicate <service...>",
+		Short: "Copy services to another region, account or backend, reporting drift",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  replicate,
+	}
+)
+
+func init() {
+	replicateCmd.Flags().StringVar(&toRegion, "to-region", "", "region to replicate to")
+	replicateCmd.Flags().StringVar(&toProfile, "to-profile", "", "profile to replicate to; --to-region and --to-role take precedence over its settings")
+	replicateCmd.Flags().StringVar(&toRole, "to-role", "", "IAM role to assume for the destination, e.g. in another account")
+	replicateCmd.Flags().BoolVar(&replicateDryRun, "dry-run", false, "report the drift, without changing anything")
+	replicateCmd.Flags().BoolVar(&replicatePrune, "prune", false, "delete secrets from the destination which aren't in the source")
+	replicateCmd.Flags().BoolVar(&replicateCheck, "check", false, "report the drift without changing anything, and exit with status 11 if there is any")
+	RootCmd.AddCommand(replicateCmd)
+}
+
+func replicate(cmd *cobra.Command, args []string) error {
+	services := make([]string, len(args))
+	for i, arg := range args {
+		services[i] = utils.NormalizeService(arg)
+		if err := validateService(services[i]); err != nil {
+			return fmt.Errorf("Failed to validate service: %w", err)
+		}
+	}
+	if toRegion == "" && toProfile == "" && toRole == "" {
+		return errors.New("Must set --to-region, --to-profile or --to-role")
+	}
+	dryRun := replicateDryRun || replicateCheck
+
+	trackCommand("replicate", telemetry.Properties{"services": services, "dry-run": dryRun, "prune": replicatePrune})
+
+	srcStore, err := getSecretStore(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("Failed to get secret store: %w", err)
+	}
+	dstStore, err := getDestinationStore(cmd)
+	if err != nil {
+		return fmt.Errorf("Failed to get destination secret store: %w", err)
+	}
+
+	drifted := false
+	for _, service := range services {
+		report, err := store.Replicate(cmd.Context(), srcStore, dstStore, service, store.ReplicateOptions{
+			DryRun: dryRun,
+			Prune:  replicatePrune,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to replicate %s: %w", service, err)
+		}
+		printReplicationReport(os.Stdout, report, dryRun, replicatePrune)
+		drifted = drifted || report.Drifted()
+	}
+	if replicateCheck && drifted {
+		return errReplicaDrifted
+	}
+	return nil
+}
+
+// getDestinationStore returns the store to replicate to: the one set up by
+// --to-profile, or else the current profile, in --to-region as --to-role if
+// given. It never has replicas of its own.
+func getDestinationStore(cmd *cobra.Command) (store.Store, error) {
+	profile, err := currentProfile()
+	if err != nil {
+		return nil, err
+	}
+	if toProfile != "" {
+		c, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		if profile, err = c.Profile(toProfile); err != nil {
+			return nil, fmt.Errorf("Failed to load config: %w", err)
+		}
+	}
+	profile.Replicas = nil
+
+	var extra []client.Option
+	if toRegion != "" {
+		extra = append(extra, client.WithRegion(toRegion))
+	}
+	if toRole != "" {
+		extra = append(extra, client.WithRole(toRole))
+	}
+	return getSecretStoreForProfile(cmd.Context(), profile, extra...)
+}
+
+func printReplicationReport(out io.Writer, report store.ReplicationReport, dryRun, prune bool) {
+	created, updated, deleted := "Created", "Updated", "Deleted"
+	if dryRun {
+		created, updated, deleted = "Would create", "Would update", "Would delete"
+	}
+	for _, key := range report.Created {
+		fmt.Fprintf(out, "%s %s/%s\n", created, report.Service, key)
+	}
+	for _, key := range report.Updated {
+		fmt.Fprintf(out, "%s %s/%s\n", updated, report.Service, key)
+	}
+	for _, key := range report.Extra {
+		if prune {
+			fmt.Fprintf(out, "%s %s/%s\n", deleted, report.Service, key)
+		} else {
+			fmt.Fprintf(out, "Only in destination: %s/%s; use --prune to delete\n", report.Service, key)
+		}
+	}
+	if report.Drifted() {
+		fmt.Fprintf(out, "%s: %d missing, %d different, %d only in destination, %d unchanged\n",
+			report.Service, len(report.Created), len(report.Updated), len(report.Extra), len(report.Unchanged))
+	} else {
+		fmt.Fprintf(out, "%s: in sync, %d unchanged\n", report.Service, len(report.Unchanged))
+	}
+}
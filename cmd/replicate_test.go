@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintReplicationReport(t *testing.T) {
+	report := store.ReplicationReport{
+		Service:   "app",
+		Created:   []string{"a"},
+		Updated:   []string{"b"},
+		Extra:     []string{"stale"},
+		Unchanged: []string{"c", "d"},
+	}
+
+	print := func(report store.ReplicationReport, dryRun, prune bool) string {
+		var buf bytes.Buffer
+		printReplicationReport(&buf, report, dryRun, prune)
+		return buf.String()
+	}
+
+	assert.Equal(t, `Created app/a
+Updated app/b
+Only in destination: app/stale; use --prune to delete
+app: 1 missing, 1 different, 1 only in destination, 2 unchanged
+`, print(report, false, false))
+
+	assert.Equal(t, `Would create app/a
+Would update app/b
+Would delete app/stale
+app: 1 missing, 1 different, 1 only in destination, 2 unchanged
+`, print(report, true, true))
+
+	assert.Equal(t, "app: in sync, 2 unchanged\n", print(store.ReplicationReport{Service: "app", Unchanged: []string{"c", "d"}}, false, false))
+}
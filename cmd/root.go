@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	analytics "github.com/segmentio/analytics-go/v3"
+	"github.com/segmentio/chamber/v3/client"
+	"github.com/segmentio/chamber/v3/config"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/spf13/cobra"
 )
 
@@ -24,9 +26,11 @@ var (
 	validServicePathFormatWithLabel = regexp.MustCompile(`^[\w\-\.]+((\/[\w\-\.]+)+(\:[\w\-\.]+)*)?$`)
 	validTagKeyFormat               = regexp.MustCompile(`^[A-Za-z0-9 +\-=\._:/@]{1,128}$`)
 	validTagValueFormat             = regexp.MustCompile(`^[A-Za-z0-9 +\-=\._:/@]{1,256}$`)
+	validLabelFormat                = regexp.MustCompile(`^[A-Za-z\-\._][\w\-\.]{0,99}$`)
 
-	verbose    bool
-	numRetries int
+	verbose          bool
+	unsafeShowValues bool
+	numRetries       int
 	// Deprecated: Use retryMode instead.
 	minThrottleDelay time.Duration
 	retryMode        string
@@ -36,11 +40,21 @@ var (
 	backendFlag         string
 	backendS3BucketFlag string
 	kmsKeyAliasFlag     string
+	backendOptionFlags  []string
+
+	encryptionKeyFileFlag string
+	encryptionKMSKeyFlags []string
+	allowUnencryptedFlag  bool
+	ageRecipientFlags     []string
+	ageIdentityFlag       string
+
+	// openClients are the clients opened by the command, closed by
+	// closeSecretStores once it's done
+	openClients []*client.Client
 
-	analyticsEnabled  bool
 	analyticsWriteKey string
-	analyticsClient   analytics.Client
-	username          string
+	telemetryFileFlag string
+	telemetryClient   *telemetry.Client
 )
 
 const (
@@ -48,33 +62,43 @@ const (
 	ShortTimeFormat = "2006-01-02 15:04:05"
 
 	// DefaultNumRetries is the default for the number of retries we'll use for our SSM client
-	DefaultNumRetries = 10
+	DefaultNumRetries = client.DefaultNumRetries
 )
 
 const (
-	NullBackend           = "NULL"
-	SSMBackend            = "SSM"
-	SecretsManagerBackend = "SECRETSMANAGER"
-	S3Backend             = "S3"
-	S3KMSBackend          = "S3-KMS"
+	NullBackend           = client.NullBackend
+	SSMBackend            = client.SSMBackend
+	SecretsManagerBackend = client.SecretsManagerBackend
+	S3Backend             = client.S3Backend
+	S3KMSBackend          = client.S3KMSBackend
 
 	BackendEnvVar    = "CHAMBER_SECRET_BACKEND"
 	BucketEnvVar     = "CHAMBER_S3_BUCKET"
 	KMSKeyEnvVar     = "CHAMBER_KMS_KEY_ALIAS"
 	NumRetriesEnvVar = "CHAMBER_RETRIES"
 
+	EncryptionKeyFileEnvVar = "CHAMBER_ENCRYPTION_KEY_FILE"
+	EncryptionKMSKeyEnvVar  = "CHAMBER_ENCRYPTION_KMS_KEY"
+	AllowUnencryptedEnvVar  = "CHAMBER_ALLOW_UNENCRYPTED"
+	AgeRecipientEnvVar      = "CHAMBER_AGE_RECIPIENT"
+	AgeIdentityEnvVar       = "CHAMBER_AGE_IDENTITY"
+
 	DefaultKMSKey = "alias/parameter_store_key"
 )
 
+// Backends lists the built-in backends.
+//
+// Deprecated: Use store.Backends, which includes registered backends.
 var Backends = []string{SSMBackend, SecretsManagerBackend, S3Backend, NullBackend, S3KMSBackend}
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
-	Use:               "chamber",
-	Short:             "CLI for storing secrets",
-	SilenceUsage:      true,
-	PersistentPreRun:  prerun,
-	PersistentPostRun: postrun,
+	Use:          "chamber",
+	Short:        "CLI for storing secrets",
+	SilenceUsage: true,
+	// errors are printed by Execute, with secret values masked
+	SilenceErrors:    true,
+	PersistentPreRun: prerun,
 }
 
 func init() {
@@ -87,16 +111,24 @@ func init() {
   `+aws.RetryModeAdaptive.String(),
 	)
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "", false, "Print more information to STDERR")
+	RootCmd.PersistentFlags().BoolVar(&unsafeShowValues, "unsafe-show-values", false, "Show secret values in logs and error messages instead of masking them")
 	RootCmd.PersistentFlags().StringVarP(&backendFlag, "backend", "b", "ssm",
 		`Backend to use; AKA $CHAMBER_SECRET_BACKEND
 	null: no-op
 	ssm: SSM Parameter Store
 	secretsmanager: Secrets Manager
 	s3: S3; requires --backend-s3-bucket
-	s3-kms: S3 using AWS-KMS encryption; requires --backend-s3-bucket and --kms-key-alias set (if you want to write or delete keys).`,
+	s3-kms: S3 using AWS-KMS encryption; requires --backend-s3-bucket and --kms-key-alias set (if you want to write or delete keys).
+	<name>: a backend registered with store.Register, or else a chamber-backend-<name> plugin on $PATH`,
 	)
 	RootCmd.PersistentFlags().StringVarP(&backendS3BucketFlag, "backend-s3-bucket", "", "", "bucket for S3 backend; AKA $CHAMBER_S3_BUCKET")
 	RootCmd.PersistentFlags().StringVarP(&kmsKeyAliasFlag, "kms-key-alias", "", DefaultKMSKey, "KMS Key Alias for writing and deleting secrets; AKA $CHAMBER_KMS_KEY_ALIAS. This option is currently only supported for the S3-KMS backend.")
+	RootCmd.PersistentFlags().StringArrayVar(&backendOptionFlags, "backend-option", nil, "backend specific option as key=value, e.g. for plugins; may be repeated")
+	RootCmd.PersistentFlags().StringVar(&encryptionKeyFileFlag, "encryption-key-file", "", "encrypt values client-side with the 32 byte key in this file; AKA $CHAMBER_ENCRYPTION_KEY_FILE")
+	RootCmd.PersistentFlags().StringArrayVar(&encryptionKMSKeyFlags, "encryption-kms-key", nil, "encrypt values client-side with data keys wrapped by this AWS KMS key ID, ARN or alias; may be repeated; AKA $CHAMBER_ENCRYPTION_KMS_KEY, comma separated")
+	RootCmd.PersistentFlags().BoolVar(&allowUnencryptedFlag, "allow-unencrypted", false, "with client-side encryption, read values written without it as they are, with a warning, instead of failing; AKA $CHAMBER_ALLOW_UNENCRYPTED")
+	RootCmd.PersistentFlags().StringArrayVar(&ageRecipientFlags, "age-recipient", nil, "encrypt values client-side for this age recipient, using the age command; may be repeated; AKA $CHAMBER_AGE_RECIPIENT, comma separated")
+	RootCmd.PersistentFlags().StringVar(&ageIdentityFlag, "age-identity", "", "age identity file for decrypting values encrypted with --age-recipient; AKA $CHAMBER_AGE_IDENTITY")
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
@@ -105,13 +137,21 @@ func Execute(vers string, writeKey string) {
 	chamberVersion = vers
 
 	analyticsWriteKey = writeKey
-	analyticsEnabled = analyticsWriteKey != ""
 
-	if cmd, err := RootCmd.ExecuteC(); err != nil {
+	cmd, err := RootCmd.ExecuteC()
+	closeSecretStores()
+	stopTracing(err)
+	stopTelemetry()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", store.Redact(err.Error()))
 		if strings.Contains(err.Error(), "arg(s)") || strings.Contains(err.Error(), "usage") {
 			_ = cmd.Usage()
 		}
-		os.Exit(1)
+		code, hint := exitCode(err)
+		if hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		os.Exit(code)
 	}
 }
 
@@ -154,14 +194,27 @@ func validateTag(key string, value string) error {
 	return nil
 }
 
+func validateLabel(label string) error {
+	lower := strings.ToLower(label)
+	if !validLabelFormat.MatchString(label) || strings.HasPrefix(lower, "aws") || strings.HasPrefix(lower, "ssm") {
+		return fmt.Errorf("Failed to validate label '%s'. Only 100 alphanumeric, dashes, full stops and underscores are allowed for labels, and labels must not start with a number, 'aws' or 'ssm'", label)
+	}
+	return nil
+}
+
 func getSecretStore(ctx context.Context) (store.Store, error) {
-	rootPflags := RootCmd.PersistentFlags()
-	if backendEnvVarValue := os.Getenv(BackendEnvVar); !rootPflags.Changed("backend") && backendEnvVarValue != "" {
-		backend = backendEnvVarValue
-	} else {
-		backend = backendFlag
+	profile, err := currentProfile()
+	if err != nil {
+		return nil, err
 	}
-	backend = strings.ToUpper(backend)
+	return getSecretStoreForProfile(ctx, profile)
+}
+
+// getSecretStoreForProfile returns the store set up by profile, with flags and
+// environment variables taking precedence, and then extra options
+func getSecretStoreForProfile(ctx context.Context, profile config.Profile, extra ...client.Option) (store.Store, error) {
+	rootPflags := RootCmd.PersistentFlags()
+	backend = strings.ToUpper(stringSetting("backend", backendFlag, BackendEnvVar, profile.Backend))
 
 	if numRetriesEnvVarValue := os.Getenv(NumRetriesEnvVar); !rootPflags.Changed("retries") && numRetriesEnvVarValue != "" {
 		var err error
@@ -169,100 +222,265 @@ func getSecretStore(ctx context.Context) (store.Store, error) {
 		if err != nil {
 			return nil, errors.New("Cannot parse $CHAMBER_RETRIES to an integer.")
 		}
+	} else if !rootPflags.Changed("retries") && profile.Retries != nil {
+		numRetries = *profile.Retries
 	}
 
-	var s store.Store
-	var err error
+	opts := []client.Option{client.WithBackend(backend), client.WithRetries(numRetries)}
+	for _, option := range backendOptionFlags {
+		key, value, found := strings.Cut(option, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("Failed to parse --backend-option %s: must be in the form key=value", option)
+		}
+		opts = append(opts, client.WithBackendOption(key, value))
+	}
+	if _, ok := os.LookupEnv(store.RegionEnvVar); !ok && profile.Region != "" {
+		opts = append(opts, client.WithRegion(profile.Region))
+	}
+	if profile.Endpoint != "" {
+		opts = append(opts, client.WithEndpoint(profile.Endpoint))
+	}
+	if profile.RoleARN != "" {
+		opts = append(opts, client.WithRole(profile.RoleARN))
+	}
 
 	switch backend {
-	case NullBackend:
-		s = store.NewNullStore()
 	case S3Backend:
 		if kmsKeyAliasFlag != DefaultKMSKey {
 			return nil, errors.New("Unable to use --kms-key-alias with this backend.")
 		}
-
-		var bucket string
-		if bucketEnvVarValue := os.Getenv(BucketEnvVar); !rootPflags.Changed("backend-s3-bucket") && bucketEnvVarValue != "" {
-			bucket = bucketEnvVarValue
-		} else {
-			bucket = backendS3BucketFlag
-		}
-		if bucket == "" {
-			return nil, errors.New("Must set bucket for s3 backend")
-		}
-		s, err = store.NewS3StoreWithBucket(ctx, numRetries, bucket)
+		opts = append(opts, client.WithBucket(bucketSetting(profile)))
 	case S3KMSBackend:
-		var bucket string
-		if bucketEnvVarValue := os.Getenv(BucketEnvVar); !rootPflags.Changed("backend-s3-bucket") && bucketEnvVarValue != "" {
-			bucket = bucketEnvVarValue
-		} else {
-			bucket = backendS3BucketFlag
+		kmsKeyAlias := stringSetting("kms-key-alias", kmsKeyAliasFlag, KMSKeyEnvVar, profile.KMSKeyAlias)
+		if kmsKeyAlias == "" {
+			return nil, errors.New("Must set kmsKeyAlias for S3 KMS backend")
 		}
-		if bucket == "" {
-			return nil, errors.New("Must set bucket for s3 backend")
+		opts = append(opts, client.WithBucket(bucketSetting(profile)), client.WithKMSAlias(kmsKeyAlias))
+	case SSMBackend:
+		if kmsKeyAliasFlag != DefaultKMSKey {
+			return nil, errors.New("Unable to use --kms-key-alias with this backend. Use CHAMBER_KMS_KEY_ALIAS instead.")
+		}
+		// the SSM store reads $CHAMBER_KMS_KEY_ALIAS itself
+		if os.Getenv(KMSKeyEnvVar) == "" && profile.KMSKeyAlias != "" {
+			opts = append(opts, client.WithKMSAlias(profile.KMSKeyAlias))
 		}
 
-		var kmsKeyAlias string
+		mode := stringSetting("retry-mode", retryMode, "", profile.RetryMode)
+		parsedRetryMode, err := aws.ParseRetryMode(mode)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid retry mode %s", mode)
+		}
+		opts = append(opts, client.WithRetryMode(parsedRetryMode))
+	case NullBackend, SecretsManagerBackend:
+		// these don't use a KMS key alias, so the profile's is left out
+	default:
+		// registered backends and plugins get whatever was configured
+		if bucket := bucketSetting(profile); bucket != "" {
+			opts = append(opts, client.WithBucket(bucket))
+		}
 		if kmsKeyAliasValue := os.Getenv(KMSKeyEnvVar); !rootPflags.Changed("kms-key-alias") && kmsKeyAliasValue != "" {
-			kmsKeyAlias = kmsKeyAliasValue
-		} else {
-			kmsKeyAlias = kmsKeyAliasFlag
+			opts = append(opts, client.WithKMSAlias(kmsKeyAliasValue))
+		} else if rootPflags.Changed("kms-key-alias") {
+			opts = append(opts, client.WithKMSAlias(kmsKeyAliasFlag))
+		} else if profile.KMSKeyAlias != "" {
+			opts = append(opts, client.WithKMSAlias(profile.KMSKeyAlias))
 		}
+	}
 
-		if !strings.HasPrefix(kmsKeyAlias, "alias/") {
-			kmsKeyAlias = fmt.Sprintf("alias/%s", kmsKeyAlias)
+	for _, route := range profile.Routes {
+		if route.Backend == "" {
+			return nil, fmt.Errorf("Route %s in the profile must set a backend", route.Prefix)
 		}
+		opts = append(opts, client.WithRoute(route.Prefix, backendOptions(config.Profile{
+			Backend:     route.Backend,
+			Bucket:      route.Bucket,
+			KMSKeyAlias: route.KMSKeyAlias,
+			Region:      route.Region,
+			RoleARN:     route.RoleARN,
+			Endpoint:    route.Endpoint,
+		})...))
+	}
 
-		if kmsKeyAlias == "" {
-			return nil, errors.New("Must set kmsKeyAlias for S3 KMS backend")
+	for _, replica := range profile.Replicas {
+		replicaProfile := config.Profile{}
+		if replica.Profile != "" {
+			c, err := loadConfig()
+			if err != nil {
+				return nil, err
+			}
+			if replicaProfile, err = c.Profile(replica.Profile); err != nil {
+				return nil, fmt.Errorf("Failed to load config: %w", err)
+			}
+			if len(replicaProfile.Routes) > 0 {
+				// a replica is a single backend
+				return nil, fmt.Errorf("Replica %s: routes and replicas can't be used together", replica.Name())
+			}
 		}
-
-		s, err = store.NewS3KMSStore(ctx, numRetries, bucket, kmsKeyAlias)
-	case SecretsManagerBackend:
-		s, err = store.NewSecretsManagerStore(ctx, numRetries)
-	case SSMBackend:
-		if kmsKeyAliasFlag != DefaultKMSKey {
-			return nil, errors.New("Unable to use --kms-key-alias with this backend. Use CHAMBER_KMS_KEY_ALIAS instead.")
+		if replica.Region != "" {
+			replicaProfile.Region = replica.Region
+		}
+		if replica.RoleARN != "" {
+			replicaProfile.RoleARN = replica.RoleARN
 		}
+		opts = append(opts, client.WithReplica(replica.Name(), backendOptions(replicaProfile)...))
+	}
 
-		var parsedRetryMode aws.RetryMode
-		parsedRetryMode, err = aws.ParseRetryMode(retryMode)
+	providers, err := keyProviders(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) > 0 {
+		allowUnencrypted, err := boolFromFlagOrEnv("allow-unencrypted", allowUnencryptedFlag, AllowUnencryptedEnvVar)
 		if err != nil {
-			return nil, fmt.Errorf("Invalid retry mode %s", retryMode)
+			return nil, err
 		}
-		s, err = store.NewSSMStoreWithRetryMode(ctx, numRetries, parsedRetryMode)
-	default:
-		return nil, fmt.Errorf("invalid backend `%s`", backend)
+		opts = append(opts,
+			client.WithEncryption(providers...),
+			client.WithEncryptionOptions(store.EncryptionOptions{AllowUnencrypted: allowUnencrypted}),
+		)
+	}
+
+	sinks, err := auditSinks()
+	if err != nil {
+		return nil, err
+	}
+	if len(sinks) > 0 {
+		opts = append(opts, client.WithAudit(store.AuditConfig{Sinks: sinks, Command: commandPath}))
 	}
-	return s, err
+
+	if tracingProviders != nil {
+		opts = append(opts, client.WithTracing(tracingOptions))
+	}
+
+	opts = append(opts, extra...)
+	c, err := client.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	openClients = append(openClients, c)
+	return c.Store, nil
 }
 
-func prerun(cmd *cobra.Command, args []string) {
-	if analyticsEnabled {
-		// set up analytics client
-		analyticsClient, _ = analytics.NewWithConfig(analyticsWriteKey, analytics.Config{
-			BatchSize: 1,
-		})
+// closeSecretStores closes the stores opened by the command, stopping any
+// plugin processes. Like stopTracing, it's called before exec replaces
+// chamber with another command, so it's safe to call more than once.
+func closeSecretStores() {
+	for _, c := range openClients {
+		if err := c.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close the secret store: %s\n", store.Redact(err.Error()))
+		}
+	}
+	openClients = nil
+}
+
+// backendOptions returns the options for the backend settings in profile, for
+// a route or replica, ignoring flags and environment variables. Settings it
+// leaves empty aren't set.
+func backendOptions(profile config.Profile) []client.Option {
+	var opts []client.Option
+	if profile.Backend != "" {
+		opts = append(opts, client.WithBackend(profile.Backend))
+	}
+	if profile.Bucket != "" {
+		opts = append(opts, client.WithBucket(profile.Bucket))
+	}
+	if profile.KMSKeyAlias != "" && usesKMSKeyAlias(profile.Backend) {
+		opts = append(opts, client.WithKMSAlias(profile.KMSKeyAlias))
+	}
+	if profile.Region != "" {
+		opts = append(opts, client.WithRegion(profile.Region))
+	}
+	if profile.RoleARN != "" {
+		opts = append(opts, client.WithRole(profile.RoleARN))
+	}
+	if profile.Endpoint != "" {
+		opts = append(opts, client.WithEndpoint(profile.Endpoint))
+	}
+	return opts
+}
 
-		username = os.Getenv("USER")
-		_ = analyticsClient.Enqueue(analytics.Identify{
-			UserId: username,
-			Traits: analytics.NewTraits().
-				Set("chamber-version", chamberVersion),
-		})
+// usesKMSKeyAlias reports whether backend, or the main backend if it's empty,
+// takes a KMS key alias: the S3, Secrets Manager and null backends reject one
+func usesKMSKeyAlias(b string) bool {
+	if b == "" {
+		b = backend
+	}
+	switch strings.ToUpper(b) {
+	case S3Backend, SecretsManagerBackend, NullBackend:
+		return false
 	}
+	return true
+}
 
-	if verbose {
-		levelVar := &slog.LevelVar{}
-		levelVar.Set(slog.LevelDebug)
-		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: levelVar})
-		slog.SetDefault(slog.New(handler))
+// keyProviders returns the key providers for client-side encryption, if any
+// were configured. KMS keys are used in the profile's region.
+func keyProviders(ctx context.Context, profile config.Profile) ([]store.KeyProvider, error) {
+	rootPflags := RootCmd.PersistentFlags()
+	var providers []store.KeyProvider
+
+	keyFile := encryptionKeyFileFlag
+	if keyFileValue := os.Getenv(EncryptionKeyFileEnvVar); !rootPflags.Changed("encryption-key-file") && keyFileValue != "" {
+		keyFile = keyFileValue
 	}
+	if keyFile != "" {
+		provider, err := store.NewLocalKeyProvider(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read encryption key: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+
+	kmsKeys := encryptionKMSKeyFlags
+	if kmsKeysValue := os.Getenv(EncryptionKMSKeyEnvVar); !rootPflags.Changed("encryption-kms-key") && kmsKeysValue != "" {
+		kmsKeys = strings.Split(kmsKeysValue, ",")
+	}
+	if len(kmsKeys) > 0 {
+		region := profile.Region
+		if _, ok := os.LookupEnv(store.RegionEnvVar); ok {
+			region = ""
+		}
+		cfg, err := store.NewConfig(ctx, region, numRetries, store.DefaultRetryMode)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to configure KMS: %w", err)
+		}
+		for _, key := range kmsKeys {
+			providers = append(providers, store.NewKMSKeyProvider(cfg, strings.TrimSpace(key)))
+		}
+	}
+
+	recipients := ageRecipientFlags
+	if recipientsValue := os.Getenv(AgeRecipientEnvVar); !rootPflags.Changed("age-recipient") && recipientsValue != "" {
+		recipients = strings.Split(recipientsValue, ",")
+	}
+	identity := ageIdentityFlag
+	if identityValue := os.Getenv(AgeIdentityEnvVar); !rootPflags.Changed("age-identity") && identityValue != "" {
+		identity = identityValue
+	}
+	if identity != "" && len(recipients) == 0 {
+		return nil, errors.New("Unable to use --age-identity without --age-recipient")
+	}
+	for _, recipient := range recipients {
+		providers = append(providers, &store.AgeKeyProvider{Recipient: strings.TrimSpace(recipient), IdentityFile: identity})
+	}
+
+	return providers, nil
 }
 
-func postrun(cmd *cobra.Command, args []string) {
-	if analyticsEnabled && analyticsClient != nil {
-		analyticsClient.Close()
+func bucketSetting(profile config.Profile) string {
+	return stringSetting("backend-s3-bucket", backendS3BucketFlag, BucketEnvVar, profile.Bucket)
+}
+
+func prerun(cmd *cobra.Command, args []string) {
+	commandPath = cmd.CommandPath()
+	startTracing(cmd)
+	startTelemetry()
+
+	store.SetShowValues(unsafeShowValues)
+	// warnings from the stores are masked too, not only --verbose output
+	levelVar := &slog.LevelVar{}
+	if verbose {
+		levelVar.Set(slog.LevelDebug)
 	}
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: levelVar})
+	slog.SetDefault(slog.New(store.NewRedactingHandler(handler)))
 }
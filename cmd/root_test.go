@@ -113,3 +113,37 @@ func TestValidateService_PathLabel_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateLabel(t *testing.T) {
+	validLabels := []string{
+		"stable",
+		"canary",
+		"release-2024.01",
+		"_blue_green",
+	}
+
+	for _, l := range validLabels {
+		t.Run("Label validation should return Nil", func(t *testing.T) {
+			result := validateLabel(l)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestValidateLabel_Invalid(t *testing.T) {
+	invalidLabels := []string{
+		"",
+		"1stable",
+		"awsdefault",
+		"SSM-label",
+		"stable:canary",
+		"stable/canary",
+	}
+
+	for _, l := range invalidLabels {
+		t.Run("Label validation should return Error", func(t *testing.T) {
+			result := validateLabel(l)
+			assert.Error(t, result)
+		})
+	}
+}
@@ -3,8 +3,8 @@ package cmd
 import (
 	"fmt"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -42,23 +42,15 @@ func tagDelete(cmd *cobra.Command, args []string) error {
 		tagKeys[i] = tagArg
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "tag delete").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("tag delete", telemetry.Properties{"service": service, "key": key})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if err := store.RequireCapabilities(secretStore, store.CapabilityTags); err != nil {
+		return fmt.Errorf("Failed to delete tags: %w", err)
+	}
 
 	secretId := store.SecretId{
 		Service: service,
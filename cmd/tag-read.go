@@ -5,8 +5,8 @@ import (
 	"os"
 	"text/tabwriter"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -36,23 +36,15 @@ func tagRead(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate key: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "tag read").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("tag read", telemetry.Properties{"service": service, "key": key})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if err := store.RequireCapabilities(secretStore, store.CapabilityTags); err != nil {
+		return fmt.Errorf("Failed to read tags: %w", err)
+	}
 
 	secretId := store.SecretId{
 		Service: service,
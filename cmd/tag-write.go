@@ -6,8 +6,8 @@ import (
 	"strings"
 	"text/tabwriter"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
@@ -52,23 +52,15 @@ func tagWrite(cmd *cobra.Command, args []string) error {
 		tags[tagKey] = tagValue
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "tag write").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("key", key).
-				Set("backend", backend),
-		})
-	}
+	trackCommand("tag write", telemetry.Properties{"service": service, "key": key})
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if err := store.RequireCapabilities(secretStore, store.CapabilityTags); err != nil {
+		return fmt.Errorf("Failed to write tags: %w", err)
+	}
 
 	secretId := store.SecretId{
 		Service: service,
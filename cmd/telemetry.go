@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// telemetryCmd represents the telemetry command
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Inspect chamber's usage analytics",
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether usage analytics are sent, where, and why",
+	Args:  cobra.NoArgs,
+	RunE:  telemetryStatusRun,
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&telemetryFileFlag, "telemetry-file", "", "record the usage analytics chamber would send to this file, as JSON lines; AKA $CHAMBER_TELEMETRY_FILE")
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+	RootCmd.AddCommand(telemetryCmd)
+}
+
+// startTelemetry sets up the analytics client, unless analytics are off
+func startTelemetry() {
+	settings, err := telemetry.LoadSettings(analyticsWriteKey, telemetryFileFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: analytics are disabled: %s\n", err)
+		return
+	}
+	telemetryClient, err = settings.NewClient(os.Getenv("USER"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: analytics are disabled: %s\n", err)
+		return
+	}
+	telemetryClient.Identify(telemetry.Properties{"chamber-version": chamberVersion})
+}
+
+// stopTelemetry sends anything buffered. It's called before exec replaces
+// chamber with another command, so it's safe to call more than once.
+func stopTelemetry() {
+	_ = telemetryClient.Close()
+	telemetryClient = nil
+}
+
+// trackCommand records that command ran, with properties
+func trackCommand(command string, properties telemetry.Properties) {
+	tracked := telemetry.Properties{
+		"command":         command,
+		"chamber-version": chamberVersion,
+		"backend":         backend,
+	}
+	for key, value := range properties {
+		tracked[key] = value
+	}
+	telemetryClient.Track("Ran Command", tracked)
+}
+
+func telemetryStatusRun(cmd *cobra.Command, args []string) error {
+	trackCommand("telemetry status", nil)
+
+	settings, err := telemetry.LoadSettings(analyticsWriteKey, telemetryFileFlag)
+	if err != nil {
+		return fmt.Errorf("Failed to load analytics settings: %w", err)
+	}
+	printTelemetryStatus(os.Stdout, settings)
+	return nil
+}
+
+func printTelemetryStatus(w io.Writer, settings telemetry.Settings) {
+	switch {
+	case settings.Disabled:
+		fmt.Fprintf(w, "Analytics: disabled by %s\n", settings.DisabledBy)
+	case settings.SegmentEnabled() || settings.FileEnabled():
+		fmt.Fprintln(w, "Analytics: enabled")
+	default:
+		fmt.Fprintln(w, "Analytics: disabled, since there's nowhere to send them")
+	}
+
+	switch {
+	case settings.SegmentEnabled():
+		fmt.Fprintln(w, "Segment: enabled")
+	case settings.WriteKey == "":
+		fmt.Fprintln(w, "Segment: disabled, since this build has no write key")
+	case !settings.Disabled && !settings.OptedIn:
+		fmt.Fprintln(w, "Segment: disabled until you opt in with analytics.enabled: true in the config file")
+	default:
+		fmt.Fprintln(w, "Segment: disabled")
+	}
+
+	switch {
+	case settings.FileEnabled():
+		fmt.Fprintf(w, "File: %s\n", settings.File)
+	case settings.File != "":
+		fmt.Fprintf(w, "File: disabled (%s)\n", settings.File)
+	default:
+		fmt.Fprintf(w, "File: none; set --telemetry-file or $%s\n", telemetry.FileEnvVar)
+	}
+
+	if settings.HashNames {
+		fmt.Fprintln(w, "Service and key names: hashed")
+	} else {
+		fmt.Fprintln(w, "Service and key names: hashed for Segment, as they are in the file")
+	}
+
+	configFile := settings.ConfigFile
+	if _, err := os.Stat(configFile); errors.Is(err, fs.ErrNotExist) {
+		configFile += " (not found)"
+	}
+	fmt.Fprintf(w, "Config file: %s\n", configFile)
+	fmt.Fprintf(w, "Opt out: set $%s=1, or analytics.enabled: false in the config file\n", telemetry.NoAnalyticsEnvVar)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintTelemetryStatus(t *testing.T) {
+	t.Run("no write key", func(t *testing.T) {
+		var buf bytes.Buffer
+		printTelemetryStatus(&buf, telemetry.Settings{HashNames: true, ConfigFile: "/nonexistent/config.yaml"})
+		assert.Equal(t, `Analytics: disabled, since there's nowhere to send them
+Segment: disabled, since this build has no write key
+File: none; set --telemetry-file or $CHAMBER_TELEMETRY_FILE
+Service and key names: hashed
+Config file: /nonexistent/config.yaml (not found)
+Opt out: set $CHAMBER_NO_ANALYTICS=1, or analytics.enabled: false in the config file
+`, buf.String())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		printTelemetryStatus(&buf, telemetry.Settings{WriteKey: "key", OptedIn: true, File: "/tmp/t.jsonl", ConfigFile: "/nonexistent/config.yaml"})
+		assert.Contains(t, buf.String(), "Analytics: enabled\nSegment: enabled\nFile: /tmp/t.jsonl\nService and key names: hashed for Segment, as they are in the file\n")
+	})
+
+	t.Run("not opted in", func(t *testing.T) {
+		var buf bytes.Buffer
+		printTelemetryStatus(&buf, telemetry.Settings{WriteKey: "key", HashNames: true, ConfigFile: "/nonexistent/config.yaml"})
+		assert.Contains(t, buf.String(), "Analytics: disabled, since there's nowhere to send them\nSegment: disabled until you opt in with analytics.enabled: true in the config file\n")
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		var buf bytes.Buffer
+		printTelemetryStatus(&buf, telemetry.Settings{WriteKey: "key", Disabled: true, DisabledBy: "$CHAMBER_NO_ANALYTICS", ConfigFile: "/nonexistent/config.yaml"})
+		assert.Contains(t, buf.String(), "Analytics: disabled by $CHAMBER_NO_ANALYTICS\nSegment: disabled\n")
+	})
+}
+
+func TestTrackCommand(t *testing.T) {
+	sink := &recordingTelemetrySink{}
+	telemetryClient = telemetry.New("alice", telemetry.NewHashingSink(sink, testHasher))
+	defer func() { telemetryClient = nil }()
+
+	trackCommand("read", telemetry.Properties{"service": "app", "key": "db_password"})
+
+	assert.Len(t, sink.events, 1)
+	properties := sink.events[0].Properties
+	assert.Equal(t, "read", properties["command"])
+	assert.Equal(t, chamberVersion, properties["chamber-version"])
+	assert.Equal(t, testHasher.Hash("app"), properties["service"])
+	assert.Equal(t, testHasher.Hash("db_password"), properties["key"])
+}
+
+func TestSegmentAuditSink(t *testing.T) {
+	sink := &recordingTelemetrySink{}
+	telemetryClient = telemetry.New("alice", telemetry.NewHashingSink(sink, testHasher))
+	defer func() { telemetryClient = nil }()
+
+	identity := "arn:aws:iam::123456789012:user/alice"
+	assert.NoError(t, segmentAuditSink(context.Background(), store.AuditEvent{Identity: identity, Operation: "write", Service: "app", Key: "db_password"}))
+
+	assert.Len(t, sink.events, 1)
+	properties := sink.events[0].Properties
+	assert.Equal(t, "write", properties["operation"])
+	assert.Equal(t, testHasher.Hash(identity), properties["identity"])
+	assert.Equal(t, testHasher.Hash("app"), properties["service"])
+}
+
+var testHasher = telemetry.NewHasher([]byte("salt"))
+
+type recordingTelemetrySink struct {
+	events []telemetry.Event
+}
+
+func (s *recordingTelemetrySink) Send(event telemetry.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingTelemetrySink) Close() error {
+	return nil
+}
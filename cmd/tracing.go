@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/tracing"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracingShutdownTimeout limits how long chamber waits to export traces
+	// and metrics before exiting
+	tracingShutdownTimeout = 5 * time.Second
+
+	// TraceSecretNamesEnvVar opts in to recording service and key names, and
+	// error messages, which may name secrets, on spans.
+	TraceSecretNamesEnvVar = "CHAMBER_TRACE_SECRET_NAMES"
+)
+
+var (
+	tracingProviders *tracing.Tracing
+	tracingOptions   store.TracingOptions
+	commandSpan      trace.Span
+)
+
+// startTracing sets up tracing from the OTEL_* environment variables, if an
+// endpoint is set, and starts a span covering the command
+func startTracing(cmd *cobra.Command) {
+	t, err := tracing.Start(cmd.Context())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing is disabled: %s\n", err)
+		return
+	}
+	if t == nil {
+		return
+	}
+	tracingProviders = t
+
+	if value := os.Getenv(TraceSecretNamesEnvVar); value != "" {
+		recordNames, err := strconv.ParseBool(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot parse $%s to a boolean, so names aren't traced\n", TraceSecretNamesEnvVar)
+		}
+		tracingOptions.RecordNames = recordNames
+	}
+
+	var ctx context.Context
+	ctx, commandSpan = otel.Tracer("github.com/segmentio/chamber/v3/cmd").Start(cmd.Context(), cmd.CommandPath(),
+		trace.WithAttributes(attribute.String("chamber.version", chamberVersion)),
+	)
+	cmd.SetContext(ctx)
+}
+
+// stopTracing ends the command's span and exports everything recorded. It's
+// called before exec replaces chamber with another command, so it's safe to
+// call more than once.
+func stopTracing(err error) {
+	if tracingProviders == nil {
+		return
+	}
+	if err != nil {
+		// error messages often name the secret involved
+		description := ""
+		if tracingOptions.RecordNames {
+			description = store.Redact(err.Error())
+		}
+		commandSpan.SetStatus(codes.Error, description)
+	}
+	commandSpan.End()
+	t := tracingProviders
+	tracingProviders = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := t.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", store.Redact(err.Error()))
+	}
+}
@@ -2,21 +2,29 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
-	analytics "github.com/segmentio/analytics-go/v3"
 	"github.com/segmentio/chamber/v3/store"
+	"github.com/segmentio/chamber/v3/telemetry"
 	"github.com/segmentio/chamber/v3/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	singleline    bool
-	skipUnchanged bool
-	tags          map[string]string
+	singleline             bool
+	skipUnchanged          bool
+	tags                   map[string]string
+	paramType              string
+	tier                   string
+	expiresAt              string
+	expirationNotification string
+	noChangeNotification   string
 
 	// writeCmd represents the write command
 	writeCmd = &cobra.Command{
@@ -31,6 +39,11 @@ func init() {
 	writeCmd.Flags().BoolVarP(&singleline, "singleline", "s", false, "Insert single line parameter (end with \\n)")
 	writeCmd.Flags().BoolVarP(&skipUnchanged, "skip-unchanged", "", false, "Skip writing secret if value is unchanged")
 	writeCmd.Flags().StringToStringVarP(&tags, "tags", "t", map[string]string{}, "Add tags to the secret; new secrets only")
+	writeCmd.Flags().StringVar(&paramType, "type", "", "SSM parameter type, one of string, stringlist or securestring; defaults to the current type, or securestring for new secrets")
+	writeCmd.Flags().StringVar(&tier, "tier", "", "SSM parameter tier, one of standard, advanced or intelligent-tiering")
+	writeCmd.Flags().StringVar(&expiresAt, "expires-at", "", "Delete the secret at this time, as RFC3339 or YYYY-MM-DD (UTC); SSM only")
+	writeCmd.Flags().StringVar(&expirationNotification, "expiration-notification", "", "Send an EventBridge notification this long before the secret expires, e.g. 7d or 12h; SSM only")
+	writeCmd.Flags().StringVar(&noChangeNotification, "no-change-notification", "", "Send an EventBridge notification if the secret is unchanged for this long, e.g. 90d; SSM only")
 	RootCmd.AddCommand(writeCmd)
 }
 
@@ -45,18 +58,7 @@ func write(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to validate key: %w", err)
 	}
 
-	if analyticsEnabled && analyticsClient != nil {
-		_ = analyticsClient.Enqueue(analytics.Track{
-			UserId: username,
-			Event:  "Ran Command",
-			Properties: analytics.NewProperties().
-				Set("command", "write").
-				Set("chamber-version", chamberVersion).
-				Set("service", service).
-				Set("backend", backend).
-				Set("key", key),
-		})
-	}
+	trackCommand("write", telemetry.Properties{"service": service, "key": key})
 
 	value := args[2]
 	if value == "-" {
@@ -76,11 +78,39 @@ func write(cmd *cobra.Command, args []string) error {
 			value = string(v)
 		}
 	}
+	store.AddKnownSecrets(value)
+
+	opts, err := writeOptions()
+	if err != nil {
+		return err
+	}
 
 	secretStore, err := getSecretStore(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("Failed to get secret store: %w", err)
 	}
+	if len(tags) > 0 {
+		if err := store.RequireCapabilities(secretStore, store.CapabilityWriteWithTags); err != nil {
+			return fmt.Errorf("Failed to write secret with tags: %w", err)
+		}
+	}
+	var optionsWriter store.OptionsWriter
+	var caps []store.Capability
+	if opts.Type != "" {
+		caps = append(caps, store.CapabilityTypes)
+	}
+	if opts.Tier != "" || !opts.Policies.IsZero() {
+		caps = append(caps, store.CapabilityPolicies)
+	}
+	if len(caps) > 0 {
+		if err := store.RequireCapabilities(secretStore, caps...); err != nil {
+			return fmt.Errorf("Failed to write secret: %w", err)
+		}
+		var ok bool
+		if optionsWriter, ok = secretStore.(store.OptionsWriter); !ok {
+			return fmt.Errorf("Failed to write secret: %w: this backend does not support %s", store.ErrNotImplemented, caps[0])
+		}
+	}
 
 	secretId := store.SecretId{
 		Service: service,
@@ -94,9 +124,88 @@ func write(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if len(tags) > 0 {
+	if optionsWriter != nil {
+		return optionsWriter.WriteWithOptions(cmd.Context(), secretId, value, opts)
+	} else if len(tags) > 0 {
 		return secretStore.WriteWithTags(cmd.Context(), secretId, value, tags)
 	} else {
 		return secretStore.Write(cmd.Context(), secretId, value)
 	}
 }
+
+// writeOptions parses the type, tier and policy flags
+func writeOptions() (store.WriteOptions, error) {
+	opts := store.WriteOptions{Tags: tags}
+
+	switch strings.ToLower(paramType) {
+	case "":
+	case "string":
+		opts.Type = store.TypeString
+	case "stringlist":
+		opts.Type = store.TypeStringList
+	case "securestring":
+		opts.Type = store.TypeSecureString
+	default:
+		return opts, fmt.Errorf("Invalid type %s, must be one of string, stringlist or securestring", paramType)
+	}
+
+	switch strings.ToLower(tier) {
+	case "":
+	case "standard":
+		opts.Tier = store.TierStandard
+	case "advanced":
+		opts.Tier = store.TierAdvanced
+	case "intelligent-tiering":
+		opts.Tier = store.TierIntelligentTiering
+	default:
+		return opts, fmt.Errorf("Invalid tier %s, must be one of standard, advanced or intelligent-tiering", tier)
+	}
+
+	if expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, expiresAt)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("Invalid --expires-at %s, must be RFC3339 or YYYY-MM-DD", expiresAt)
+		}
+		opts.Policies.ExpiresAt = t
+	}
+
+	var err error
+	if opts.Policies.ExpirationNotification, err = parsePolicyDuration(expirationNotification); err != nil {
+		return opts, fmt.Errorf("Invalid --expiration-notification: %w", err)
+	}
+	if opts.Policies.NoChangeNotification, err = parsePolicyDuration(noChangeNotification); err != nil {
+		return opts, fmt.Errorf("Invalid --no-change-notification: %w", err)
+	}
+	if opts.Policies.ExpirationNotification != 0 && opts.Policies.ExpiresAt.IsZero() {
+		return opts, errors.New("--expiration-notification needs --expires-at")
+	}
+	return opts, nil
+}
+
+// parsePolicyDuration parses a duration such as 12h, or a number of days
+// such as 7d
+func parsePolicyDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a number of days", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 || d%time.Hour != 0 {
+		return 0, fmt.Errorf("%s must be a positive whole number of hours or days", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/chamber/v3/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicyDuration(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Duration
+		err      bool
+	}{
+		{"", 0, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 0, true},
+		{"0d", 0, true},
+		{"xd", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			d, err := parsePolicyDuration(test.in)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, d)
+		})
+	}
+}
+
+func TestWriteOptions(t *testing.T) {
+	defer func() {
+		paramType, tier, expiresAt, expirationNotification, noChangeNotification = "", "", "", "", ""
+	}()
+
+	paramType, tier, expiresAt, expirationNotification = "stringlist", "Intelligent-Tiering", "2030-01-02", "7d"
+	opts, err := writeOptions()
+	require.NoError(t, err)
+	assert.Equal(t, store.TypeStringList, opts.Type)
+	assert.Equal(t, store.TierIntelligentTiering, opts.Tier)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), opts.Policies.ExpiresAt)
+	assert.Equal(t, 7*24*time.Hour, opts.Policies.ExpirationNotification)
+
+	tier = "premium"
+	_, err = writeOptions()
+	assert.Error(t, err)
+
+	paramType, tier = "binary", ""
+	_, err = writeOptions()
+	assert.Error(t, err)
+	paramType = ""
+
+	tier, expiresAt = "", ""
+	_, err = writeOptions()
+	assert.EqualError(t, err, "--expiration-notification needs --expires-at")
+}
@@ -0,0 +1,372 @@
+// Package config loads chamber's configuration files: the user's file, at
+// $XDG_CONFIG_HOME/chamber/config.yaml, and a project's .chamber.yaml, found in
+// the working directory or the nearest parent with one. Both may define named
+// profiles, which set how chamber reaches a backend, and the project file takes
+// precedence over the user file, setting by setting.
+//
+// Since a project file comes with whatever repository is checked out, it may
+// only set the backend, and the other settings deciding where secrets are
+// sent, if the user file trusts it, as with git's safe.directory:
+//
+//	trusted_projects:
+//	  - /src/app
+//
+//	profile: dev
+//	profiles:
+//	  dev:
+//	    backend: ssm
+//	    region: us-east-1
+//	  prod:
+//	    backend: s3-kms
+//	    bucket: prod-secrets
+//	    kms_key_alias: prod-chamber
+//	    services: [app, app/web]
+//	    routes:
+//	      - prefix: payments
+//	        backend: secretsmanager
+//
+// Flags and $CHAMBER_* environment variables take precedence over both files.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+const (
+	// ProjectFileName is the name of a project's config file.
+	ProjectFileName = ".chamber.yaml"
+	// ProfileEnvVar selects a profile when --profile isn't given.
+	ProfileEnvVar = "CHAMBER_PROFILE"
+)
+
+// Profile is a named set of settings for reaching a backend. Empty fields are
+// unset, and fall back to the user file, then to chamber's defaults.
+type Profile struct {
+	Backend     string `yaml:"backend,omitempty"`
+	Bucket      string `yaml:"bucket,omitempty"`
+	KMSKeyAlias string `yaml:"kms_key_alias,omitempty"`
+	Region      string `yaml:"region,omitempty"`
+	// RoleARN is an IAM role to assume, e.g. in another account.
+	RoleARN string `yaml:"role_arn,omitempty"`
+	// Endpoint is a custom AWS endpoint for the backend's service.
+	Endpoint  string `yaml:"endpoint,omitempty"`
+	Retries   *int   `yaml:"retries,omitempty"`
+	RetryMode string `yaml:"retry_mode,omitempty"`
+	// Services are used by exec when it's given none.
+	Services []string `yaml:"services,omitempty"`
+	// KeyMapping is used by exec, env and export when no mapping flags are
+	// given. See environ.KeyMapping.
+	KeyMapping *KeyMapping `yaml:"key_mapping,omitempty"`
+	// Routes send services to other backends, by the longest prefix
+	// matching the service. Services matching no route use the profile's
+	// backend.
+	Routes []Route `yaml:"routes,omitempty"`
+	// Replicas are mirrored every write and delete. See Replica.
+	Replicas []Replica `yaml:"replicas,omitempty"`
+}
+
+// Replica is a store which writes and deletes are mirrored to. Its settings
+// are those of Profile, or else of the profile it's in, with Region and
+// RoleARN taking precedence.
+type Replica struct {
+	Profile string `yaml:"profile,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+	RoleARN string `yaml:"role_arn,omitempty"`
+}
+
+// Name describes r for messages, e.g. "profile dr in us-west-2".
+func (r Replica) Name() string {
+	var parts []string
+	if r.Profile != "" {
+		parts = append(parts, "profile "+r.Profile)
+	}
+	if r.Region != "" {
+		parts = append(parts, "in "+r.Region)
+	}
+	if r.RoleARN != "" {
+		parts = append(parts, "as "+r.RoleARN)
+	}
+	if len(parts) == 0 {
+		return "replica"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Route sends the services under Prefix, e.g. "payments" or "payments/*", to
+// a backend other than the profile's. Retry settings are the profile's, as is
+// the region unless Region is set.
+type Route struct {
+	Prefix      string `yaml:"prefix"`
+	Backend     string `yaml:"backend"`
+	Bucket      string `yaml:"bucket,omitempty"`
+	KMSKeyAlias string `yaml:"kms_key_alias,omitempty"`
+	Region      string `yaml:"region,omitempty"`
+	RoleARN     string `yaml:"role_arn,omitempty"`
+	Endpoint    string `yaml:"endpoint,omitempty"`
+}
+
+// KeyMapping selects secrets and names their variables, as the --include,
+// --exclude, --strip-prefix, --prefix and --map flags do.
+type KeyMapping struct {
+	Include     []string          `yaml:"include,omitempty"`
+	Exclude     []string          `yaml:"exclude,omitempty"`
+	StripPrefix string            `yaml:"strip_prefix,omitempty"`
+	Prefix      string            `yaml:"prefix,omitempty"`
+	Map         map[string]string `yaml:"map,omitempty"`
+}
+
+// File is the contents of a config file. Other sections, such as analytics,
+// are read by the packages they configure.
+type File struct {
+	// Profile is the profile used when none is selected.
+	Profile  string             `yaml:"profile,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// TrustedProjects are the directories whose project files may set any
+	// setting, or "*" for all of them. It's only read from the user file.
+	TrustedProjects []string `yaml:"trusted_projects,omitempty"`
+}
+
+// trusts reports whether f trusts the project file at path
+func (f *File) trusts(path string) bool {
+	if f == nil {
+		return false
+	}
+	dir := filepath.Dir(path)
+	for _, trusted := range f.TrustedProjects {
+		if trusted == "*" || filepath.Clean(trusted) == dir || filepath.Clean(trusted) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// untrustedSettings returns the settings in f which an untrusted project file
+// may not set, as "profile.setting"
+func (f *File) untrustedSettings() []string {
+	var settings []string
+	for name, p := range f.Profiles {
+		for _, setting := range p.untrustedSettings() {
+			settings = append(settings, name+"."+setting)
+		}
+	}
+	sort.Strings(settings)
+	return settings
+}
+
+// Config is the merged contents of the config files.
+type Config struct {
+	// UserFile and ProjectFile are the files read; either may be empty if
+	// there's no such file.
+	UserFile    string
+	ProjectFile string
+	// DefaultProfile is the profile used when none is selected.
+	DefaultProfile string
+	// Profiles are the merged profiles, by name.
+	Profiles map[string]Profile
+	// Sources are the files defining each profile, user file first.
+	Sources map[string][]string
+}
+
+// UserFile returns the path of the user's chamber config file:
+// $XDG_CONFIG_HOME/chamber/config.yaml, or ~/.config/chamber/config.yaml.
+func UserFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "chamber", "config.yaml")
+}
+
+// FindProjectFile returns the path of the .chamber.yaml in dir or its nearest
+// parent with one, or "" if there's none.
+func FindProjectFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user's config file and the project file for the working
+// directory. Missing files are skipped.
+func Load() (*Config, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	projectFile, err := FindProjectFile(wd)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFiles(UserFile(), projectFile)
+}
+
+// LoadFiles reads userFile and then projectFile, which may be empty or
+// missing, merging the project's settings over the user's. It fails if the
+// project file sets the backend, or another setting deciding where secrets are
+// sent, without the user file trusting it.
+func LoadFiles(userFile, projectFile string) (*Config, error) {
+	c := &Config{
+		Profiles: map[string]Profile{},
+		Sources:  map[string][]string{},
+	}
+	var user *File
+	for _, path := range []string{userFile, projectFile} {
+		f, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			continue
+		}
+		if path == userFile {
+			c.UserFile = path
+			user = f
+		} else {
+			c.ProjectFile = path
+			if settings := f.untrustedSettings(); len(settings) > 0 && !user.trusts(path) {
+				return nil, fmt.Errorf("%s sets %s, which only trusted project files may set: add %s to trusted_projects in %s to trust it", path, strings.Join(settings, ", "), filepath.Dir(path), userFile)
+			}
+		}
+		if f.Profile != "" {
+			c.DefaultProfile = f.Profile
+		}
+		for name, p := range f.Profiles {
+			c.Profiles[name] = c.Profiles[name].merge(p)
+			c.Sources[name] = append(c.Sources[name], path)
+		}
+	}
+	if c.DefaultProfile != "" {
+		if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("default profile `%s` is not defined", c.DefaultProfile)
+		}
+	}
+	return c, nil
+}
+
+// ReadFile parses the config file at path, returning nil if path is empty or
+// doesn't exist.
+func ReadFile(path string) (*File, error) {
+	if path == "" {
+		return nil, nil
+	}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var f File
+	if err := yaml.Unmarshal(contents, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// ProfileNames returns the sorted names of the profiles.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the profile called name, or the default profile if name is
+// empty. With no name and no default, it returns an empty profile, so that
+// chamber works the same without a config file.
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile `%s` is not defined", name)
+	}
+	return p, nil
+}
+
+// untrustedSettings returns the settings set in p which decide where secrets
+// are read from and written to, or which plugin is run: only the services, key
+// mapping, region and retry settings are left out.
+func (p Profile) untrustedSettings() []string {
+	var settings []string
+	for _, setting := range []struct {
+		name string
+		set  bool
+	}{
+		{"backend", p.Backend != ""},
+		{"bucket", p.Bucket != ""},
+		{"kms_key_alias", p.KMSKeyAlias != ""},
+		{"role_arn", p.RoleARN != ""},
+		{"endpoint", p.Endpoint != ""},
+		{"routes", len(p.Routes) > 0},
+		{"replicas", len(p.Replicas) > 0},
+	} {
+		if setting.set {
+			settings = append(settings, setting.name)
+		}
+	}
+	return settings
+}
+
+// merge returns p with every setting set in over replaced.
+func (p Profile) merge(over Profile) Profile {
+	setString := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	setString(&p.Backend, over.Backend)
+	setString(&p.Bucket, over.Bucket)
+	setString(&p.KMSKeyAlias, over.KMSKeyAlias)
+	setString(&p.Region, over.Region)
+	setString(&p.RoleARN, over.RoleARN)
+	setString(&p.Endpoint, over.Endpoint)
+	setString(&p.RetryMode, over.RetryMode)
+	if over.Retries != nil {
+		p.Retries = over.Retries
+	}
+	if len(over.Services) > 0 {
+		p.Services = over.Services
+	}
+	if over.KeyMapping != nil {
+		p.KeyMapping = over.KeyMapping
+	}
+	if len(over.Routes) > 0 {
+		p.Routes = over.Routes
+	}
+	if len(over.Replicas) > 0 {
+		p.Replicas = over.Replicas
+	}
+	return p
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, contents string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	userFile := writeFile(t, filepath.Join(dir, "user", "config.yaml"), `
+profile: dev
+trusted_projects:
+  - `+filepath.Join(dir, "project")+`
+analytics:
+  enabled: false
+profiles:
+  dev:
+    backend: ssm
+    region: us-east-1
+    retries: 3
+  prod:
+    backend: s3-kms
+    bucket: user-bucket
+    kms_key_alias: prod-key
+    services: [app]
+    role_arn: arn:aws:iam::123456789012:role/chamber
+    replicas:
+      - region: us-west-2
+      - profile: dr
+`)
+	projectFile := writeFile(t, filepath.Join(dir, "project", ProjectFileName), `
+profile: prod
+profiles:
+  prod:
+    bucket: project-bucket
+    retries: 0
+    key_mapping:
+      prefix: APP_
+      map:
+        db_url: DATABASE_URL
+  staging:
+    backend: secretsmanager
+    routes:
+      - prefix: payments/*
+        backend: s3-kms
+        bucket: payments-secrets
+`)
+
+	t.Run("user file only", func(t *testing.T) {
+		c, err := LoadFiles(userFile, "")
+		require.NoError(t, err)
+		assert.Equal(t, userFile, c.UserFile)
+		assert.Equal(t, "", c.ProjectFile)
+		assert.Equal(t, []string{"dev", "prod"}, c.ProfileNames())
+
+		p, err := c.Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, "ssm", p.Backend)
+		assert.Equal(t, "us-east-1", p.Region)
+		require.NotNil(t, p.Retries)
+		assert.Equal(t, 3, *p.Retries)
+	})
+
+	t.Run("the project file takes precedence, setting by setting", func(t *testing.T) {
+		c, err := LoadFiles(userFile, projectFile)
+		require.NoError(t, err)
+		assert.Equal(t, projectFile, c.ProjectFile)
+		assert.Equal(t, "prod", c.DefaultProfile)
+		assert.Equal(t, []string{"dev", "prod", "staging"}, c.ProfileNames())
+		assert.Equal(t, []string{userFile, projectFile}, c.Sources["prod"])
+		assert.Equal(t, []string{projectFile}, c.Sources["staging"])
+
+		p, err := c.Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, "s3-kms", p.Backend)
+		assert.Equal(t, "project-bucket", p.Bucket)
+		assert.Equal(t, "prod-key", p.KMSKeyAlias)
+		assert.Equal(t, []string{"app"}, p.Services)
+		require.NotNil(t, p.Retries)
+		assert.Equal(t, 0, *p.Retries)
+		require.NotNil(t, p.KeyMapping)
+		assert.Equal(t, "APP_", p.KeyMapping.Prefix)
+		assert.Equal(t, map[string]string{"db_url": "DATABASE_URL"}, p.KeyMapping.Map)
+
+		assert.Equal(t, "arn:aws:iam::123456789012:role/chamber", p.RoleARN)
+		assert.Equal(t, []Replica{{Region: "us-west-2"}, {Profile: "dr"}}, p.Replicas)
+
+		p, err = c.Profile("dev")
+		require.NoError(t, err)
+		assert.Equal(t, "ssm", p.Backend)
+
+		p, err = c.Profile("staging")
+		require.NoError(t, err)
+		assert.Equal(t, []Route{{Prefix: "payments/*", Backend: "s3-kms", Bucket: "payments-secrets"}}, p.Routes)
+	})
+
+	t.Run("untrusted project files", func(t *testing.T) {
+		untrusted := writeFile(t, filepath.Join(dir, "untrusted", ProjectFileName), `
+profile: prod
+profiles:
+  prod:
+    region: us-west-2
+    retries: 1
+    services: [app]
+`)
+		c, err := LoadFiles(userFile, untrusted)
+		require.NoError(t, err)
+		p, err := c.Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, "s3-kms", p.Backend)
+		assert.Equal(t, "us-west-2", p.Region)
+
+		writeFile(t, untrusted, `
+profiles:
+  prod:
+    endpoint: https://attacker.example.com
+    replicas:
+      - role_arn: arn:aws:iam::210987654321:role/chamber
+  plugin:
+    backend: evil
+`)
+		_, err = LoadFiles(userFile, untrusted)
+		assert.EqualError(t, err, untrusted+" sets plugin.backend, prod.endpoint, prod.replicas, which only trusted project files may set: add "+filepath.Dir(untrusted)+" to trusted_projects in "+userFile+" to trust it")
+
+		_, err = LoadFiles("", untrusted)
+		assert.Error(t, err)
+
+		trustsAll := writeFile(t, filepath.Join(dir, "trusting", "config.yaml"), "trusted_projects: ['*']\n")
+		_, err = LoadFiles(trustsAll, untrusted)
+		assert.NoError(t, err)
+
+		// only the user file can trust a project
+		writeFile(t, untrusted, "trusted_projects: ['*']\nprofiles:\n  plugin:\n    backend: evil\n")
+		_, err = LoadFiles("", untrusted)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing files are skipped", func(t *testing.T) {
+		c, err := LoadFiles(filepath.Join(dir, "missing.yaml"), "")
+		require.NoError(t, err)
+		assert.Equal(t, "", c.UserFile)
+		assert.Empty(t, c.ProfileNames())
+
+		p, err := c.Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, Profile{}, p)
+	})
+
+	t.Run("undefined profiles", func(t *testing.T) {
+		c, err := LoadFiles(userFile, "")
+		require.NoError(t, err)
+		_, err = c.Profile("nope")
+		assert.EqualError(t, err, "profile `nope` is not defined")
+
+		badDefault := writeFile(t, filepath.Join(dir, "bad", "config.yaml"), "profile: nope\n")
+		_, err = LoadFiles(badDefault, "")
+		assert.EqualError(t, err, "default profile `nope` is not defined")
+	})
+
+	t.Run("malformed files", func(t *testing.T) {
+		bad := writeFile(t, filepath.Join(dir, "malformed", "config.yaml"), "profiles: [\n")
+		_, err := LoadFiles(bad, "")
+		assert.ErrorContains(t, err, "failed to parse "+bad)
+	})
+}
+
+func TestFindProjectFile(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0700))
+
+	path, err := FindProjectFile(nested)
+	require.NoError(t, err)
+	assert.NotEqual(t, filepath.Join(dir, ProjectFileName), path)
+
+	projectFile := writeFile(t, filepath.Join(dir, "a", ProjectFileName), "")
+	path, err = FindProjectFile(nested)
+	require.NoError(t, err)
+	assert.Equal(t, projectFile, path)
+}
+
+func TestUserFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	assert.Equal(t, filepath.Join("/xdg", "chamber", "config.yaml"), UserFile())
+}
+
+func TestReplicaName(t *testing.T) {
+	assert.Equal(t, "in us-west-2", Replica{Region: "us-west-2"}.Name())
+	assert.Equal(t, "profile dr in us-west-2", Replica{Profile: "dr", Region: "us-west-2"}.Name())
+	assert.Equal(t, "as arn:aws:iam::123456789012:role/chamber", Replica{RoleARN: "arn:aws:iam::123456789012:role/chamber"}.Name())
+	assert.Equal(t, "replica", Replica{}.Name())
+}
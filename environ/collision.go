@@ -0,0 +1,77 @@
+package environ
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when a variable is supplied more than
+// once.
+type CollisionPolicy string
+
+const (
+	// CollisionWarn keeps the latest value and reports the collision. This is
+	// the default.
+	CollisionWarn CollisionPolicy = "warn"
+	// CollisionError fails the load.
+	CollisionError CollisionPolicy = "error"
+	// CollisionFirstWins silently keeps the earliest value.
+	CollisionFirstWins CollisionPolicy = "first-wins"
+	// CollisionLastWins silently keeps the latest value.
+	CollisionLastWins CollisionPolicy = "last-wins"
+)
+
+// CollisionPolicies lists every valid CollisionPolicy.
+var CollisionPolicies = []CollisionPolicy{CollisionWarn, CollisionError, CollisionFirstWins, CollisionLastWins}
+
+// ParseCollisionPolicy parses a policy name, as used on the command line.
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	for _, p := range CollisionPolicies {
+		if CollisionPolicy(strings.ToLower(s)) == p {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid collision policy `%s`; must be one of %v", s, CollisionPolicies)
+}
+
+// keepsExisting returns whether the value already set wins under p.
+func (p CollisionPolicy) keepsExisting() bool {
+	return p == CollisionFirstWins
+}
+
+// Collision describes a variable that was supplied more than once.
+type Collision struct {
+	// Name is the variable name.
+	Name string
+	// Service is the service supplying the new value.
+	Service string
+	// Previous is the service that supplied the existing value, or "" if it
+	// was inherited from the parent environment.
+	Previous string
+	// Policy is the policy used to resolve the collision.
+	Policy CollisionPolicy
+}
+
+// Parent returns whether the collision was with the parent environment.
+func (c Collision) Parent() bool {
+	return c.Previous == ""
+}
+
+func (c Collision) String() string {
+	if c.Parent() {
+		return fmt.Sprintf("service %s overwriting environment variable %s", c.Service, c.Name)
+	}
+	return fmt.Sprintf("service %s overwriting environment variable %s from service %s", c.Service, c.Name, c.Previous)
+}
+
+// ErrCollision is returned when a collision is resolved with CollisionError.
+type ErrCollision struct {
+	Collision
+}
+
+func (e ErrCollision) Error() string {
+	if e.Parent() {
+		return fmt.Sprintf("service %s supplies %s, which is already set in the parent env", e.Service, e.Name)
+	}
+	return fmt.Sprintf("services %s and %s both supply %s", e.Previous, e.Service, e.Name)
+}
@@ -2,7 +2,9 @@ package environ
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/segmentio/chamber/v3/store"
@@ -74,10 +76,6 @@ func key(s string) string {
 }
 
 // transforms a secret key to an env var name, i.e. upppercase, substitute `-` -> `_`
-func secretKeyToEnvVarName(k string) string {
-	return normalizeEnvVarName(key(k))
-}
-
 func normalizeEnvVarName(k string) string {
 	return strings.Replace(strings.ToUpper(k), "-", "_", -1)
 }
@@ -90,7 +88,8 @@ func (e *Environ) load(ctx context.Context, s store.Store, service string, colli
 		return err
 	}
 	for _, rawSecret := range rawSecrets {
-		envVarKey := secretKeyToEnvVarName(rawSecret.Key)
+		store.AddKnownSecrets(rawSecret.Value)
+		envVarKey := normalizeEnvVarName(key(rawSecret.Key))
 
 		if e.IsSet(envVarKey) {
 			*collisions = append(*collisions, envVarKey)
@@ -110,29 +109,176 @@ func (e *Environ) Load(ctx context.Context, s store.Store, service string, colli
 // are the only ones substituted. If there are any env vars in s that are also in e, but don't have their value
 // set to valueExpected, this is an error.
 func (e *Environ) LoadStrict(ctx context.Context, s store.Store, valueExpected string, pristine bool, services ...string) error {
-	return e.loadStrict(ctx, s, valueExpected, pristine, services...)
+	_, err := e.LoadServicesStrict(ctx, s, valueExpected, pristine, services, LoadOptions{OnCollision: CollisionLastWins})
+	return err
 }
 
-func (e *Environ) loadStrict(ctx context.Context, s store.Store, valueExpected string, pristine bool, services ...string) error {
-	for _, service := range services {
-		rawSecrets, err := s.ListRaw(ctx, utils.NormalizeService(service))
-		if err != nil {
-			return err
+// LoadOptions controls how LoadServices and LoadServicesStrict select, name
+// and merge secrets.
+type LoadOptions struct {
+	// Mapping selects secrets and names variables; nil uses every secret.
+	Mapping *KeyMapping
+	// Normalize turns secret keys without an explicit mapping into variable
+	// names. Defaults to upper case with `-` replaced by `_`.
+	Normalize func(string) string
+	// OnCollision resolves two services supplying the same variable. Defaults
+	// to CollisionWarn.
+	OnCollision CollisionPolicy
+	// OnParentCollision resolves a service supplying a variable that is already
+	// in the environment it's loaded into. Defaults to CollisionWarn. Ignored in
+	// strict mode, where overriding the parent is the point.
+	OnParentCollision CollisionPolicy
+	// Concurrency is the number of services fetched at once. Values below 1
+	// fetch one at a time. Services are still merged in the order given.
+	Concurrency int
+}
+
+// LoadResult describes what LoadServices and LoadServicesStrict loaded.
+type LoadResult struct {
+	// Sources maps each variable set from the store to the service supplying it.
+	Sources map[string]string
+	// Collisions lists every collision, with the policy used to resolve it.
+	Collisions []Collision
+}
+
+// loadedSecret is a secret along with its variable name and service
+type loadedSecret struct {
+	store.RawSecret
+	Name    string
+	Service string
+}
+
+// LoadServices loads the secrets of each service from s into e, in order.
+// Collisions between services and with variables already in e are resolved as
+// set in opts.
+func (e *Environ) LoadServices(ctx context.Context, s store.Store, services []string, opts LoadOptions) (LoadResult, error) {
+	secrets, result, err := mergeServices(ctx, s, services, opts)
+	if err != nil {
+		return result, err
+	}
+
+	for _, secret := range secrets {
+		if e.IsSet(secret.Name) {
+			c := Collision{Name: secret.Name, Service: secret.Service, Policy: policyOrDefault(opts.OnParentCollision)}
+			result.Collisions = append(result.Collisions, c)
+			if c.Policy == CollisionError {
+				return result, ErrCollision{c}
+			}
+			if c.Policy.keepsExisting() {
+				continue
+			}
+		}
+		e.Set(secret.Name, secret.Value)
+		result.Sources[secret.Name] = secret.Service
+	}
+	return result, nil
+}
+
+// LoadServicesStrict is like LoadStrict, but selects, names and merges secrets
+// as set in opts.
+func (e *Environ) LoadServicesStrict(ctx context.Context, s store.Store, valueExpected string, pristine bool, services []string, opts LoadOptions) (LoadResult, error) {
+	// gather secrets from every service before substituting, so that an expected
+	// key only needs to be present in one of them
+	secrets, result, err := mergeServices(ctx, s, services, opts)
+	if err != nil {
+		return result, err
+	}
+
+	// secrets are already named, so substitute them under those exact names
+	parentMap := e.Map()
+	rawSecrets := make([]store.RawSecret, len(secrets))
+	for i, secret := range secrets {
+		rawSecrets[i] = store.RawSecret{Key: secret.Name, Value: secret.Value}
+		if parentMap[secret.Name] == valueExpected {
+			result.Sources[secret.Name] = secret.Service
 		}
-		err = e.loadStrictOne(rawSecrets, valueExpected, pristine)
+	}
+	return result, e.loadStrictOne(rawSecrets, &KeyMapping{Map: identityMap(secrets)}, valueExpected, pristine)
+}
+
+// mergeServices lists the secrets of each service, names them, and resolves
+// collisions between services in the order the services are given. Secrets are returned in the order
+// they were first supplied.
+func mergeServices(ctx context.Context, s store.Store, services []string, opts LoadOptions) ([]loadedSecret, LoadResult, error) {
+	result := LoadResult{Sources: map[string]string{}}
+	normalize := opts.Normalize
+	if normalize == nil {
+		normalize = normalizeEnvVarName
+	}
+
+	services = slices.Clone(services)
+	for i := range services {
+		services[i] = utils.NormalizeService(services[i])
+	}
+
+	// fetch in parallel, but merge in order, so precedence stays deterministic
+	serviceSecrets := make([][]store.RawSecret, len(services))
+	errs := make([]error, len(services))
+	utils.Parallel(len(services), opts.Concurrency, func(i int) {
+		serviceSecrets[i], errs[i] = s.ListRaw(ctx, services[i])
+	})
+	for i, err := range errs {
 		if err != nil {
-			return err
+			errs[i] = fmt.Errorf("service %s: %w", services[i], err)
 		}
 	}
-	return nil
+	if err := errors.Join(errs...); err != nil {
+		return nil, result, err
+	}
+
+	var secrets []loadedSecret
+	index := map[string]int{}
+	for i, service := range services {
+		for _, rawSecret := range serviceSecrets[i] {
+			store.AddKnownSecrets(rawSecret.Value)
+			name, ok := opts.Mapping.Name(rawSecret.Key, normalize)
+			if !ok {
+				continue
+			}
+			secret := loadedSecret{RawSecret: rawSecret, Name: name, Service: service}
+
+			existing, ok := index[name]
+			if !ok {
+				index[name] = len(secrets)
+				secrets = append(secrets, secret)
+				continue
+			}
+
+			c := Collision{Name: name, Service: service, Previous: secrets[existing].Service, Policy: policyOrDefault(opts.OnCollision)}
+			result.Collisions = append(result.Collisions, c)
+			if c.Policy == CollisionError {
+				return nil, result, ErrCollision{c}
+			}
+			if !c.Policy.keepsExisting() {
+				secrets[existing] = secret
+			}
+		}
+	}
+	return secrets, result, nil
+}
+
+func policyOrDefault(p CollisionPolicy) CollisionPolicy {
+	if p == "" {
+		return CollisionWarn
+	}
+	return p
+}
+
+// identityMap maps the name of every secret to itself
+func identityMap(secrets []loadedSecret) map[string]string {
+	m := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		m[secret.Name] = secret.Name
+	}
+	return m
 }
 
-func (e *Environ) loadStrictOne(rawSecrets []store.RawSecret, valueExpected string, pristine bool) error {
+func (e *Environ) loadStrictOne(rawSecrets []store.RawSecret, mapping *KeyMapping, valueExpected string, pristine bool) error {
 	parentMap := e.Map()
 	parentExpects := map[string]struct{}{}
 	for k, v := range parentMap {
 		if v == valueExpected {
-			if k != normalizeEnvVarName(k) {
+			if k != normalizeEnvVarName(k) && !mapping.isMapped(k) {
 				return ErrExpectedKeyUnnormalized{Key: k, ValueExpected: valueExpected}
 			}
 			// TODO: what if this key isn't chamber-compatible but could collide? MY_cool_var vs my-cool-var
@@ -142,7 +288,10 @@ func (e *Environ) loadStrictOne(rawSecrets []store.RawSecret, valueExpected stri
 
 	envVarKeysAdded := map[string]struct{}{}
 	for _, rawSecret := range rawSecrets {
-		envVarKey := secretKeyToEnvVarName(rawSecret.Key)
+		envVarKey, ok := mapping.Name(rawSecret.Key, normalizeEnvVarName)
+		if !ok {
+			continue
+		}
 
 		parentVal, parentOk := parentMap[envVarKey]
 		// skip injecting secrets that are not present in the parent
@@ -151,7 +300,7 @@ func (e *Environ) loadStrictOne(rawSecrets []store.RawSecret, valueExpected stri
 		}
 		delete(parentExpects, envVarKey)
 		if parentVal != valueExpected {
-			return ErrStoreUnexpectedValue{Key: envVarKey, ValueExpected: valueExpected, ValueActual: parentVal}
+			return ErrStoreUnexpectedValue{Key: envVarKey, ValueExpected: valueExpected, ValueActual: store.Redacted(parentVal)}
 		}
 		envVarKeysAdded[envVarKey] = struct{}{}
 		e.Set(envVarKey, rawSecret.Value)
@@ -176,7 +325,8 @@ type ErrStoreUnexpectedValue struct {
 	// store-style key
 	Key           string
 	ValueExpected string
-	ValueActual   string
+	// ValueActual is masked in the message, since it may be a secret
+	ValueActual store.Redacted
 }
 
 func (e ErrStoreUnexpectedValue) Error() string {
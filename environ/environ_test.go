@@ -1,6 +1,8 @@
 package environ
 
 import (
+	"context"
+	"errors"
 	"sort"
 	"testing"
 
@@ -84,6 +86,18 @@ func TestEnvironStrict(t *testing.T) {
 			},
 			expectedErr: ErrExpectedKeyUnnormalized{Key: "DB_username", ValueExpected: "chamberme"},
 		},
+
+		{
+			name: "parent with unexpected value",
+			e: fromMap(map[string]string{
+				"HOME":        "/tmp",
+				"DB_PASSWORD": "hunter22",
+			}),
+			secrets: map[string]string{
+				"db_password": "hunter22",
+			},
+			expectedErr: ErrStoreUnexpectedValue{Key: "DB_PASSWORD", ValueExpected: "chamberme", ValueActual: "hunter22"},
+		},
 	}
 
 	for _, tc := range cases {
@@ -99,7 +113,7 @@ func TestEnvironStrict(t *testing.T) {
 			if strictVal == "" {
 				strictVal = "chamberme"
 			}
-			err := tc.e.loadStrictOne(rawSecrets, strictVal, tc.pristine)
+			err := tc.e.loadStrictOne(rawSecrets, nil, strictVal, tc.pristine)
 			if err != nil {
 				assert.EqualValues(t, tc.expectedErr, err)
 			} else {
@@ -109,6 +123,11 @@ func TestEnvironStrict(t *testing.T) {
 	}
 }
 
+func TestErrStoreUnexpectedValueMasksValue(t *testing.T) {
+	err := ErrStoreUnexpectedValue{Key: "DB_PASSWORD", ValueExpected: "chamberme", ValueActual: "hunter22"}
+	assert.Equal(t, "parent env has DB_PASSWORD, but was expecting value `chamberme`, not `*****`", err.Error())
+}
+
 func TestMap(t *testing.T) {
 	cases := []struct {
 		name string
@@ -184,3 +203,151 @@ func TestFromMap(t *testing.T) {
 		})
 	}
 }
+
+// servicesStore serves ListRaw from a fixed map of service to secrets
+type servicesStore struct {
+	store.NullStore
+	services map[string]map[string]string
+	errs     map[string]error
+}
+
+func (s *servicesStore) ListRaw(ctx context.Context, service string) ([]store.RawSecret, error) {
+	if err := s.errs[service]; err != nil {
+		return nil, err
+	}
+	rawSecrets := []store.RawSecret{}
+	for k, v := range s.services[service] {
+		rawSecrets = append(rawSecrets, store.RawSecret{Key: "/" + service + "/" + k, Value: v})
+	}
+	return rawSecrets, nil
+}
+
+func TestLoadStrictMultipleServices(t *testing.T) {
+	s := &servicesStore{services: map[string]map[string]string{
+		"global": {"db_host": "global-db", "log_level": "info"},
+		"app":    {"db_host": "app-db", "api_key": "secret"},
+	}}
+	e := fromMap(map[string]string{
+		"HOME":      "/tmp",
+		"DB_HOST":   "chamberme",
+		"LOG_LEVEL": "chamberme",
+		"API_KEY":   "chamberme",
+	})
+
+	err := e.LoadStrict(context.Background(), s, "chamberme", true, "global", "app")
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[string]string{
+		"DB_HOST":   "app-db",
+		"LOG_LEVEL": "info",
+		"API_KEY":   "secret",
+	}, e.Map())
+}
+
+func TestLoadServicesCollisions(t *testing.T) {
+	s := &servicesStore{services: map[string]map[string]string{
+		"global": {"db_host": "global-db", "log_level": "info"},
+		"app":    {"db_host": "app-db", "home": "/app"},
+	}}
+
+	cases := []struct {
+		name              string
+		onCollision       CollisionPolicy
+		onParentCollision CollisionPolicy
+		expectedEnvMap    map[string]string
+		expectedSources   map[string]string
+		expectedErr       error
+	}{
+		{
+			name: "defaults warn and take the last value",
+			expectedEnvMap: map[string]string{
+				"HOME":      "/app",
+				"DB_HOST":   "app-db",
+				"LOG_LEVEL": "info",
+			},
+			expectedSources: map[string]string{
+				"HOME":      "app",
+				"DB_HOST":   "app",
+				"LOG_LEVEL": "global",
+			},
+		},
+		{
+			name:              "first wins",
+			onCollision:       CollisionFirstWins,
+			onParentCollision: CollisionFirstWins,
+			expectedEnvMap: map[string]string{
+				"HOME":      "/tmp",
+				"DB_HOST":   "global-db",
+				"LOG_LEVEL": "info",
+			},
+			expectedSources: map[string]string{
+				"DB_HOST":   "global",
+				"LOG_LEVEL": "global",
+			},
+		},
+		{
+			name:        "error between services",
+			onCollision: CollisionError,
+			expectedErr: ErrCollision{Collision{Name: "DB_HOST", Service: "app", Previous: "global", Policy: CollisionError}},
+		},
+		{
+			name:              "error with parent",
+			onParentCollision: CollisionError,
+			expectedErr:       ErrCollision{Collision{Name: "HOME", Service: "app", Policy: CollisionError}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := fromMap(map[string]string{"HOME": "/tmp"})
+			result, err := e.LoadServices(context.Background(), s, []string{"global", "app"}, LoadOptions{
+				OnCollision:       tc.onCollision,
+				OnParentCollision: tc.onParentCollision,
+			})
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.EqualValues(t, tc.expectedEnvMap, e.Map())
+			assert.EqualValues(t, tc.expectedSources, result.Sources)
+			assert.Len(t, result.Collisions, 2)
+		})
+	}
+}
+
+func TestParseCollisionPolicy(t *testing.T) {
+	for _, p := range CollisionPolicies {
+		parsed, err := ParseCollisionPolicy(string(p))
+		assert.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	_, err := ParseCollisionPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestLoadServicesConcurrentErrors(t *testing.T) {
+	s := &servicesStore{
+		services: map[string]map[string]string{
+			"a": {"key": "a"},
+			"b": {"key": "b"},
+			"c": {"key": "c"},
+		},
+		errs: map[string]error{
+			"b": store.ErrSecretNotFound,
+			"d": errors.New("access denied"),
+		},
+	}
+
+	e := Environ{}
+	_, err := e.LoadServices(context.Background(), s, []string{"a", "b", "c", "d"}, LoadOptions{Concurrency: 4})
+	assert.ErrorIs(t, err, store.ErrSecretNotFound)
+	assert.ErrorContains(t, err, "service b")
+	assert.ErrorContains(t, err, "service d")
+
+	// merge order is the order given, however the fetches complete
+	e = Environ{}
+	result, err := e.LoadServices(context.Background(), s, []string{"a", "c"}, LoadOptions{Concurrency: 4, OnCollision: CollisionLastWins})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"KEY": "c"}, e.Map())
+	assert.Equal(t, map[string]string{"KEY": "c"}, result.Sources)
+}
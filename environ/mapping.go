@@ -0,0 +1,94 @@
+package environ
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// KeyMapping selects secrets and turns their keys into variable names. The
+// steps are applied in order:
+//
+//  1. keys not matching any Include glob are dropped (if Include is set)
+//  2. keys matching any Exclude glob are dropped
+//  3. keys present in Map are renamed to exactly the mapped name, and skip
+//     the remaining steps
+//  4. StripPrefix is removed from the start of the key
+//  5. the key is normalized
+//  6. Prefix is added to the start of the name
+//
+// Globs use path.Match syntax and, like everything else here, apply to the
+// secret key without its service path. A nil *KeyMapping keeps every secret and
+// only normalizes its key.
+type KeyMapping struct {
+	Include     []string
+	Exclude     []string
+	StripPrefix string
+	Prefix      string
+	Map         map[string]string
+}
+
+// Validate returns an error if any of the globs in m are malformed.
+func (m *KeyMapping) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, m.Include...), m.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid key pattern `%s`: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the variable name for secret key k, or false if k is filtered
+// out. Keys which aren't explicitly mapped are passed through normalize, which
+// may be nil to leave them as they are.
+func (m *KeyMapping) Name(k string, normalize func(string) string) (string, bool) {
+	k = key(k)
+	if m == nil {
+		if normalize != nil {
+			k = normalize(k)
+		}
+		return k, true
+	}
+
+	if len(m.Include) > 0 && !matchAny(m.Include, k) {
+		return "", false
+	}
+	if matchAny(m.Exclude, k) {
+		return "", false
+	}
+	if name, ok := m.Map[k]; ok {
+		return name, true
+	}
+
+	name := strings.TrimPrefix(k, m.StripPrefix)
+	if normalize != nil {
+		name = normalize(name)
+	}
+	return m.Prefix + name, true
+}
+
+// isMapped returns whether name is the target of an explicit mapping.
+func (m *KeyMapping) isMapped(name string) bool {
+	if m == nil {
+		return false
+	}
+	for _, v := range m.Map {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, k string) bool {
+	for _, pattern := range patterns {
+		// patterns were checked by Validate, so errors only mean "no match"
+		if ok, _ := path.Match(pattern, k); ok {
+			return true
+		}
+	}
+	return false
+}
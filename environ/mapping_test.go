@@ -0,0 +1,42 @@
+package environ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyMappingName(t *testing.T) {
+	cases := []struct {
+		name     string
+		mapping  *KeyMapping
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"nil mapping normalizes", nil, "/service/db-host", "DB_HOST", true},
+		{"prefix", &KeyMapping{Prefix: "APP_"}, "/service/db_host", "APP_DB_HOST", true},
+		{"strip prefix", &KeyMapping{StripPrefix: "app_"}, "/service/app_db_host", "DB_HOST", true},
+		{"strip and add prefix", &KeyMapping{StripPrefix: "app_", Prefix: "SVC_"}, "/service/app_db_host", "SVC_DB_HOST", true},
+		{"included", &KeyMapping{Include: []string{"db_*"}}, "/service/db_host", "DB_HOST", true},
+		{"not included", &KeyMapping{Include: []string{"db_*"}}, "/service/api_key", "", false},
+		{"excluded", &KeyMapping{Exclude: []string{"*_key"}}, "/service/api_key", "", false},
+		{"exclude wins over include", &KeyMapping{Include: []string{"api_*"}, Exclude: []string{"*_key"}}, "/service/api_key", "", false},
+		{"mapped exactly", &KeyMapping{Prefix: "APP_", Map: map[string]string{"api_key": "ThirdPartyToken"}}, "/service/api_key", "ThirdPartyToken", true},
+		{"mapped but excluded", &KeyMapping{Exclude: []string{"api_key"}, Map: map[string]string{"api_key": "TOKEN"}}, "/service/api_key", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := tc.mapping.Name(tc.key, normalizeEnvVarName)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+}
+
+func TestKeyMappingValidate(t *testing.T) {
+	assert.NoError(t, (*KeyMapping)(nil).Validate())
+	assert.NoError(t, (&KeyMapping{Include: []string{"db_*"}, Exclude: []string{"secret?"}}).Validate())
+	assert.Error(t, (&KeyMapping{Exclude: []string{"[db"}}).Validate())
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// FileAuditSink appends events to a file, one JSON object per line.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ AuditSink = &FileAuditSink{}
+
+// NewFileAuditSink opens path for appending, creating it if needed.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+func (s *FileAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	return s.file.Close()
+}
+
+// WebhookAuditSink POSTs each event as JSON to a URL.
+type WebhookAuditSink struct {
+	URL string
+	// Client is used for requests; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+var _ AuditSink = &WebhookAuditSink{}
+
+func (s *WebhookAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// AuditSinkFunc adapts a function to an AuditSink.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent) error
+
+func (f AuditSinkFunc) Record(ctx context.Context, event AuditEvent) error {
+	return f(ctx, event)
+}
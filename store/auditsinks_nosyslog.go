@@ -0,0 +1,27 @@
+//go:build windows || plan9
+
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SyslogAuditSink is unavailable on this platform.
+type SyslogAuditSink struct{}
+
+var _ AuditSink = &SyslogAuditSink{}
+
+// NewSyslogAuditSink returns an error, since there's no syslog on this
+// platform.
+func NewSyslogAuditSink(tag string) (*SyslogAuditSink, error) {
+	return nil, fmt.Errorf("%w: syslog is not available on this platform", ErrNotImplemented)
+}
+
+func (s *SyslogAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	return fmt.Errorf("%w: syslog is not available on this platform", ErrNotImplemented)
+}
+
+func (s *SyslogAuditSink) Close() error {
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogAuditSink sends events to the local syslog daemon as JSON, at the
+// notice level of the auth facility.
+type SyslogAuditSink struct {
+	w *syslog.Writer
+}
+
+var _ AuditSink = &SyslogAuditSink{}
+
+// NewSyslogAuditSink connects to the local syslog daemon, tagging messages
+// with tag.
+func NewSyslogAuditSink(tag string) (*SyslogAuditSink, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogAuditSink{w: w}, nil
+}
+
+func (s *SyslogAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(line))
+}
+
+// Close closes the connection to syslog.
+func (s *SyslogAuditSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Outcomes of audited operations
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent records an operation on a store. It never includes values.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Identity is who performed the operation, e.g. their STS ARN.
+	Identity string `json:"identity"`
+	// Command is the chamber command, e.g. "chamber write".
+	Command string `json:"command,omitempty"`
+	// Operation is the store operation, e.g. "write" or "delete_tags".
+	Operation string `json:"operation"`
+	Backend   string `json:"backend,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Key       string `json:"key,omitempty"`
+	// Version is the version written or read, if the backend reports it.
+	Version int    `json:"version,omitempty"`
+	Outcome string `json:"outcome"`
+	// Error is the error message of a failed operation, with known secret
+	// values masked.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink records audit events somewhere.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// AuditConfig describes where audit events come from and go to.
+type AuditConfig struct {
+	// Sinks receive every event.
+	Sinks []AuditSink
+	// Identity returns who is performing operations. It's called once, when
+	// the first event is recorded; if it fails, $USER is recorded instead.
+	Identity func(ctx context.Context) (string, error)
+	// Command and Backend are recorded with every event.
+	Command string
+	Backend string
+}
+
+// AuditingStore records every operation which reads values from, or changes,
+// another store in an audit log, by sending an AuditEvent to each sink.
+// Listing services and reading metadata such as tags or history isn't
+// recorded. Failing to record an event is logged, but doesn't fail the
+// operation.
+type AuditingStore struct {
+	Store
+	config AuditConfig
+
+	identityOnce sync.Once
+	identity     string
+}
+
+var (
+	_ Store           = &AuditingStore{}
+	_ BatchReader     = &AuditingStore{}
+	_ BatchWriter     = &AuditingStore{}
+	_ StreamingLister = &AuditingStore{}
+	_ OptionsWriter   = &AuditingStore{}
+	_ Labeler         = &AuditingStore{}
+	_ Reencrypter     = &AuditingStore{}
+)
+
+// NewAuditingStore wraps s so that its operations are recorded as set in
+// config.
+func NewAuditingStore(s Store, config AuditConfig) *AuditingStore {
+	return &AuditingStore{Store: s, config: config}
+}
+
+// STSIdentity returns the ARN of the caller, as reported by STS.
+func STSIdentity(ctx context.Context, cfg aws.Config) (string, error) {
+	resp, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", classifyError(err, "sts:GetCallerIdentity")
+	}
+	return aws.ToString(resp.Arn), nil
+}
+
+// Supports reports what the wrapped store supports.
+func (s *AuditingStore) Supports(c Capability) bool {
+	return Supports(s.Store, c)
+}
+
+func (s *AuditingStore) SetConfig(ctx context.Context, config StoreConfig) error {
+	err := s.Store.SetConfig(ctx, config)
+	s.record(ctx, AuditEvent{Operation: "set_config", Service: ChamberService}, err)
+	return err
+}
+
+func (s *AuditingStore) Write(ctx context.Context, id SecretId, value string) error {
+	writeCtx, written := withWrittenVersions(ctx)
+	err := s.Store.Write(writeCtx, id, value)
+	s.recordWrite(ctx, "write", id, written, err)
+	return err
+}
+
+func (s *AuditingStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+	writeCtx, written := withWrittenVersions(ctx)
+	err := s.Store.WriteWithTags(writeCtx, id, value, tags)
+	s.recordWrite(ctx, "write_with_tags", id, written, err)
+	return err
+}
+
+func (s *AuditingStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error {
+	ow, ok := s.Store.(OptionsWriter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+	}
+	writeCtx, written := withWrittenVersions(ctx)
+	err := ow.WriteWithOptions(writeCtx, id, value, opts)
+	s.recordWrite(ctx, "write_with_options", id, written, err)
+	return err
+}
+
+func (s *AuditingStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	writeCtx, written := withWrittenVersions(ctx)
+	err := WriteMany(writeCtx, s.Store, values)
+	for _, id := range sortedIds(values) {
+		s.recordWrite(ctx, "write_many", id, written, err)
+	}
+	return err
+}
+
+func (s *AuditingStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
+	secret, err := s.Store.Read(ctx, id, version)
+	s.record(ctx, AuditEvent{Operation: "read", Service: id.Service, Key: id.Key, Version: secret.Meta.Version}, err)
+	return secret, err
+}
+
+func (s *AuditingStore) ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error) {
+	secrets, err := ReadMany(ctx, s.Store, ids)
+	for _, id := range ids {
+		s.record(ctx, AuditEvent{Operation: "read_many", Service: id.Service, Key: id.Key, Version: secrets[id].Meta.Version}, err)
+	}
+	return secrets, err
+}
+
+func (s *AuditingStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
+	err := s.Store.WriteTags(ctx, id, tags, deleteOtherTags)
+	s.record(ctx, AuditEvent{Operation: "write_tags", Service: id.Service, Key: id.Key}, err)
+	return err
+}
+
+func (s *AuditingStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
+	err := s.Store.DeleteTags(ctx, id, tagKeys)
+	s.record(ctx, AuditEvent{Operation: "delete_tags", Service: id.Service, Key: id.Key}, err)
+	return err
+}
+
+func (s *AuditingStore) Delete(ctx context.Context, id SecretId) error {
+	err := s.Store.Delete(ctx, id)
+	s.record(ctx, AuditEvent{Operation: "delete", Service: id.Service, Key: id.Key}, err)
+	return err
+}
+
+func (s *AuditingStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
+	return collect(s.ListIter(ctx, service, includeValues))
+}
+
+func (s *AuditingStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	return collect(s.ListRawIter(ctx, service))
+}
+
+// ListIter records listing a service, if values are included.
+func (s *AuditingStore) ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error] {
+	if !includeValues {
+		return ListIter(ctx, s.Store, service, includeValues)
+	}
+	return func(yield func(Secret, error) bool) {
+		var err error
+		defer func() { s.record(ctx, AuditEvent{Operation: "list", Service: service}, err) }()
+		for secret, listErr := range ListIter(ctx, s.Store, service, includeValues) {
+			err = listErr
+			if !yield(secret, listErr) || listErr != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *AuditingStore) ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error] {
+	return func(yield func(RawSecret, error) bool) {
+		var err error
+		defer func() { s.record(ctx, AuditEvent{Operation: "list_raw", Service: service}, err) }()
+		for rawSecret, listErr := range ListRawIter(ctx, s.Store, service) {
+			err = listErr
+			if !yield(rawSecret, listErr) || listErr != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *AuditingStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return ListServicesIter(ctx, s.Store, service, includeSecretName)
+}
+
+func (s *AuditingStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	err := l.LabelVersion(ctx, id, version, labels)
+	s.record(ctx, AuditEvent{Operation: "label", Service: id.Service, Key: id.Key, Version: max(version, 0)}, err)
+	return err
+}
+
+func (s *AuditingStore) Unlabel(ctx context.Context, id SecretId, labels []string) error {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	err := l.Unlabel(ctx, id, labels)
+	s.record(ctx, AuditEvent{Operation: "unlabel", Service: id.Service, Key: id.Key}, err)
+	return err
+}
+
+func (s *AuditingStore) ListLabels(ctx context.Context, id SecretId) (map[int][]string, error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return nil, fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.ListLabels(ctx, id)
+}
+
+func (s *AuditingStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	r, ok := s.Store.(Reencrypter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityReencrypt)
+	}
+	writeCtx, written := withWrittenVersions(ctx)
+	err := r.Reencrypt(writeCtx, id, kmsKey)
+	s.recordWrite(ctx, "reencrypt", id, written, err)
+	return err
+}
+
+// recordWrite records a write, with the version written if the backend
+// reported it
+func (s *AuditingStore) recordWrite(ctx context.Context, operation string, id SecretId, written *writtenVersions, err error) {
+	s.record(ctx, AuditEvent{Operation: operation, Service: id.Service, Key: id.Key, Version: written.get(id)}, err)
+}
+
+// record fills in the common fields of event and sends it to every sink
+func (s *AuditingStore) record(ctx context.Context, event AuditEvent, err error) {
+	s.identityOnce.Do(func() {
+		s.identity = "unknown"
+		if s.config.Identity == nil {
+			return
+		}
+		identity, err := s.config.Identity(ctx)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("audit: failed to get identity: %s", err))
+			if user := os.Getenv("USER"); user != "" {
+				s.identity = "user:" + user
+			}
+			return
+		}
+		s.identity = identity
+	})
+
+	event.Time = time.Now().UTC()
+	event.Identity = s.identity
+	event.Command = s.config.Command
+	event.Backend = s.config.Backend
+	event.Outcome = AuditSuccess
+	if err != nil {
+		event.Outcome = AuditFailure
+		event.Error = Redact(err.Error())
+	}
+
+	var errs []error
+	for _, sink := range s.config.Sinks {
+		errs = append(errs, sink.Record(ctx, event))
+	}
+	if err := errors.Join(errs...); err != nil {
+		slog.Warn(fmt.Sprintf("audit: failed to record %s of %s/%s: %s", event.Operation, event.Service, event.Key, Redact(err.Error())))
+	}
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAuditSink collects events in memory
+type memoryAuditSink struct {
+	events []AuditEvent
+}
+
+func (s *memoryAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// failingWriteStore fails every write with an error including the value
+type failingWriteStore struct {
+	Store
+}
+
+func (s *failingWriteStore) Write(ctx context.Context, id SecretId, value string) error {
+	return fmt.Errorf("invalid value %s", value)
+}
+
+// readCountingStore counts reads of values
+type readCountingStore struct {
+	Store
+	reads int
+}
+
+func (s *readCountingStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
+	s.reads++
+	return s.Store.Read(ctx, id, version)
+}
+
+func newTestAuditingStore(parameters map[string]mockParameter) (*AuditingStore, *memoryAuditSink) {
+	sink := &memoryAuditSink{}
+	s := NewAuditingStore(NewTestSSMStore(parameters), AuditConfig{
+		Sinks:    []AuditSink{sink},
+		Identity: func(ctx context.Context) (string, error) { return "arn:aws:iam::123456789012:user/alice", nil },
+		Command:  "chamber write",
+		Backend:  "ssm",
+	})
+	return s, sink
+}
+
+func TestAuditingStore(t *testing.T) {
+	ctx := context.Background()
+	id := SecretId{Service: "app", Key: "key"}
+
+	t.Run("writes are recorded with the version written, without reading it back", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		reads := &readCountingStore{Store: s.Store}
+		s.Store = reads
+
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		require.NoError(t, s.Write(ctx, id, "secret2"))
+
+		require.Len(t, sink.events, 2)
+		event := sink.events[1]
+		assert.Equal(t, "arn:aws:iam::123456789012:user/alice", event.Identity)
+		assert.Equal(t, "chamber write", event.Command)
+		assert.Equal(t, "write", event.Operation)
+		assert.Equal(t, "ssm", event.Backend)
+		assert.Equal(t, "app", event.Service)
+		assert.Equal(t, "key", event.Key)
+		assert.Equal(t, 2, event.Version)
+		assert.Equal(t, AuditSuccess, event.Outcome)
+		assert.False(t, event.Time.IsZero())
+		assert.Zero(t, reads.reads)
+	})
+
+	t.Run("batch writes record each secret", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		require.NoError(t, s.Write(ctx, id, "secret"))
+
+		other := SecretId{Service: "app", Key: "other"}
+		require.NoError(t, s.WriteMany(ctx, map[SecretId]string{id: "secret2", other: "secret3"}))
+
+		require.Len(t, sink.events, 3)
+		assert.Equal(t, "write_many", sink.events[1].Operation)
+		assert.Equal(t, "key", sink.events[1].Key)
+		assert.Equal(t, 2, sink.events[1].Version)
+		assert.Equal(t, "other", sink.events[2].Key)
+		assert.Equal(t, 1, sink.events[2].Version)
+	})
+
+	t.Run("re-encryption records the version rewritten", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		require.NoError(t, s.Write(ctx, id, "secret2"))
+
+		require.NoError(t, s.Reencrypt(ctx, id, "alias/new"))
+		require.Len(t, sink.events, 3)
+		assert.Equal(t, "reencrypt", sink.events[2].Operation)
+		assert.Equal(t, 2, sink.events[2].Version)
+	})
+
+	t.Run("replicated writes record the primary's version", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		s.Store = NewReplicatingStore(s.Store, Replica{Name: "dr", Store: NewTestSSMStore(map[string]mockParameter{})})
+
+		require.NoError(t, s.Write(ctx, id, "secret2"))
+		require.Len(t, sink.events, 2)
+		assert.Equal(t, 2, sink.events[1].Version)
+	})
+
+	t.Run("tag changes and deletes are recorded", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		require.NoError(t, s.Write(ctx, id, "secret"))
+
+		require.NoError(t, s.WriteTags(ctx, id, map[string]string{"team": "core"}, false))
+		require.NoError(t, s.DeleteTags(ctx, id, []string{"team"}))
+		require.NoError(t, s.Delete(ctx, id))
+
+		var operations []string
+		for _, event := range sink.events {
+			operations = append(operations, event.Operation)
+		}
+		assert.Equal(t, []string{"write", "write_tags", "delete_tags", "delete"}, operations)
+	})
+
+	t.Run("reads of values are recorded", func(t *testing.T) {
+		s, sink := newTestAuditingStore(map[string]mockParameter{})
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		sink.events = nil
+
+		_, err := s.Read(ctx, id, -1)
+		require.NoError(t, err)
+		_, err = s.List(ctx, "app", false)
+		require.NoError(t, err)
+		_, err = s.List(ctx, "app", true)
+		require.NoError(t, err)
+		_, err = s.ListRaw(ctx, "app")
+		require.NoError(t, err)
+
+		var operations []string
+		for _, event := range sink.events {
+			operations = append(operations, event.Operation)
+		}
+		assert.Equal(t, []string{"read", "list", "list_raw"}, operations)
+		assert.Equal(t, 1, sink.events[0].Version)
+	})
+
+	t.Run("failures are recorded without values", func(t *testing.T) {
+		sink := &memoryAuditSink{}
+		s := NewAuditingStore(&failingWriteStore{NewTestSSMStore(map[string]mockParameter{})}, AuditConfig{Sinks: []AuditSink{sink}})
+		AddKnownSecrets("supersecretvalue")
+
+		require.Error(t, s.Write(ctx, id, "supersecretvalue"))
+		_ = s.Delete(ctx, SecretId{Service: "app", Key: "missing"})
+
+		require.Len(t, sink.events, 2)
+		for _, event := range sink.events {
+			assert.Equal(t, AuditFailure, event.Outcome)
+			assert.NotEmpty(t, event.Error)
+			assert.NotContains(t, event.Error, "supersecretvalue")
+			assert.Zero(t, event.Version)
+		}
+	})
+
+	t.Run("identity falls back to $USER", func(t *testing.T) {
+		t.Setenv("USER", "alice")
+		sink := &memoryAuditSink{}
+		s := NewAuditingStore(NewTestSSMStore(map[string]mockParameter{}), AuditConfig{
+			Sinks:    []AuditSink{sink},
+			Identity: func(ctx context.Context) (string, error) { return "", errors.New("no credentials") },
+		})
+
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		require.Len(t, sink.events, 1)
+		assert.Equal(t, "user:alice", sink.events[0].Identity)
+	})
+
+	t.Run("sink errors don't fail operations", func(t *testing.T) {
+		s := NewAuditingStore(NewTestSSMStore(map[string]mockParameter{}), AuditConfig{
+			Sinks: []AuditSink{AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+				return errors.New("sink is down")
+			})},
+		})
+		assert.NoError(t, s.Write(ctx, id, "secret"))
+	})
+
+	t.Run("capabilities come from the wrapped store", func(t *testing.T) {
+		s, _ := newTestAuditingStore(map[string]mockParameter{})
+		assert.Equal(t, Supports(NewTestSSMStore(map[string]mockParameter{}), CapabilityLabels), s.Supports(CapabilityLabels))
+	})
+}
+
+func TestFileAuditSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileAuditSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Record(ctx, AuditEvent{Operation: "write", Service: "app", Key: "a", Outcome: AuditSuccess}))
+	require.NoError(t, sink.Record(ctx, AuditEvent{Operation: "delete", Service: "app", Key: "b", Outcome: AuditSuccess}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].Key)
+	assert.Equal(t, "delete", events[1].Operation)
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	ctx := context.Background()
+
+	var received AuditEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Key == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sink := &WebhookAuditSink{URL: server.URL}
+	require.NoError(t, sink.Record(ctx, AuditEvent{Operation: "write", Key: "key"}))
+	assert.Equal(t, "key", received.Key)
+
+	assert.Error(t, sink.Record(ctx, AuditEvent{Operation: "write", Key: "fail"}))
+}
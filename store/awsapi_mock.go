@@ -5,6 +5,7 @@ package store
 
 import (
 	"context"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
@@ -12,6 +13,140 @@ import (
 	"sync"
 )
 
+// Ensure, that apiKMSMock does implement apiKMS.
+// If this is not the case, regenerate this file with moq.
+var _ apiKMS = &apiKMSMock{}
+
+// apiKMSMock is a mock implementation of apiKMS.
+//
+//	func TestSomethingThatUsesapiKMS(t *testing.T) {
+//
+//		// make and configure a mocked apiKMS
+//		mockedapiKMS := &apiKMSMock{
+//			DecryptFunc: func(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+//				panic("mock out the Decrypt method")
+//			},
+//			EncryptFunc: func(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+//				panic("mock out the Encrypt method")
+//			},
+//		}
+//
+//		// use mockedapiKMS in code that requires apiKMS
+//		// and then make assertions.
+//
+//	}
+type apiKMSMock struct {
+	// DecryptFunc mocks the Decrypt method.
+	DecryptFunc func(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+
+	// EncryptFunc mocks the Encrypt method.
+	EncryptFunc func(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Decrypt holds details about calls to the Decrypt method.
+		Decrypt []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *kms.DecryptInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*kms.Options)
+		}
+		// Encrypt holds details about calls to the Encrypt method.
+		Encrypt []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *kms.EncryptInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*kms.Options)
+		}
+	}
+	lockDecrypt sync.RWMutex
+	lockEncrypt sync.RWMutex
+}
+
+// Decrypt calls DecryptFunc.
+func (mock *apiKMSMock) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if mock.DecryptFunc == nil {
+		panic("apiKMSMock.DecryptFunc: method is nil but apiKMS.Decrypt was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *kms.DecryptInput
+		OptFns []func(*kms.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockDecrypt.Lock()
+	mock.calls.Decrypt = append(mock.calls.Decrypt, callInfo)
+	mock.lockDecrypt.Unlock()
+	return mock.DecryptFunc(ctx, params, optFns...)
+}
+
+// DecryptCalls gets all the calls that were made to Decrypt.
+// Check the length with:
+//
+//	len(mockedapiKMS.DecryptCalls())
+func (mock *apiKMSMock) DecryptCalls() []struct {
+	Ctx    context.Context
+	Params *kms.DecryptInput
+	OptFns []func(*kms.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *kms.DecryptInput
+		OptFns []func(*kms.Options)
+	}
+	mock.lockDecrypt.RLock()
+	calls = mock.calls.Decrypt
+	mock.lockDecrypt.RUnlock()
+	return calls
+}
+
+// Encrypt calls EncryptFunc.
+func (mock *apiKMSMock) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	if mock.EncryptFunc == nil {
+		panic("apiKMSMock.EncryptFunc: method is nil but apiKMS.Encrypt was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *kms.EncryptInput
+		OptFns []func(*kms.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockEncrypt.Lock()
+	mock.calls.Encrypt = append(mock.calls.Encrypt, callInfo)
+	mock.lockEncrypt.Unlock()
+	return mock.EncryptFunc(ctx, params, optFns...)
+}
+
+// EncryptCalls gets all the calls that were made to Encrypt.
+// Check the length with:
+//
+//	len(mockedapiKMS.EncryptCalls())
+func (mock *apiKMSMock) EncryptCalls() []struct {
+	Ctx    context.Context
+	Params *kms.EncryptInput
+	OptFns []func(*kms.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *kms.EncryptInput
+		OptFns []func(*kms.Options)
+	}
+	mock.lockEncrypt.RLock()
+	calls = mock.calls.Encrypt
+	mock.lockEncrypt.RUnlock()
+	return calls
+}
+
 // Ensure, that apiS3Mock does implement apiS3.
 // If this is not the case, regenerate this file with moq.
 var _ apiS3 = &apiS3Mock{}
@@ -286,6 +421,9 @@ var _ apiSSM = &apiSSMMock{}
 //			GetParametersByPathFunc: func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
 //				panic("mock out the GetParametersByPath method")
 //			},
+//			LabelParameterVersionFunc: func(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error) {
+//				panic("mock out the LabelParameterVersion method")
+//			},
 //			ListTagsForResourceFunc: func(ctx context.Context, params *ssm.ListTagsForResourceInput, optFns ...func(*ssm.Options)) (*ssm.ListTagsForResourceOutput, error) {
 //				panic("mock out the ListTagsForResource method")
 //			},
@@ -295,6 +433,9 @@ var _ apiSSM = &apiSSMMock{}
 //			RemoveTagsFromResourceFunc: func(ctx context.Context, params *ssm.RemoveTagsFromResourceInput, optFns ...func(*ssm.Options)) (*ssm.RemoveTagsFromResourceOutput, error) {
 //				panic("mock out the RemoveTagsFromResource method")
 //			},
+//			UnlabelParameterVersionFunc: func(ctx context.Context, params *ssm.UnlabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.UnlabelParameterVersionOutput, error) {
+//				panic("mock out the UnlabelParameterVersion method")
+//			},
 //		}
 //
 //		// use mockedapiSSM in code that requires apiSSM
@@ -320,6 +461,9 @@ type apiSSMMock struct {
 	// GetParametersByPathFunc mocks the GetParametersByPath method.
 	GetParametersByPathFunc func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
 
+	// LabelParameterVersionFunc mocks the LabelParameterVersion method.
+	LabelParameterVersionFunc func(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error)
+
 	// ListTagsForResourceFunc mocks the ListTagsForResource method.
 	ListTagsForResourceFunc func(ctx context.Context, params *ssm.ListTagsForResourceInput, optFns ...func(*ssm.Options)) (*ssm.ListTagsForResourceOutput, error)
 
@@ -329,6 +473,9 @@ type apiSSMMock struct {
 	// RemoveTagsFromResourceFunc mocks the RemoveTagsFromResource method.
 	RemoveTagsFromResourceFunc func(ctx context.Context, params *ssm.RemoveTagsFromResourceInput, optFns ...func(*ssm.Options)) (*ssm.RemoveTagsFromResourceOutput, error)
 
+	// UnlabelParameterVersionFunc mocks the UnlabelParameterVersion method.
+	UnlabelParameterVersionFunc func(ctx context.Context, params *ssm.UnlabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.UnlabelParameterVersionOutput, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// AddTagsToResource holds details about calls to the AddTagsToResource method.
@@ -385,6 +532,15 @@ type apiSSMMock struct {
 			// OptFns is the optFns argument value.
 			OptFns []func(*ssm.Options)
 		}
+		// LabelParameterVersion holds details about calls to the LabelParameterVersion method.
+		LabelParameterVersion []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *ssm.LabelParameterVersionInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*ssm.Options)
+		}
 		// ListTagsForResource holds details about calls to the ListTagsForResource method.
 		ListTagsForResource []struct {
 			// Ctx is the ctx argument value.
@@ -412,16 +568,27 @@ type apiSSMMock struct {
 			// OptFns is the optFns argument value.
 			OptFns []func(*ssm.Options)
 		}
+		// UnlabelParameterVersion holds details about calls to the UnlabelParameterVersion method.
+		UnlabelParameterVersion []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *ssm.UnlabelParameterVersionInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*ssm.Options)
+		}
 	}
-	lockAddTagsToResource      sync.RWMutex
-	lockDeleteParameter        sync.RWMutex
-	lockDescribeParameters     sync.RWMutex
-	lockGetParameterHistory    sync.RWMutex
-	lockGetParameters          sync.RWMutex
-	lockGetParametersByPath    sync.RWMutex
-	lockListTagsForResource    sync.RWMutex
-	lockPutParameter           sync.RWMutex
-	lockRemoveTagsFromResource sync.RWMutex
+	lockAddTagsToResource       sync.RWMutex
+	lockDeleteParameter         sync.RWMutex
+	lockDescribeParameters      sync.RWMutex
+	lockGetParameterHistory     sync.RWMutex
+	lockGetParameters           sync.RWMutex
+	lockGetParametersByPath     sync.RWMutex
+	lockLabelParameterVersion   sync.RWMutex
+	lockListTagsForResource     sync.RWMutex
+	lockPutParameter            sync.RWMutex
+	lockRemoveTagsFromResource  sync.RWMutex
+	lockUnlabelParameterVersion sync.RWMutex
 }
 
 // AddTagsToResource calls AddTagsToResourceFunc.
@@ -664,6 +831,46 @@ func (mock *apiSSMMock) GetParametersByPathCalls() []struct {
 	return calls
 }
 
+// LabelParameterVersion calls LabelParameterVersionFunc.
+func (mock *apiSSMMock) LabelParameterVersion(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error) {
+	if mock.LabelParameterVersionFunc == nil {
+		panic("apiSSMMock.LabelParameterVersionFunc: method is nil but apiSSM.LabelParameterVersion was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *ssm.LabelParameterVersionInput
+		OptFns []func(*ssm.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockLabelParameterVersion.Lock()
+	mock.calls.LabelParameterVersion = append(mock.calls.LabelParameterVersion, callInfo)
+	mock.lockLabelParameterVersion.Unlock()
+	return mock.LabelParameterVersionFunc(ctx, params, optFns...)
+}
+
+// LabelParameterVersionCalls gets all the calls that were made to LabelParameterVersion.
+// Check the length with:
+//
+//	len(mockedapiSSM.LabelParameterVersionCalls())
+func (mock *apiSSMMock) LabelParameterVersionCalls() []struct {
+	Ctx    context.Context
+	Params *ssm.LabelParameterVersionInput
+	OptFns []func(*ssm.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *ssm.LabelParameterVersionInput
+		OptFns []func(*ssm.Options)
+	}
+	mock.lockLabelParameterVersion.RLock()
+	calls = mock.calls.LabelParameterVersion
+	mock.lockLabelParameterVersion.RUnlock()
+	return calls
+}
+
 // ListTagsForResource calls ListTagsForResourceFunc.
 func (mock *apiSSMMock) ListTagsForResource(ctx context.Context, params *ssm.ListTagsForResourceInput, optFns ...func(*ssm.Options)) (*ssm.ListTagsForResourceOutput, error) {
 	if mock.ListTagsForResourceFunc == nil {
@@ -784,6 +991,46 @@ func (mock *apiSSMMock) RemoveTagsFromResourceCalls() []struct {
 	return calls
 }
 
+// UnlabelParameterVersion calls UnlabelParameterVersionFunc.
+func (mock *apiSSMMock) UnlabelParameterVersion(ctx context.Context, params *ssm.UnlabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.UnlabelParameterVersionOutput, error) {
+	if mock.UnlabelParameterVersionFunc == nil {
+		panic("apiSSMMock.UnlabelParameterVersionFunc: method is nil but apiSSM.UnlabelParameterVersion was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *ssm.UnlabelParameterVersionInput
+		OptFns []func(*ssm.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockUnlabelParameterVersion.Lock()
+	mock.calls.UnlabelParameterVersion = append(mock.calls.UnlabelParameterVersion, callInfo)
+	mock.lockUnlabelParameterVersion.Unlock()
+	return mock.UnlabelParameterVersionFunc(ctx, params, optFns...)
+}
+
+// UnlabelParameterVersionCalls gets all the calls that were made to UnlabelParameterVersion.
+// Check the length with:
+//
+//	len(mockedapiSSM.UnlabelParameterVersionCalls())
+func (mock *apiSSMMock) UnlabelParameterVersionCalls() []struct {
+	Ctx    context.Context
+	Params *ssm.UnlabelParameterVersionInput
+	OptFns []func(*ssm.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *ssm.UnlabelParameterVersionInput
+		OptFns []func(*ssm.Options)
+	}
+	mock.lockUnlabelParameterVersion.RLock()
+	calls = mock.calls.UnlabelParameterVersion
+	mock.lockUnlabelParameterVersion.RUnlock()
+	return calls
+}
+
 // Ensure, that apiSTSMock does implement apiSTS.
 // If this is not the case, regenerate this file with moq.
 var _ apiSTS = &apiSTSMock{}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// BatchReader is implemented by stores which can read the latest versions of
+// many secrets in fewer requests than one per secret.
+type BatchReader interface {
+	// ReadMany reads the latest version of each secret. Secrets which don't
+	// exist are left out of the result.
+	ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error)
+}
+
+// BatchWriter is implemented by stores which can write many secrets in fewer
+// requests than one per secret.
+type BatchWriter interface {
+	// WriteMany writes a new version of each secret.
+	WriteMany(ctx context.Context, values map[SecretId]string) error
+}
+
+// ReadMany reads the latest version of each secret from s, in batches if s
+// is a BatchReader, or else one at a time. Secrets which don't exist are left
+// out of the result.
+func ReadMany(ctx context.Context, s Store, ids []SecretId) (map[SecretId]Secret, error) {
+	if br, ok := s.(BatchReader); ok {
+		return br.ReadMany(ctx, ids)
+	}
+
+	secrets := make(map[SecretId]Secret, len(ids))
+	for _, id := range ids {
+		secret, err := s.Read(ctx, id, -1)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		secrets[id] = secret
+	}
+	return secrets, nil
+}
+
+// WriteMany writes a new version of each secret to s, in batches if s is a
+// BatchWriter, or else one at a time.
+func WriteMany(ctx context.Context, s Store, values map[SecretId]string) error {
+	if bw, ok := s.(BatchWriter); ok {
+		return bw.WriteMany(ctx, values)
+	}
+
+	for _, id := range sortedIds(values) {
+		if err := s.Write(ctx, id, values[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByService splits values by service, keyed by secret key
+func groupByService(values map[SecretId]string) map[string]map[string]string {
+	services := map[string]map[string]string{}
+	for id, value := range values {
+		if services[id.Service] == nil {
+			services[id.Service] = map[string]string{}
+		}
+		services[id.Service][id.Key] = value
+	}
+	return services
+}
+
+// sortedIds returns the IDs of values, sorted by service then key, so that
+// batches are applied in a predictable order
+func sortedIds(values map[SecretId]string) []SecretId {
+	ids := make([]SecretId, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Service != ids[j].Service {
+			return ids[i].Service < ids[j].Service
+		}
+		return ids[i].Key < ids[j].Key
+	})
+	return ids
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMReadMany(t *testing.T) {
+	ctx := context.Background()
+	parameters := map[string]mockParameter{}
+	s := NewTestSSMStore(parameters)
+
+	var ids []SecretId
+	for i := 0; i < 25; i++ {
+		id := SecretId{Service: "app", Key: fmt.Sprintf("key%02d", i)}
+		require.NoError(t, s.Write(ctx, id, fmt.Sprintf("value%d", i)))
+		ids = append(ids, id)
+	}
+	require.NoError(t, s.Write(ctx, ids[0], "updated"))
+	require.NoError(t, s.Write(ctx, SecretId{Service: "other", Key: "key"}, "other"))
+	ids = append(ids, SecretId{Service: "other", Key: "key"}, SecretId{Service: "app", Key: "missing"})
+
+	mock := s.svc.(*apiSSMMock)
+	getCalls := len(mock.GetParametersCalls())
+
+	secrets, err := s.ReadMany(ctx, ids)
+	require.NoError(t, err)
+	assert.Len(t, secrets, 26)
+	assert.Equal(t, "updated", *secrets[ids[0]].Value)
+	assert.Equal(t, 2, secrets[ids[0]].Meta.Version)
+	assert.Equal(t, "value24", *secrets[ids[24]].Value)
+	assert.Equal(t, "/app/key24", secrets[ids[24]].Meta.Key)
+	assert.Equal(t, "other", *secrets[SecretId{Service: "other", Key: "key"}].Value)
+	assert.NotContains(t, secrets, SecretId{Service: "app", Key: "missing"})
+
+	// 27 names in batches of 10
+	assert.Len(t, mock.GetParametersCalls(), getCalls+3)
+	for _, call := range mock.GetParametersCalls()[getCalls:] {
+		assert.LessOrEqual(t, len(call.Params.Names), 10)
+	}
+}
+
+func TestSSMWriteMany(t *testing.T) {
+	ctx := context.Background()
+	parameters := map[string]mockParameter{}
+	s := NewTestSSMStore(parameters)
+	require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "a"}, "a1"))
+
+	err := WriteMany(ctx, s, map[SecretId]string{
+		{Service: "app", Key: "a"}: "a2",
+		{Service: "app", Key: "b"}: "b1",
+	})
+	require.NoError(t, err)
+
+	a, err := s.Read(ctx, SecretId{Service: "app", Key: "a"}, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "a2", *a.Value)
+	assert.Equal(t, 2, a.Meta.Version)
+
+	b, err := s.Read(ctx, SecretId{Service: "app", Key: "b"}, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "b1", *b.Value)
+	assert.Equal(t, 1, b.Meta.Version)
+}
+
+func TestSecretsManagerWriteMany(t *testing.T) {
+	ctx := context.Background()
+	secrets := map[string]mockSecret{}
+	s := NewTestSecretsManagerStore(secrets, map[string]secretsmanager.DescribeSecretOutput{})
+	require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "a"}, "a1"))
+
+	err := WriteMany(ctx, s, map[SecretId]string{
+		{Service: "app", Key: "a"}:   "a2",
+		{Service: "app", Key: "b"}:   "b1",
+		{Service: "other", Key: "c"}: "c1",
+	})
+	require.NoError(t, err)
+
+	mock := s.svc.(*apiSecretsManagerMock)
+	assert.Len(t, mock.PutSecretValueCalls(), 1)
+	assert.Equal(t, []string{"AWSCURRENT", "CHAMBER2"}, mock.PutSecretValueCalls()[0].Params.VersionStages)
+	assert.Len(t, mock.CreateSecretCalls(), 2)
+
+	secretsRead, err := ReadMany(ctx, s, []SecretId{
+		{Service: "app", Key: "a"},
+		{Service: "app", Key: "b"},
+		{Service: "other", Key: "c"},
+		{Service: "other", Key: "missing"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, secretsRead, 3)
+	assert.Equal(t, "a2", *secretsRead[SecretId{Service: "app", Key: "a"}].Value)
+	assert.Equal(t, 2, secretsRead[SecretId{Service: "app", Key: "a"}].Meta.Version)
+	assert.Equal(t, "b1", *secretsRead[SecretId{Service: "app", Key: "b"}].Value)
+	assert.Equal(t, "c1", *secretsRead[SecretId{Service: "other", Key: "c"}].Value)
+}
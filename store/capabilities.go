@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotImplemented is returned, wrapped, by stores for operations their
+// backend doesn't support. Check for it with errors.Is.
+var ErrNotImplemented = errors.New("not implemented")
+
+// Capability is an optional feature of a store.
+type Capability string
+
+const (
+	// CapabilityTags is reading, writing and deleting tags on secrets.
+	CapabilityTags Capability = "tags"
+	// CapabilityLabels is reading services as <service>:<label>. Stores which
+	// can also change labels implement Labeler.
+	CapabilityLabels Capability = "labels"
+	// CapabilityHistory is listing the changes made to a secret.
+	CapabilityHistory Capability = "history"
+	// CapabilityListServices is listing services and their secrets.
+	CapabilityListServices Capability = "list-services"
+	// CapabilityConfig is setting the store configuration, e.g. required tags.
+	CapabilityConfig Capability = "config"
+	// CapabilityWriteWithTags is tagging new secrets as they're written.
+	CapabilityWriteWithTags Capability = "write-with-tags"
+	// CapabilityPolicies is writing secrets with a storage tier and policies
+	// such as expiration. Stores which support it implement OptionsWriter.
+	CapabilityPolicies Capability = "policies"
+	// CapabilityTypes is writing plain, unencrypted values as well as secrets.
+	// Stores which support it implement OptionsWriter.
+	CapabilityTypes Capability = "types"
+	// CapabilityReencrypt is moving secrets to a different KMS key. Stores
+	// which support it implement Reencrypter.
+	CapabilityReencrypt Capability = "reencrypt"
+)
+
+// AllCapabilities lists every capability, in display order.
+var AllCapabilities = []Capability{
+	CapabilityTags,
+	CapabilityLabels,
+	CapabilityHistory,
+	CapabilityListServices,
+	CapabilityConfig,
+	CapabilityWriteWithTags,
+	CapabilityPolicies,
+	CapabilityTypes,
+	CapabilityReencrypt,
+}
+
+// Capabilities is implemented by stores which can report which optional
+// features they support, so callers can check before changing anything.
+type Capabilities interface {
+	Supports(c Capability) bool
+}
+
+// Supports reports whether s supports c. Stores which don't implement
+// Capabilities are assumed to support everything, leaving it to the
+// operations themselves to fail.
+func Supports(s Store, c Capability) bool {
+	caps, ok := s.(Capabilities)
+	return !ok || caps.Supports(c)
+}
+
+// RequireCapabilities returns an error wrapping ErrNotImplemented for the
+// first of caps that s doesn't support.
+func RequireCapabilities(s Store, caps ...Capability) error {
+	for _, c := range caps {
+		if !Supports(s, c) {
+			return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, c)
+		}
+	}
+	return nil
+}
+
+// capabilitySet implements Capabilities for a fixed set of capabilities
+type capabilitySet map[Capability]bool
+
+func (c capabilitySet) Supports(capability Capability) bool {
+	return c[capability]
+}
+
+// NewCapabilities returns Capabilities supporting exactly caps.
+func NewCapabilities(caps ...Capability) Capabilities {
+	set := capabilitySet{}
+	for _, c := range caps {
+		set[c] = true
+	}
+	return set
+}
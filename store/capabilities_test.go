@@ -0,0 +1,29 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireCapabilities(t *testing.T) {
+	assert.NoError(t, RequireCapabilities(&SSMStore{}, AllCapabilities...))
+	assert.NoError(t, RequireCapabilities(&S3KMSStore{}, CapabilityHistory, CapabilityReencrypt))
+
+	err := RequireCapabilities(&S3Store{}, CapabilityHistory, CapabilityTags)
+	assert.ErrorIs(t, err, ErrNotImplemented)
+	assert.EqualError(t, err, "not implemented: this backend does not support tags")
+
+	// stores which don't report capabilities are assumed to support everything
+	assert.NoError(t, RequireCapabilities(struct{ Store }{}, CapabilityLabels))
+}
+
+func TestBackendCapabilities(t *testing.T) {
+	caps, ok := BackendCapabilities("secretsmanager")
+	assert.True(t, ok)
+	assert.True(t, caps.Supports(CapabilityHistory))
+	assert.False(t, caps.Supports(CapabilityWriteWithTags))
+
+	_, ok = BackendCapabilities("vault")
+	assert.False(t, ok)
+}
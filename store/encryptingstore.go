@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Encrypted values are envelopePrefix, then the base64 JSON envelopeHeader,
+// a full stop, and the base64 AES-GCM ciphertext with its nonce prepended.
+// The header and the secret's service and key are authenticated as
+// additional data, so that a value copied to another secret can't be read.
+const envelopePrefix = "chamber:enc:v1:"
+
+// ErrNoDecryptionKey is returned, wrapped, when none of an EncryptingStore's
+// key providers can unwrap the data key of a value.
+var ErrNoDecryptionKey = errors.New("no key to decrypt value")
+
+// ErrNotEncrypted is returned, wrapped, when an EncryptingStore reads a value
+// which wasn't written through one, unless EncryptionOptions.AllowUnencrypted
+// is set.
+var ErrNotEncrypted = errors.New("value is not encrypted")
+
+// EncryptionOptions configures an EncryptingStore.
+type EncryptionOptions struct {
+	// AllowUnencrypted reads values which weren't written through an
+	// EncryptingStore as they are, logging a warning, e.g. while moving
+	// a service to client-side encryption. Otherwise reading them fails,
+	// since anyone who can write to the backend could have put them there.
+	AllowUnencrypted bool
+}
+
+// envelopeHeader lists the data key of a value, wrapped by each key provider
+type envelopeHeader struct {
+	Keys []wrappedKey `json:"keys"`
+}
+
+type wrappedKey struct {
+	ID  string `json:"id"`
+	Key []byte `json:"key"`
+}
+
+// EncryptingStore encrypts values before they're written to another store,
+// and decrypts them as they're read, so that the backend only ever holds
+// ciphertext. Each value has its own AES-GCM data key, wrapped by every key
+// provider; any one of them can decrypt it. Values which weren't written
+// through an EncryptingStore can't be read, unless the options allow it.
+type EncryptingStore struct {
+	Store
+	providers []KeyProvider
+	options   EncryptionOptions
+}
+
+var (
+	_ Store           = &EncryptingStore{}
+	_ BatchReader     = &EncryptingStore{}
+	_ BatchWriter     = &EncryptingStore{}
+	_ StreamingLister = &EncryptingStore{}
+	_ OptionsWriter   = &EncryptingStore{}
+	_ Labeler         = &EncryptingStore{}
+	_ Reencrypter     = &EncryptingStore{}
+)
+
+// NewEncryptingStore wraps s so that values are encrypted with data keys
+// wrapped by each of providers.
+func NewEncryptingStore(s Store, providers ...KeyProvider) (*EncryptingStore, error) {
+	return NewEncryptingStoreWithOptions(s, EncryptionOptions{}, providers...)
+}
+
+// NewEncryptingStoreWithOptions is like NewEncryptingStore, configured by
+// options.
+func NewEncryptingStoreWithOptions(s Store, options EncryptionOptions, providers ...KeyProvider) (*EncryptingStore, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("at least one key provider is required")
+	}
+	return &EncryptingStore{Store: s, providers: providers, options: options}, nil
+}
+
+// Supports reports what the wrapped store supports.
+func (s *EncryptingStore) Supports(c Capability) bool {
+	return Supports(s.Store, c)
+}
+
+func (s *EncryptingStore) Write(ctx context.Context, id SecretId, value string) error {
+	encrypted, err := s.encrypt(ctx, id, value)
+	if err != nil {
+		return err
+	}
+	return s.Store.Write(ctx, id, encrypted)
+}
+
+func (s *EncryptingStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+	encrypted, err := s.encrypt(ctx, id, value)
+	if err != nil {
+		return err
+	}
+	return s.Store.WriteWithTags(ctx, id, encrypted, tags)
+}
+
+func (s *EncryptingStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error {
+	ow, ok := s.Store.(OptionsWriter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+	}
+	encrypted, err := s.encrypt(ctx, id, value)
+	if err != nil {
+		return err
+	}
+	return ow.WriteWithOptions(ctx, id, encrypted, opts)
+}
+
+func (s *EncryptingStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	encrypted := make(map[SecretId]string, len(values))
+	for id, value := range values {
+		var err error
+		if encrypted[id], err = s.encrypt(ctx, id, value); err != nil {
+			return err
+		}
+	}
+	return WriteMany(ctx, s.Store, encrypted)
+}
+
+func (s *EncryptingStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
+	secret, err := s.Store.Read(ctx, id, version)
+	if err != nil {
+		return Secret{}, err
+	}
+	return secret, s.decryptSecret(ctx, id, &secret)
+}
+
+func (s *EncryptingStore) ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error) {
+	secrets, err := ReadMany(ctx, s.Store, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, secret := range secrets {
+		if err := s.decryptSecret(ctx, id, &secret); err != nil {
+			return nil, err
+		}
+		secrets[id] = secret
+	}
+	return secrets, nil
+}
+
+func (s *EncryptingStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
+	return collect(s.ListIter(ctx, service, includeValues))
+}
+
+func (s *EncryptingStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	return collect(s.ListRawIter(ctx, service))
+}
+
+func (s *EncryptingStore) ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error] {
+	return func(yield func(Secret, error) bool) {
+		for secret, err := range ListIter(ctx, s.Store, service, includeValues) {
+			if err == nil {
+				err = s.decryptSecret(ctx, listedId(service, secret.Meta.Key), &secret)
+			}
+			if !yield(secret, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *EncryptingStore) ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error] {
+	return func(yield func(RawSecret, error) bool) {
+		for rawSecret, err := range ListRawIter(ctx, s.Store, service) {
+			if err == nil {
+				rawSecret.Value, err = s.decrypt(ctx, listedId(service, rawSecret.Key), rawSecret.Key, rawSecret.Value)
+				if err != nil {
+					err = fmt.Errorf("%s: %w", rawSecret.Key, err)
+				}
+			}
+			if !yield(rawSecret, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *EncryptingStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return ListServicesIter(ctx, s.Store, service, includeSecretName)
+}
+
+func (s *EncryptingStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error {
+	l, err := s.labeler()
+	if err != nil {
+		return err
+	}
+	return l.LabelVersion(ctx, id, version, labels)
+}
+
+func (s *EncryptingStore) Unlabel(ctx context.Context, id SecretId, labels []string) error {
+	l, err := s.labeler()
+	if err != nil {
+		return err
+	}
+	return l.Unlabel(ctx, id, labels)
+}
+
+func (s *EncryptingStore) ListLabels(ctx context.Context, id SecretId) (map[int][]string, error) {
+	l, err := s.labeler()
+	if err != nil {
+		return nil, err
+	}
+	return l.ListLabels(ctx, id)
+}
+
+// Reencrypt moves a secret to another KMS key in the wrapped store. The value
+// stays encrypted with its data key.
+func (s *EncryptingStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	r, ok := s.Store.(Reencrypter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityReencrypt)
+	}
+	return r.Reencrypt(ctx, id, kmsKey)
+}
+
+func (s *EncryptingStore) labeler() (Labeler, error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return nil, fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l, nil
+}
+
+// encrypt seals value, of the secret id, with a new data key, wrapped by every
+// provider
+func (s *EncryptingStore) encrypt(ctx context.Context, id SecretId, value string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	var header envelopeHeader
+	for _, provider := range s.providers {
+		wrapCtx, span := otel.Tracer(instrumentationName).Start(ctx, "chamber.WrapKey", trace.WithAttributes(attribute.String("chamber.key_provider", provider.KeyID())))
+		wrapped, err := provider.WrapKey(wrapCtx, dataKey)
+		endSpan(span, err)
+		if err != nil {
+			return "", fmt.Errorf("failed to wrap data key with %s: %w", provider.KeyID(), err)
+		}
+		header.Keys = append(header.Keys, wrappedKey{ID: provider.KeyID(), Key: wrapped})
+	}
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := sealAESGCM(dataKey, []byte(value), additionalData(encodedHeader, id))
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix +
+		base64.RawURLEncoding.EncodeToString(encodedHeader) + "." +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value of the secret id sealed by encrypt, with the data key
+// unwrapped by the first provider which wrapped it. name is the secret's, for
+// warnings.
+func (s *EncryptingStore) decrypt(ctx context.Context, id SecretId, name, value string) (string, error) {
+	if !strings.HasPrefix(value, envelopePrefix) {
+		if !s.options.AllowUnencrypted {
+			return "", ErrNotEncrypted
+		}
+		slog.Warn(fmt.Sprintf("encryption: %s is not encrypted, so it's read as it is", name))
+		return value, nil
+	}
+
+	encodedHeader, sealed, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	var header envelopeHeader
+	if err := json.Unmarshal(encodedHeader, &header); err != nil {
+		return "", fmt.Errorf("invalid envelope header: %w", err)
+	}
+
+	dataKey, err := s.unwrapKey(ctx, header)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openAESGCM(dataKey, sealed, additionalData(encodedHeader, id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *EncryptingStore) unwrapKey(ctx context.Context, header envelopeHeader) ([]byte, error) {
+	var ids []string
+	for _, key := range header.Keys {
+		ids = append(ids, key.ID)
+		for _, provider := range s.providers {
+			if provider.KeyID() != key.ID {
+				continue
+			}
+			unwrapCtx, span := otel.Tracer(instrumentationName).Start(ctx, "chamber.UnwrapKey", trace.WithAttributes(attribute.String("chamber.key_provider", key.ID)))
+			dataKey, err := provider.UnwrapKey(unwrapCtx, key.Key)
+			endSpan(span, err)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unwrap data key with %s: %w", key.ID, err)
+			}
+			return dataKey, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: value is encrypted for %s", ErrNoDecryptionKey, strings.Join(ids, ", "))
+}
+
+func (s *EncryptingStore) decryptSecret(ctx context.Context, id SecretId, secret *Secret) error {
+	if secret.Value == nil {
+		return nil
+	}
+	value, err := s.decrypt(ctx, id, secret.Meta.Key, *secret.Value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", secret.Meta.Key, err)
+	}
+	secret.Value = &value
+	return nil
+}
+
+// additionalData is what a value's ciphertext is bound to: its envelope
+// header, and the service, without any label, and key of its secret. JSON
+// can't hold a raw newline, so the header can't run into the rest.
+func additionalData(encodedHeader []byte, id SecretId) []byte {
+	service, _, _ := strings.Cut(id.Service, ":")
+	return fmt.Appendf(slices.Clip(encodedHeader), "\n%s/%s", service, id.Key)
+}
+
+// listedId returns the ID of a secret listed in service, whose key backends
+// give either as it is or as its full path
+func listedId(service, key string) SecretId {
+	return SecretId{Service: service, Key: key[strings.LastIndex(key, "/")+1:]}
+}
+
+// EnvelopeKeyIDs returns the IDs of the keys an encrypted value can be
+// decrypted with, or nil if the value isn't encrypted.
+func EnvelopeKeyIDs(value string) ([]string, error) {
+	if !strings.HasPrefix(value, envelopePrefix) {
+		return nil, nil
+	}
+	encodedHeader, _, err := parseEnvelope(value)
+	if err != nil {
+		return nil, err
+	}
+	var header envelopeHeader
+	if err := json.Unmarshal(encodedHeader, &header); err != nil {
+		return nil, fmt.Errorf("invalid envelope header: %w", err)
+	}
+	ids := make([]string, len(header.Keys))
+	for i, key := range header.Keys {
+		ids[i] = key.ID
+	}
+	return ids, nil
+}
+
+func parseEnvelope(value string) (header, sealed []byte, err error) {
+	encodedHeader, encodedSealed, ok := strings.Cut(strings.TrimPrefix(value, envelopePrefix), ".")
+	if !ok {
+		return nil, nil, errors.New("invalid envelope")
+	}
+	if header, err = base64.RawURLEncoding.DecodeString(encodedHeader); err != nil {
+		return nil, nil, fmt.Errorf("invalid envelope header: %w", err)
+	}
+	if sealed, err = base64.RawURLEncoding.DecodeString(encodedSealed); err != nil {
+		return nil, nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+	return header, sealed, nil
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKMSKeyProvider returns a KMSKeyProvider whose KMS "encrypts" by
+// prefixing the key ID, and fails to decrypt anything else
+func newTestKMSKeyProvider(keyID string) *KMSKeyProvider {
+	return &KMSKeyProvider{
+		keyID: keyID,
+		svc: &apiKMSMock{
+			EncryptFunc: func(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+				return &kms.EncryptOutput{CiphertextBlob: append([]byte(*params.KeyId+":"), params.Plaintext...)}, nil
+			},
+			DecryptFunc: func(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+				plaintext, ok := strings.CutPrefix(string(params.CiphertextBlob), *params.KeyId+":")
+				if !ok {
+					return nil, errors.New("wrong key")
+				}
+				return &kms.DecryptOutput{Plaintext: []byte(plaintext)}, nil
+			},
+		},
+	}
+}
+
+func newTestLocalKeyProvider(t *testing.T) *LocalKeyProvider {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, key, 0600))
+	provider, err := NewLocalKeyProvider(path)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestEncryptingStore(t *testing.T) {
+	ctx := context.Background()
+	id := SecretId{Service: "app", Key: "key"}
+
+	t.Run("the backend only holds ciphertext", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s, err := NewEncryptingStore(NewTestSSMStore(parameters), newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		stored := *parameters["/app/key"].currentParam.Value
+		assert.True(t, strings.HasPrefix(stored, envelopePrefix))
+		assert.NotContains(t, stored, "secret")
+
+		secret, err := s.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", *secret.Value)
+
+		secrets, err := s.List(ctx, "app", true)
+		require.NoError(t, err)
+		require.Len(t, secrets, 1)
+		assert.Equal(t, "secret", *secrets[0].Value)
+
+		rawSecrets, err := s.ListRaw(ctx, "app")
+		require.NoError(t, err)
+		require.Len(t, rawSecrets, 1)
+		assert.Equal(t, "secret", rawSecrets[0].Value)
+
+		read, err := s.ReadMany(ctx, []SecretId{id})
+		require.NoError(t, err)
+		assert.Equal(t, "secret", *read[id].Value)
+	})
+
+	t.Run("batch writes and write options are encrypted", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s, err := NewEncryptingStore(NewTestSSMStore(parameters), newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+
+		require.NoError(t, s.WriteWithOptions(ctx, SecretId{Service: "app", Key: "other"}, "two", WriteOptions{Type: TypeString}))
+		require.NoError(t, s.WriteMany(ctx, map[SecretId]string{id: "one"}))
+		assert.True(t, strings.HasPrefix(*parameters["/app/key"].currentParam.Value, envelopePrefix))
+		assert.True(t, strings.HasPrefix(*parameters["/app/other"].currentParam.Value, envelopePrefix))
+
+		secret, err := s.Read(ctx, SecretId{Service: "app", Key: "other"}, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "two", *secret.Value)
+	})
+
+	t.Run("any provider can decrypt", func(t *testing.T) {
+		backend := NewTestSSMStore(map[string]mockParameter{})
+		local := newTestLocalKeyProvider(t)
+		kmsProvider := newTestKMSKeyProvider("alias/chamber")
+		writer, err := NewEncryptingStore(backend, local, kmsProvider)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx, id, "secret"))
+
+		reader, err := NewEncryptingStore(backend, kmsProvider)
+		require.NoError(t, err)
+		secret, err := reader.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", *secret.Value)
+
+		ids, err := EnvelopeKeyIDs(*secret.Value)
+		require.NoError(t, err)
+		assert.Nil(t, ids)
+		stored, err := backend.Read(ctx, id, -1)
+		require.NoError(t, err)
+		ids, err = EnvelopeKeyIDs(*stored.Value)
+		require.NoError(t, err)
+		assert.Equal(t, []string{local.KeyID(), "kms:alias/chamber"}, ids)
+	})
+
+	t.Run("other keys can't decrypt", func(t *testing.T) {
+		backend := NewTestSSMStore(map[string]mockParameter{})
+		writer, err := NewEncryptingStore(backend, newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx, id, "secret"))
+
+		reader, err := NewEncryptingStore(backend, newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		_, err = reader.Read(ctx, id, -1)
+		assert.ErrorIs(t, err, ErrNoDecryptionKey)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s, err := NewEncryptingStore(NewTestSSMStore(parameters), newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		require.NoError(t, s.Write(ctx, id, "secret"))
+
+		stored := *parameters["/app/key"].currentParam.Value
+		tampered := stored[:len(stored)-2] + "AA"
+		if tampered == stored {
+			tampered = stored[:len(stored)-2] + "BB"
+		}
+		parameters["/app/key"].currentParam.Value = &tampered
+		_, err = s.Read(ctx, id, -1)
+		assert.ErrorContains(t, err, "failed to decrypt value")
+	})
+
+	t.Run("values copied to another secret can't be read", func(t *testing.T) {
+		backend := NewTestSSMStore(map[string]mockParameter{})
+		s, err := NewEncryptingStore(backend, newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		password := SecretId{Service: "prod", Key: "db_password"}
+		logLevel := SecretId{Service: "prod", Key: "log_level"}
+		require.NoError(t, s.Write(ctx, password, "hunter2"))
+		require.NoError(t, s.Write(ctx, logLevel, "debug"))
+
+		stored, err := backend.Read(ctx, password, -1)
+		require.NoError(t, err)
+		require.NoError(t, backend.Write(ctx, logLevel, *stored.Value))
+		require.NoError(t, backend.Write(ctx, SecretId{Service: "staging", Key: "db_password"}, *stored.Value))
+
+		_, err = s.Read(ctx, logLevel, -1)
+		assert.ErrorContains(t, err, "failed to decrypt value")
+		_, err = s.ListRaw(ctx, "prod")
+		assert.ErrorContains(t, err, "failed to decrypt value")
+		_, err = s.List(ctx, "prod", true)
+		assert.ErrorContains(t, err, "failed to decrypt value")
+		_, err = s.Read(ctx, SecretId{Service: "staging", Key: "db_password"}, -1)
+		assert.ErrorContains(t, err, "failed to decrypt value")
+
+		secret, err := s.Read(ctx, password, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", *secret.Value)
+	})
+
+	t.Run("unencrypted values", func(t *testing.T) {
+		backend := NewTestSSMStore(map[string]mockParameter{})
+		require.NoError(t, backend.Write(ctx, id, "plain"))
+		s, err := NewEncryptingStore(backend, newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+
+		_, err = s.Read(ctx, id, -1)
+		assert.ErrorIs(t, err, ErrNotEncrypted)
+		_, err = s.ListRaw(ctx, "app")
+		assert.ErrorIs(t, err, ErrNotEncrypted)
+
+		s, err = NewEncryptingStoreWithOptions(backend, EncryptionOptions{AllowUnencrypted: true}, newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		secret, err := s.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "plain", *secret.Value)
+	})
+
+	t.Run("forwards capabilities", func(t *testing.T) {
+		s, err := NewEncryptingStore(NewNullStore(), newTestLocalKeyProvider(t))
+		require.NoError(t, err)
+		assert.Equal(t, Supports(NewNullStore(), CapabilityTags), s.Supports(CapabilityTags))
+		_, err = s.ListLabels(ctx, id)
+		assert.ErrorIs(t, err, ErrNotImplemented)
+	})
+
+	t.Run("requires a provider", func(t *testing.T) {
+		_, err := NewEncryptingStore(NewNullStore())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// Errors returned by stores are classified as one of these kinds when the
+// backend's error is recognized. Check for them with errors.Is; the
+// underlying smithy.APIError is available with errors.As.
+var (
+	// ErrAccessDenied means the caller isn't allowed to perform the operation.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrKMSAccessDenied means the KMS key protecting a secret couldn't be
+	// used, because it's missing, disabled, or the caller isn't allowed to.
+	ErrKMSAccessDenied = errors.New("KMS key access denied")
+	// ErrThrottled means requests were still throttled after retrying.
+	ErrThrottled = errors.New("throttled")
+	// ErrConflict means the resource already exists or was changed
+	// concurrently.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the backend rejected the request as invalid.
+	ErrValidation = errors.New("validation failed")
+	// ErrQuotaExceeded means a service quota, such as the number of secrets or
+	// versions, was reached.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Error is a backend error classified as one of the kinds above.
+type Error struct {
+	// Kind is one of the classified errors, e.g. ErrAccessDenied.
+	Kind error
+	// Resource is the path of the secret, service or object involved.
+	Resource string
+	// Err is the error returned by the backend.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s on %s: %s", e.Kind, e.Resource, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// error codes by kind, across SSM, Secrets Manager, S3 and STS
+var (
+	accessDeniedCodes = []string{
+		"AccessDenied",
+		"AccessDeniedException",
+		"AllAccessDisabled",
+		"UnauthorizedOperation",
+	}
+	throttledCodes = []string{
+		"RequestLimitExceeded",
+		"SlowDown",
+		"ThrottledException",
+		"Throttling",
+		"ThrottlingException",
+		"TooManyUpdates",
+	}
+	conflictCodes = []string{
+		"ConditionalRequestConflict",
+		"OperationAborted",
+		"ParameterAlreadyExists",
+		"PreconditionFailed",
+		"ResourceExistsException",
+	}
+	validationCodes = []string{
+		"HierarchyTypeMismatchException",
+		"InvalidAllowedPatternException",
+		"InvalidArgument",
+		"InvalidFilterKey",
+		"InvalidFilterValue",
+		"InvalidParameterException",
+		"InvalidParameterValue",
+		"InvalidRequestException",
+		"ParameterPatternMismatchException",
+		"UnsupportedParameterType",
+		"ValidationException",
+	}
+	quotaExceededCodes = []string{
+		"HierarchyLevelLimitExceededException",
+		"LimitExceededException",
+		"ParameterLimitExceeded",
+		"ParameterMaxVersionLimitExceeded",
+		"ParameterVersionLabelLimitExceeded",
+		"PoliciesLimitExceededException",
+		"ServiceQuotaExceededException",
+		"TooManyTagsError",
+	}
+)
+
+// classifyError wraps err in an Error if it's an API error of a known kind,
+// and otherwise returns it unchanged.
+func classifyError(err error, resource string) error {
+	var apiErr smithy.APIError
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	kind := errorKind(apiErr)
+	if kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Resource: resource, Err: err}
+}
+
+func errorKind(apiErr smithy.APIError) error {
+	code := apiErr.ErrorCode()
+	switch {
+	case isKMSError(apiErr):
+		return ErrKMSAccessDenied
+	case slices.Contains(accessDeniedCodes, code):
+		return ErrAccessDenied
+	case slices.Contains(throttledCodes, code):
+		return ErrThrottled
+	case slices.Contains(conflictCodes, code):
+		return ErrConflict
+	case slices.Contains(validationCodes, code):
+		return ErrValidation
+	case slices.Contains(quotaExceededCodes, code):
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// isKMSError reports whether apiErr is about the KMS key rather than the
+// secret itself. Services report these either with their own KMS error codes,
+// or as access denied with the KMS action in the message.
+func isKMSError(apiErr smithy.APIError) bool {
+	code := apiErr.ErrorCode()
+	if strings.HasPrefix(code, "KMS") || code == "InvalidKeyId" {
+		return true
+	}
+	message := apiErr.ErrorMessage()
+	return slices.Contains(accessDeniedCodes, code) && (strings.Contains(message, "kms:") || strings.Contains(message, "KMS"))
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		code     string
+		message  string
+		expected error
+	}{
+		{code: "AccessDeniedException", message: "not authorized to perform: ssm:GetParametersByPath", expected: ErrAccessDenied},
+		{code: "AccessDeniedException", message: "not authorized to perform: kms:Decrypt", expected: ErrKMSAccessDenied},
+		{code: "KMSDisabledException", expected: ErrKMSAccessDenied},
+		{code: "InvalidKeyId", expected: ErrKMSAccessDenied},
+		{code: "ThrottlingException", expected: ErrThrottled},
+		{code: "SlowDown", expected: ErrThrottled},
+		{code: "ResourceExistsException", expected: ErrConflict},
+		{code: "ValidationException", expected: ErrValidation},
+		{code: "ParameterLimitExceeded", expected: ErrQuotaExceeded},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.code, func(t *testing.T) {
+			apiErr := &smithy.GenericAPIError{Code: tc.code, Message: tc.message}
+			err := classifyError(fmt.Errorf("operation error: %w", apiErr), "/app/key")
+
+			assert.ErrorIs(t, err, tc.expected)
+			var classified *Error
+			require.ErrorAs(t, err, &classified)
+			assert.Equal(t, "/app/key", classified.Resource)
+			var unwrapped smithy.APIError
+			require.ErrorAs(t, err, &unwrapped)
+			assert.Equal(t, tc.code, unwrapped.ErrorCode())
+		})
+	}
+
+	t.Run("unknown errors are unchanged", func(t *testing.T) {
+		apiErr := &smithy.GenericAPIError{Code: "InternalServerError"}
+		assert.Equal(t, error(apiErr), classifyError(apiErr, "/app/key"))
+
+		plain := errors.New("boom")
+		assert.Equal(t, plain, classifyError(plain, "/app/key"))
+		assert.Nil(t, classifyError(nil, "/app/key"))
+	})
+}
+
+func TestSSMHistoryAccessDenied(t *testing.T) {
+	s := &SSMStore{svc: &apiSSMMock{
+		GetParameterHistoryFunc: func(ctx context.Context, params *ssm.GetParameterHistoryInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error) {
+			return nil, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform: ssm:GetParameterHistory"}
+		},
+	}}
+
+	_, err := s.History(context.Background(), SecretId{Service: "app", Key: "key"})
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.NotErrorIs(t, err, ErrSecretNotFound)
+	assert.ErrorContains(t, err, "access denied on /app/key")
+
+	_, err = s.Read(context.Background(), SecretId{Service: "app", Key: "key"}, 1)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamingLister is implemented by stores which can list secrets a page at
+// a time, rather than holding a whole service, or every service, in memory
+// before returning.
+//
+// Each iterator yields a non-nil error at most once, as its last element.
+// Results are yielded in the order the backend returns them.
+type StreamingLister interface {
+	// ListIter yields the secrets of a service, as List does.
+	ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error]
+	// ListRawIter yields the keys and values of a service, as ListRaw does.
+	ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error]
+	// ListServicesIter yields service names, or secret names if
+	// includeSecretName is true, as ListServices does.
+	ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error]
+}
+
+// ListIter yields the secrets of a service from s, page by page if s is a
+// StreamingLister, or else from a single List.
+func ListIter(ctx context.Context, s Store, service string, includeValues bool) iter.Seq2[Secret, error] {
+	if sl, ok := s.(StreamingLister); ok {
+		return sl.ListIter(ctx, service, includeValues)
+	}
+	return func(yield func(Secret, error) bool) {
+		secrets, err := s.List(ctx, service, includeValues)
+		yieldAll(secrets, err, yield)
+	}
+}
+
+// ListRawIter yields the keys and values of a service from s, page by page if
+// s is a StreamingLister, or else from a single ListRaw.
+func ListRawIter(ctx context.Context, s Store, service string) iter.Seq2[RawSecret, error] {
+	if sl, ok := s.(StreamingLister); ok {
+		return sl.ListRawIter(ctx, service)
+	}
+	return func(yield func(RawSecret, error) bool) {
+		rawSecrets, err := s.ListRaw(ctx, service)
+		yieldAll(rawSecrets, err, yield)
+	}
+}
+
+// ListServicesIter yields service or secret names from s, page by page if s
+// is a StreamingLister, or else from a single ListServices.
+func ListServicesIter(ctx context.Context, s Store, service string, includeSecretName bool) iter.Seq2[string, error] {
+	if sl, ok := s.(StreamingLister); ok {
+		return sl.ListServicesIter(ctx, service, includeSecretName)
+	}
+	return func(yield func(string, error) bool) {
+		services, err := s.ListServices(ctx, service, includeSecretName)
+		yieldAll(services, err, yield)
+	}
+}
+
+// collect gathers everything an iterator yields, stopping at the first error
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	all := []T{}
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, v)
+	}
+	return all, nil
+}
+
+func yieldAll[T any](all []T, err error, yield func(T, error) bool) {
+	if err != nil {
+		var zero T
+		yield(zero, err)
+		return
+	}
+	for _, v := range all {
+		if !yield(v, nil) {
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMListIter(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestSSMStore(map[string]mockParameter{})
+	for i := 0; i < 15; i++ {
+		require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: fmt.Sprintf("key%02d", i)}, fmt.Sprintf("value%d", i)))
+	}
+	require.NoError(t, s.Write(ctx, SecretId{Service: "other", Key: "key"}, "other"))
+
+	t.Run("yields every secret with its value", func(t *testing.T) {
+		values := map[string]string{}
+		for secret, err := range s.ListIter(ctx, "app", true) {
+			require.NoError(t, err)
+			values[secret.Meta.Key] = *secret.Value
+		}
+		assert.Len(t, values, 15)
+		assert.Equal(t, "value14", values["/app/key14"])
+	})
+
+	t.Run("stops when the loop breaks", func(t *testing.T) {
+		n := 0
+		for _, err := range s.ListIter(ctx, "app", false) {
+			require.NoError(t, err)
+			n++
+			break
+		}
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("yields raw secrets", func(t *testing.T) {
+		var keys []string
+		for rawSecret, err := range s.ListRawIter(ctx, "app") {
+			require.NoError(t, err)
+			keys = append(keys, rawSecret.Key)
+		}
+		assert.Len(t, keys, 15)
+	})
+
+	t.Run("yields each service once", func(t *testing.T) {
+		var services []string
+		for service, err := range s.ListServicesIter(ctx, "", false) {
+			require.NoError(t, err)
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		assert.Equal(t, []string{"app", "other"}, services)
+	})
+
+	t.Run("yields errors", func(t *testing.T) {
+		failing := NewTestSSMStore(map[string]mockParameter{})
+		failing.svc.(*apiSSMMock).DescribeParametersFunc = func(ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error) {
+			return nil, errors.New("describe failed")
+		}
+		_, err := failing.List(ctx, "app", true)
+		assert.EqualError(t, err, "describe failed")
+	})
+}
+
+func TestListIterFallback(t *testing.T) {
+	ctx := context.Background()
+	s := NewNullStore()
+
+	for _, err := range ListIter(ctx, s, "app", false) {
+		t.Fatalf("unexpected element, error %v", err)
+	}
+	for _, err := range ListServicesIter(ctx, s, "app", false) {
+		t.Fatalf("unexpected element, error %v", err)
+	}
+
+	rawSecrets, err := collect(ListRawIter(ctx, NewTestSSMStore(map[string]mockParameter{}), "app"))
+	require.NoError(t, err)
+	assert.Empty(t, rawSecrets)
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// KeyProvider wraps and unwraps the data keys which EncryptingStore encrypts
+// values with.
+type KeyProvider interface {
+	// KeyID identifies the key data keys are wrapped with. It's stored
+	// alongside each value, to pick the provider which can unwrap it.
+	KeyID() string
+	// WrapKey encrypts a data key.
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with AES-GCM, using a 256-bit key from a
+// local file.
+type LocalKeyProvider struct {
+	key []byte
+}
+
+var _ KeyProvider = &LocalKeyProvider{}
+
+// NewLocalKeyProvider reads a key from a file, which holds 32 bytes either as
+// they are or encoded as hex or base64.
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseLocalKey(contents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return &LocalKeyProvider{key: key}, nil
+}
+
+func parseLocalKey(contents []byte) ([]byte, error) {
+	if len(contents) == 32 {
+		return contents, nil
+	}
+	text := strings.TrimSpace(string(contents))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("key must be 32 bytes, raw or encoded as hex or base64")
+}
+
+// KeyID is "local:" followed by a fingerprint of the key.
+func (p *LocalKeyProvider) KeyID() string {
+	sum := sha256.Sum256(p.key)
+	return "local:" + hex.EncodeToString(sum[:8])
+}
+
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return sealAESGCM(p.key, dataKey, nil)
+}
+
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return openAESGCM(p.key, wrapped, nil)
+}
+
+// AgeKeyProvider wraps data keys for an age recipient, by running the age
+// command. Unwrapping needs the recipient's identity file.
+type AgeKeyProvider struct {
+	// Recipient is the age public key, e.g. age1...
+	Recipient string
+	// IdentityFile is the path of the private key; only needed for reading.
+	IdentityFile string
+	// Command is the age executable; empty means "age" on $PATH.
+	Command string
+}
+
+var _ KeyProvider = &AgeKeyProvider{}
+
+// KeyID is "age:" followed by the recipient.
+func (p *AgeKeyProvider) KeyID() string {
+	return "age:" + p.Recipient
+}
+
+func (p *AgeKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return p.run(ctx, dataKey, "--encrypt", "--recipient", p.Recipient)
+}
+
+func (p *AgeKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if p.IdentityFile == "" {
+		return nil, fmt.Errorf("no identity file for age recipient %s", p.Recipient)
+	}
+	return p.run(ctx, wrapped, "--decrypt", "--identity", p.IdentityFile)
+}
+
+func (p *AgeKeyProvider) run(ctx context.Context, input []byte, args ...string) ([]byte, error) {
+	command := p.Command
+	if command == "" {
+		command = "age"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("age %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// KMSKeyProvider wraps data keys with an AWS KMS key, so that reading a value
+// needs permission to decrypt with the key, which is logged by CloudTrail.
+type KMSKeyProvider struct {
+	svc   apiKMS
+	keyID string
+}
+
+var _ KeyProvider = &KMSKeyProvider{}
+
+// NewKMSKeyProvider creates a KMSKeyProvider for the KMS key keyID, which may
+// be a key ID, key ARN, alias name (alias/...) or alias ARN.
+func NewKMSKeyProvider(cfg aws.Config, keyID string) *KMSKeyProvider {
+	return &KMSKeyProvider{svc: kms.NewFromConfig(cfg), keyID: keyID}
+}
+
+// KeyID is "kms:" followed by the key ID as given.
+func (p *KMSKeyProvider) KeyID() string {
+	return "kms:" + p.keyID
+}
+
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	resp, err := p.svc.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(p.keyID),
+		Plaintext:         dataKey,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, classifyError(err, p.keyID)
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	resp, err := p.svc.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(p.keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, classifyError(err, p.keyID)
+	}
+	return resp.Plaintext, nil
+}
+
+// kmsEncryptionContext is bound to data keys wrapped with KMS, so that they
+// can't be unwrapped by a call meant for something else, and shows up in
+// CloudTrail
+var kmsEncryptionContext = map[string]string{"chamber": "data-key"}
+
+// sealAESGCM encrypts plaintext with a random nonce, which is prepended to
+// the result
+func sealAESGCM(key, plaintext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openAESGCM decrypts the output of sealAESGCM
+func openAESGCM(key, sealed, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLocalKeyProvider(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dir := t.TempDir()
+
+	var ids []string
+	for name, contents := range map[string]string{
+		"raw":    string(key),
+		"hex":    hex.EncodeToString(key) + "\n",
+		"base64": base64.StdEncoding.EncodeToString(key) + "\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		provider, err := NewLocalKeyProvider(path)
+		require.NoError(t, err, name)
+		ids = append(ids, provider.KeyID())
+	}
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, ids[0], ids[2])
+
+	path := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0600))
+	_, err := NewLocalKeyProvider(path)
+	assert.Error(t, err)
+}
+
+func TestLocalKeyProviderWrapKey(t *testing.T) {
+	ctx := context.Background()
+	provider := &LocalKeyProvider{key: []byte("0123456789abcdef0123456789abcdef")}
+
+	wrapped, err := provider.WrapKey(ctx, []byte("data key"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(wrapped), "data key")
+
+	dataKey, err := provider.UnwrapKey(ctx, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "data key", string(dataKey))
+}
+
+func TestAgeKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	// a stand-in for age which passes its input through
+	command := filepath.Join(t.TempDir(), "age")
+	require.NoError(t, os.WriteFile(command, []byte("#!/bin/sh\ncat\n"), 0700))
+
+	provider := &AgeKeyProvider{Recipient: "age1test", Command: command}
+	assert.Equal(t, "age:age1test", provider.KeyID())
+
+	wrapped, err := provider.WrapKey(ctx, []byte("data key"))
+	require.NoError(t, err)
+	assert.Equal(t, "data key", string(wrapped))
+
+	_, err = provider.UnwrapKey(ctx, wrapped)
+	assert.ErrorContains(t, err, "no identity file")
+
+	provider.IdentityFile = "identity.txt"
+	dataKey, err := provider.UnwrapKey(ctx, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "data key", string(dataKey))
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	svc := &apiKMSMock{
+		EncryptFunc: func(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+			return &kms.EncryptOutput{CiphertextBlob: []byte("wrapped")}, nil
+		},
+		DecryptFunc: func(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+			return &kms.DecryptOutput{Plaintext: []byte("data key")}, nil
+		},
+	}
+	provider := &KMSKeyProvider{svc: svc, keyID: "alias/chamber"}
+	assert.Equal(t, "kms:alias/chamber", provider.KeyID())
+
+	wrapped, err := provider.WrapKey(ctx, []byte("data key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("wrapped"), wrapped)
+	require.Len(t, svc.EncryptCalls(), 1)
+	assert.Equal(t, aws.String("alias/chamber"), svc.EncryptCalls()[0].Params.KeyId)
+	assert.Equal(t, []byte("data key"), svc.EncryptCalls()[0].Params.Plaintext)
+
+	dataKey, err := provider.UnwrapKey(ctx, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data key"), dataKey)
+	// the key and encryption context are checked when unwrapping too
+	assert.Equal(t, wrapped, svc.DecryptCalls()[0].Params.CiphertextBlob)
+	assert.Equal(t, aws.String("alias/chamber"), svc.DecryptCalls()[0].Params.KeyId)
+	assert.Equal(t, svc.EncryptCalls()[0].Params.EncryptionContext, svc.DecryptCalls()[0].Params.EncryptionContext)
+}
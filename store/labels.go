@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLabelNotFound is returned when removing a label which isn't on any
+// version of a secret.
+var ErrLabelNotFound = errors.New("label not found")
+
+// Labeler is implemented by stores which can label versions of secrets, so
+// that a service can be read as <service>:<label>.
+type Labeler interface {
+	// LabelVersion attaches labels to a version of a secret, moving them
+	// from any other version they're on. Use -1 for the latest version.
+	LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error
+	// Unlabel removes labels from whichever versions of a secret they're on.
+	Unlabel(ctx context.Context, id SecretId, labels []string) error
+	// ListLabels returns the labels on each labeled version of a secret.
+	ListLabels(ctx context.Context, id SecretId) (map[int][]string, error)
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMLabels(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestSSMStore(map[string]mockParameter{})
+	id := SecretId{Service: "app", Key: "db_host"}
+	for _, value := range []string{"db1", "db2", "db3"} {
+		require.NoError(t, s.Write(ctx, id, value))
+	}
+
+	t.Run("labels the latest version", func(t *testing.T) {
+		require.NoError(t, s.LabelVersion(ctx, id, -1, []string{"canary"}))
+		labels, err := s.ListLabels(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, map[int][]string{3: {"canary"}}, labels)
+	})
+
+	t.Run("moves a label to another version", func(t *testing.T) {
+		require.NoError(t, s.LabelVersion(ctx, id, 1, []string{"stable"}))
+		require.NoError(t, s.LabelVersion(ctx, id, 2, []string{"stable"}))
+		labels, err := s.ListLabels(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, map[int][]string{2: {"stable"}, 3: {"canary"}}, labels)
+	})
+
+	t.Run("removes labels", func(t *testing.T) {
+		require.NoError(t, s.Unlabel(ctx, id, []string{"canary"}))
+		labels, err := s.ListLabels(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, map[int][]string{2: {"stable"}}, labels)
+
+		err = s.Unlabel(ctx, id, []string{"canary"})
+		assert.ErrorIs(t, err, ErrLabelNotFound)
+	})
+
+	t.Run("missing versions and secrets", func(t *testing.T) {
+		err := s.LabelVersion(ctx, id, 7, []string{"stable"})
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+
+		err = s.LabelVersion(ctx, SecretId{Service: "app", Key: "missing"}, -1, []string{"stable"})
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+
+		_, err = s.ListLabels(ctx, SecretId{Service: "app", Key: "missing"})
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+}
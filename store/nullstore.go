@@ -2,7 +2,7 @@ package store
 
 import (
 	"context"
-	"errors"
+	"fmt"
 )
 
 var _ Store = &NullStore{}
@@ -13,6 +13,11 @@ func NewNullStore() *NullStore {
 	return &NullStore{}
 }
 
+// Supports reports that the Null Store supports no optional features.
+func (s *NullStore) Supports(c Capability) bool {
+	return false
+}
+
 func (s *NullStore) Config(ctx context.Context) (StoreConfig, error) {
 	return StoreConfig{
 		Version: LatestStoreConfigVersion,
@@ -20,27 +25,27 @@ func (s *NullStore) Config(ctx context.Context) (StoreConfig, error) {
 }
 
 func (s *NullStore) SetConfig(ctx context.Context, config StoreConfig) error {
-	return errors.New("SetConfig is not implemented for Null Store")
+	return fmt.Errorf("SetConfig is %w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) Write(ctx context.Context, id SecretId, value string) error {
-	return errors.New("Write is not implemented for Null Store")
+	return fmt.Errorf("Write is %w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
-	return errors.New("WriteWithTags is not implemented for Null Store")
+	return fmt.Errorf("WriteWithTags is %w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
-	return Secret{}, errors.New("Not implemented for Null Store")
+	return Secret{}, fmt.Errorf("%w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
-	return errors.New("Not implemented for Null Store")
+	return fmt.Errorf("%w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) ReadTags(ctx context.Context, id SecretId) (map[string]string, error) {
-	return nil, errors.New("Not implemented for Null Store")
+	return nil, fmt.Errorf("%w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) ListServices(ctx context.Context, service string, includeSecretNames bool) ([]string, error) {
@@ -60,9 +65,9 @@ func (s *NullStore) History(ctx context.Context, id SecretId) ([]ChangeEvent, er
 }
 
 func (s *NullStore) Delete(ctx context.Context, id SecretId) error {
-	return errors.New("Not implemented for Null Store")
+	return fmt.Errorf("%w for Null Store", ErrNotImplemented)
 }
 
 func (s *NullStore) DeleteTags(ctx context.Context, id SecretId, tags []string) error {
-	return errors.New("Not implemented for Null Store")
+	return fmt.Errorf("%w for Null Store", ErrNotImplemented)
 }
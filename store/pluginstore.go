@@ -0,0 +1,542 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Plugins speak JSON-RPC 2.0 over their stdin and stdout, one message per
+// line. chamber first calls `initialize` with the backend configuration, which
+// may answer with the capabilities the plugin supports; then one method per
+// Store method: config, set_config, write, write_with_tags, read, write_tags,
+// read_tags, list, list_raw, list_services, history, delete and delete_tags.
+// Every method takes a pluginParams object; see ServePlugin for a ready-made
+// implementation of the plugin side in Go.
+
+const (
+	pluginMethodInitialize = "initialize"
+
+	// error codes; -32601 is JSON-RPC's own "method not found"
+	pluginCodeMethodNotFound = -32601
+	pluginCodeError          = -32000
+	pluginCodeSecretNotFound = -32001
+	pluginCodeNotImplemented = -32002
+
+	// pluginStopTimeout is how long a plugin has to exit once its stdin is
+	// closed, before it's killed
+	pluginStopTimeout = time.Second
+)
+
+// ensure PluginStore confirms to Store interface
+var _ Store = &PluginStore{}
+
+// pluginParams holds the parameters of every plugin method. Each method only
+// uses the fields matching the arguments of its Store method.
+type pluginParams struct {
+	Config            *BackendConfig    `json:"config,omitempty"`
+	StoreConfig       *StoreConfig      `json:"storeConfig,omitempty"`
+	ID                *pluginSecretId   `json:"id,omitempty"`
+	Service           string            `json:"service,omitempty"`
+	Value             string            `json:"value,omitempty"`
+	Version           int               `json:"version,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	DeleteOtherTags   bool              `json:"deleteOtherTags,omitempty"`
+	TagKeys           []string          `json:"tagKeys,omitempty"`
+	IncludeValues     bool              `json:"includeValues,omitempty"`
+	IncludeSecretName bool              `json:"includeSecretName,omitempty"`
+}
+
+type pluginSecretId struct {
+	Service string `json:"service"`
+	Key     string `json:"key"`
+}
+
+type pluginSecret struct {
+	Value     *string   `json:"value,omitempty"`
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"createdBy"`
+	Version   int       `json:"version"`
+	Key       string    `json:"key"`
+}
+
+type pluginRawSecret struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type pluginChangeEvent struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Version int       `json:"version"`
+}
+
+type pluginInitializeResult struct {
+	// Capabilities lists what the plugin supports; if missing, the plugin is
+	// assumed to support everything.
+	Capabilities *[]Capability `json:"capabilities,omitempty"`
+}
+
+type pluginRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  *pluginParams `json:"params,omitempty"`
+}
+
+type pluginResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *pluginError    `json:"error,omitempty"`
+}
+
+type pluginError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// PluginStore implements the Store interface by calling an external plugin
+// process. Requests are sent one at a time. Once a request is canceled, the
+// plugin is killed and every later request fails.
+type PluginStore struct {
+	name string
+	cmd  *exec.Cmd
+	caps Capabilities
+
+	mu     sync.Mutex
+	w      io.WriteCloser
+	enc    *json.Encoder
+	dec    *json.Decoder
+	nextID uint64
+	err    error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewPluginStore starts the plugin executable at path and initializes it with
+// cfg. The plugin's stderr is passed through to chamber's.
+func NewPluginStore(ctx context.Context, path string, cfg BackendConfig) (*PluginStore, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+
+	name := strings.TrimPrefix(filepath.Base(path), PluginPrefix)
+	s, err := newPluginStore(ctx, name, r, w, cfg)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	s.cmd = cmd
+	return s, nil
+}
+
+func newPluginStore(ctx context.Context, name string, r io.Reader, w io.WriteCloser, cfg BackendConfig) (*PluginStore, error) {
+	s := &PluginStore{
+		name: name,
+		w:    w,
+		enc:  json.NewEncoder(w),
+		dec:  json.NewDecoder(r),
+	}
+	var result pluginInitializeResult
+	if err := s.call(ctx, pluginMethodInitialize, &pluginParams{Config: &cfg}, &result); err != nil {
+		return nil, fmt.Errorf("failed to initialize plugin %s: %w", name, err)
+	}
+	if result.Capabilities != nil {
+		s.caps = NewCapabilities(*result.Capabilities...)
+	}
+	return s, nil
+}
+
+// Supports reports the capabilities the plugin declared when initialized, or
+// that it supports everything if it declared none.
+func (s *PluginStore) Supports(c Capability) bool {
+	return s.caps == nil || s.caps.Supports(c)
+}
+
+// Close stops the plugin by closing its stdin, kills it if it hasn't exited
+// within a second, and waits for it. It doesn't wait for a request in
+// progress, which fails instead. Only the first call stops the plugin; later
+// ones return the same error.
+func (s *PluginStore) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.w.Close()
+		if s.cmd == nil {
+			return
+		}
+
+		exited := make(chan error, 1)
+		go func() {
+			exited <- s.cmd.Wait()
+		}()
+		select {
+		case err := <-exited:
+			if err != nil {
+				s.closeErr = err
+			}
+		case <-time.After(pluginStopTimeout):
+			// it was killed on purpose, so how it exited doesn't matter
+			_ = s.cmd.Process.Kill()
+			<-exited
+		}
+	})
+	return s.closeErr
+}
+
+// kill stops the plugin at once, for when it can't be used anymore. Close
+// still waits for it.
+func (s *PluginStore) kill() {
+	_ = s.w.Close()
+	if s.cmd != nil {
+		_ = s.cmd.Process.Kill()
+	}
+}
+
+func (s *PluginStore) call(ctx context.Context, method string, params *pluginParams, result any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	s.nextID++
+	req := pluginRequest{JSONRPC: "2.0", ID: s.nextID, Method: method, Params: params}
+	var resp pluginResponse
+	done := make(chan error, 1)
+	go func() {
+		done <- s.roundTrip(req, &resp)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		// the plugin may still answer, and its answer would be read as the
+		// response to the next request, so it can't be used anymore
+		s.err = fmt.Errorf("plugin %s was stopped after a request was canceled", s.name)
+		s.kill()
+		return ctx.Err()
+	}
+
+	if resp.ID != req.ID {
+		return fmt.Errorf("plugin %s answered request %d with response %d", s.name, req.ID, resp.ID)
+	}
+	if resp.Error != nil {
+		switch resp.Error.Code {
+		case pluginCodeSecretNotFound:
+			return ErrSecretNotFound
+		case pluginCodeMethodNotFound:
+			return fmt.Errorf("%w for %s plugin store", ErrNotImplemented, s.name)
+		case pluginCodeNotImplemented:
+			return fmt.Errorf("%w for %s plugin store: %s", ErrNotImplemented, s.name, resp.Error.Message)
+		}
+		return fmt.Errorf("plugin %s: %s", s.name, resp.Error.Message)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode response from plugin %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// roundTrip sends req to the plugin and reads its response into resp
+func (s *PluginStore) roundTrip(req pluginRequest, resp *pluginResponse) error {
+	if err := s.enc.Encode(req); err != nil {
+		return fmt.Errorf("failed to send request to plugin %s: %w", s.name, err)
+	}
+	if err := s.dec.Decode(resp); err != nil {
+		return fmt.Errorf("failed to read response from plugin %s: %w", s.name, err)
+	}
+	return nil
+}
+
+func pluginID(id SecretId) *pluginSecretId {
+	return &pluginSecretId{Service: id.Service, Key: id.Key}
+}
+
+func (s *PluginStore) Config(ctx context.Context) (StoreConfig, error) {
+	var config StoreConfig
+	if err := s.call(ctx, "config", &pluginParams{}, &config); err != nil {
+		return StoreConfig{}, err
+	}
+	return config, nil
+}
+
+func (s *PluginStore) SetConfig(ctx context.Context, config StoreConfig) error {
+	return s.call(ctx, "set_config", &pluginParams{StoreConfig: &config}, nil)
+}
+
+func (s *PluginStore) Write(ctx context.Context, id SecretId, value string) error {
+	return s.call(ctx, "write", &pluginParams{ID: pluginID(id), Value: value}, nil)
+}
+
+func (s *PluginStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+	return s.call(ctx, "write_with_tags", &pluginParams{ID: pluginID(id), Value: value, Tags: tags}, nil)
+}
+
+func (s *PluginStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
+	var secret pluginSecret
+	if err := s.call(ctx, "read", &pluginParams{ID: pluginID(id), Version: version}, &secret); err != nil {
+		return Secret{}, err
+	}
+	return secret.toSecret(), nil
+}
+
+func (s *PluginStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
+	return s.call(ctx, "write_tags", &pluginParams{ID: pluginID(id), Tags: tags, DeleteOtherTags: deleteOtherTags}, nil)
+}
+
+func (s *PluginStore) ReadTags(ctx context.Context, id SecretId) (map[string]string, error) {
+	var tags map[string]string
+	if err := s.call(ctx, "read_tags", &pluginParams{ID: pluginID(id)}, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *PluginStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
+	var pluginSecrets []pluginSecret
+	if err := s.call(ctx, "list", &pluginParams{Service: service, IncludeValues: includeValues}, &pluginSecrets); err != nil {
+		return nil, err
+	}
+	secrets := make([]Secret, len(pluginSecrets))
+	for i, secret := range pluginSecrets {
+		secrets[i] = secret.toSecret()
+	}
+	return secrets, nil
+}
+
+func (s *PluginStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	var pluginSecrets []pluginRawSecret
+	if err := s.call(ctx, "list_raw", &pluginParams{Service: service}, &pluginSecrets); err != nil {
+		return nil, err
+	}
+	rawSecrets := make([]RawSecret, len(pluginSecrets))
+	for i, secret := range pluginSecrets {
+		rawSecrets[i] = RawSecret{Key: secret.Key, Value: secret.Value}
+	}
+	return rawSecrets, nil
+}
+
+func (s *PluginStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
+	var services []string
+	if err := s.call(ctx, "list_services", &pluginParams{Service: service, IncludeSecretName: includeSecretName}, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (s *PluginStore) History(ctx context.Context, id SecretId) ([]ChangeEvent, error) {
+	var pluginEvents []pluginChangeEvent
+	if err := s.call(ctx, "history", &pluginParams{ID: pluginID(id)}, &pluginEvents); err != nil {
+		return nil, err
+	}
+	events := make([]ChangeEvent, len(pluginEvents))
+	for i, event := range pluginEvents {
+		events[i] = ChangeEvent{Time: event.Time, User: event.User, Version: event.Version}
+		if event.Type == "updated" {
+			events[i].Type = Updated
+		}
+	}
+	return events, nil
+}
+
+func (s *PluginStore) Delete(ctx context.Context, id SecretId) error {
+	return s.call(ctx, "delete", &pluginParams{ID: pluginID(id)}, nil)
+}
+
+func (s *PluginStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
+	return s.call(ctx, "delete_tags", &pluginParams{ID: pluginID(id), TagKeys: tagKeys}, nil)
+}
+
+func (p pluginSecret) toSecret() Secret {
+	return Secret{
+		Value: p.Value,
+		Meta: SecretMetadata{
+			Created:   p.Created,
+			CreatedBy: p.CreatedBy,
+			Version:   p.Version,
+			Key:       p.Key,
+		},
+	}
+}
+
+func fromSecret(s Secret) pluginSecret {
+	return pluginSecret{
+		Value:     s.Value,
+		Created:   s.Meta.Created,
+		CreatedBy: s.Meta.CreatedBy,
+		Version:   s.Meta.Version,
+		Key:       s.Meta.Key,
+	}
+}
+
+// ServePlugin implements the plugin side of the protocol, for plugins written
+// in Go: it reads requests from r and answers them on w, using the store
+// created by factory when chamber initializes the plugin. It returns when r
+// is closed. A plugin's main function is typically just:
+//
+//	err := store.ServePlugin(ctx, os.Stdin, os.Stdout, newMyStore)
+func ServePlugin(ctx context.Context, r io.Reader, w io.Writer, factory Factory) error {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+
+	var s Store
+	for {
+		var req pluginRequest
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		params := req.Params
+		if params == nil {
+			params = &pluginParams{}
+		}
+
+		var result any
+		var err error
+		if req.Method == pluginMethodInitialize {
+			var cfg BackendConfig
+			if params.Config != nil {
+				cfg = *params.Config
+			}
+			s, err = factory(ctx, cfg)
+			if caps, ok := s.(Capabilities); ok && err == nil {
+				supported := []Capability{}
+				for _, c := range AllCapabilities {
+					if caps.Supports(c) {
+						supported = append(supported, c)
+					}
+				}
+				result = pluginInitializeResult{Capabilities: &supported}
+			}
+		} else if s == nil {
+			err = errors.New("plugin is not initialized")
+		} else {
+			result, err = servePluginMethod(ctx, s, req.Method, params)
+		}
+
+		resp := pluginResponse{JSONRPC: "2.0", ID: req.ID}
+		if err != nil {
+			resp.Error = &pluginError{Code: pluginCodeError, Message: err.Error()}
+			if errors.Is(err, ErrSecretNotFound) {
+				resp.Error.Code = pluginCodeSecretNotFound
+			} else if errors.Is(err, errPluginMethodNotFound) {
+				resp.Error.Code = pluginCodeMethodNotFound
+			} else if errors.Is(err, ErrNotImplemented) {
+				resp.Error.Code = pluginCodeNotImplemented
+			}
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
+
+var errPluginMethodNotFound = errors.New("method not found")
+
+func servePluginMethod(ctx context.Context, s Store, method string, params *pluginParams) (any, error) {
+	var id SecretId
+	if params.ID != nil {
+		id = SecretId{Service: params.ID.Service, Key: params.ID.Key}
+	}
+
+	switch method {
+	case "config":
+		return s.Config(ctx)
+	case "set_config":
+		var config StoreConfig
+		if params.StoreConfig != nil {
+			config = *params.StoreConfig
+		}
+		return nil, s.SetConfig(ctx, config)
+	case "write":
+		return nil, s.Write(ctx, id, params.Value)
+	case "write_with_tags":
+		return nil, s.WriteWithTags(ctx, id, params.Value, params.Tags)
+	case "read":
+		secret, err := s.Read(ctx, id, params.Version)
+		if err != nil {
+			return nil, err
+		}
+		return fromSecret(secret), nil
+	case "write_tags":
+		return nil, s.WriteTags(ctx, id, params.Tags, params.DeleteOtherTags)
+	case "read_tags":
+		return s.ReadTags(ctx, id)
+	case "list":
+		secrets, err := s.List(ctx, params.Service, params.IncludeValues)
+		if err != nil {
+			return nil, err
+		}
+		pluginSecrets := make([]pluginSecret, len(secrets))
+		for i, secret := range secrets {
+			pluginSecrets[i] = fromSecret(secret)
+		}
+		return pluginSecrets, nil
+	case "list_raw":
+		rawSecrets, err := s.ListRaw(ctx, params.Service)
+		if err != nil {
+			return nil, err
+		}
+		pluginSecrets := make([]pluginRawSecret, len(rawSecrets))
+		for i, secret := range rawSecrets {
+			pluginSecrets[i] = pluginRawSecret{Key: secret.Key, Value: secret.Value}
+		}
+		return pluginSecrets, nil
+	case "list_services":
+		return s.ListServices(ctx, params.Service, params.IncludeSecretName)
+	case "history":
+		events, err := s.History(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		pluginEvents := make([]pluginChangeEvent, len(events))
+		for i, event := range events {
+			pluginEvents[i] = pluginChangeEvent{
+				Type:    strings.ToLower(event.Type.String()),
+				Time:    event.Time,
+				User:    event.User,
+				Version: event.Version,
+			}
+		}
+		return pluginEvents, nil
+	case "delete":
+		return nil, s.Delete(ctx, id)
+	case "delete_tags":
+		return nil, s.DeleteTags(ctx, id, params.TagKeys)
+	}
+	return nil, fmt.Errorf("%w: %s", errPluginMethodNotFound, method)
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPluginStore connects a PluginStore to ServePlugin over pipes, as if
+// the plugin were a separate process
+func newTestPluginStore(t *testing.T, factory Factory) *PluginStore {
+	t.Helper()
+	requestsR, requestsW := io.Pipe()
+	responsesR, responsesW := io.Pipe()
+
+	done := make(chan error)
+	go func() {
+		err := ServePlugin(context.Background(), requestsR, responsesW, factory)
+		responsesW.Close()
+		done <- err
+	}()
+
+	s, err := newPluginStore(context.Background(), "test", responsesR, requestsW, BackendConfig{Options: map[string]string{"url": "https://secrets.example.com"}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, s.Close())
+		assert.NoError(t, <-done)
+	})
+	return s
+}
+
+func TestPluginStore(t *testing.T) {
+	ctx := context.Background()
+	var initialized BackendConfig
+	s := newTestPluginStore(t, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		initialized = cfg
+		return NewTestSSMStore(map[string]mockParameter{}), nil
+	})
+	assert.Equal(t, "https://secrets.example.com", initialized.Options["url"])
+
+	id := SecretId{Service: "app", Key: "db_host"}
+	require.NoError(t, s.Write(ctx, id, "db1"))
+	require.NoError(t, s.Write(ctx, id, "db2"))
+
+	secret, err := s.Read(ctx, id, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "db2", *secret.Value)
+	assert.Equal(t, 2, secret.Meta.Version)
+
+	secret, err = s.Read(ctx, id, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "db1", *secret.Value)
+
+	rawSecrets, err := s.ListRaw(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, []RawSecret{{Key: "/app/db_host", Value: "db2"}}, rawSecrets)
+
+	secrets, err := s.List(ctx, "app", false)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	assert.Nil(t, secrets[0].Value)
+	assert.Equal(t, "/app/db_host", secrets[0].Meta.Key)
+
+	events, err := s.History(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, Created, events[0].Type)
+	assert.Equal(t, Updated, events[1].Type)
+
+	_, err = s.Read(ctx, SecretId{Service: "app", Key: "missing"}, -1)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	require.NoError(t, s.Delete(ctx, id))
+	_, err = s.Read(ctx, id, -1)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestPluginStoreErrors(t *testing.T) {
+	s := newTestPluginStore(t, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		return NewNullStore(), nil
+	})
+
+	assert.False(t, s.Supports(CapabilityTags))
+
+	err := s.Write(context.Background(), SecretId{Service: "app", Key: "key"}, "value")
+	assert.ErrorIs(t, err, ErrNotImplemented)
+	assert.EqualError(t, err, "not implemented for test plugin store: Write is not implemented for Null Store")
+
+	err = s.call(context.Background(), "rotate", &pluginParams{}, nil)
+	assert.ErrorIs(t, err, ErrNotImplemented)
+}
+
+// blockingStore blocks ListRaw until unblock is closed
+type blockingStore struct {
+	NullStore
+	unblock chan struct{}
+}
+
+func (s *blockingStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	<-s.unblock
+	return []RawSecret{}, nil
+}
+
+func TestPluginStoreCanceled(t *testing.T) {
+	unblock := make(chan struct{})
+	s := newTestPluginStore(t, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		return &blockingStore{unblock: unblock}, nil
+	})
+	t.Cleanup(func() { close(unblock) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := s.ListRaw(ctx, "app")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.ListRaw(context.Background(), "app")
+	assert.EqualError(t, err, "plugin test was stopped after a request was canceled")
+}
+
+func TestPluginStoreClose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	// a plugin which initializes, then ignores its stdin closing
+	path := filepath.Join(t.TempDir(), PluginPrefix+"stuck")
+	script := "#!/bin/sh\nread line\necho '{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}'\nexec sleep 60\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+
+	s, err := NewPluginStore(context.Background(), path, BackendConfig{})
+	require.NoError(t, err)
+
+	start := time.Now()
+	assert.NoError(t, s.Close())
+	assert.Less(t, time.Since(start), 10*time.Second)
+	// killed, rather than exited
+	require.NotNil(t, s.cmd.ProcessState)
+	assert.False(t, s.cmd.ProcessState.Exited())
+	assert.NoError(t, s.Close())
+}
+
+func TestOpen(t *testing.T) {
+	s, err := Open(context.Background(), "null", BackendConfig{})
+	require.NoError(t, err)
+	assert.IsType(t, &NullStore{}, s)
+
+	_, err = Open(context.Background(), "s3", BackendConfig{})
+	assert.EqualError(t, err, "Must set bucket for s3 backend")
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = Open(context.Background(), "vault", BackendConfig{})
+	assert.EqualError(t, err, "invalid backend `vault`")
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-registered", func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		return NewNullStore(), nil
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "TEST-REGISTERED")
+		registryMu.Unlock()
+	}()
+
+	assert.Contains(t, Backends(), "TEST-REGISTERED")
+	s, err := Open(context.Background(), "Test-Registered", BackendConfig{})
+	require.NoError(t, err)
+	assert.IsType(t, &NullStore{}, s)
+
+	assert.Panics(t, func() {
+		Register("TEST-REGISTERED", func(ctx context.Context, cfg BackendConfig) (Store, error) {
+			return nil, nil
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RedactedMask replaces secret values in logs and errors.
+const RedactedMask = "*****"
+
+// minRedactedLength is the length below which known values aren't masked
+// wherever they appear, since values such as "true", "prod" or "info" are
+// more often words in a message than secrets
+const minRedactedLength = 8
+
+var redactor = struct {
+	sync.RWMutex
+	values map[string]struct{}
+	// sorted is values, longest first, so that a value containing another is
+	// masked whole; nil when values has changed
+	sorted []string
+	show   bool
+}{values: map[string]struct{}{}}
+
+// Redacted is a secret value, which is masked when formatted, logged or
+// marshalled. Use string(r) for the value itself.
+type Redacted string
+
+func (r Redacted) String() string {
+	if ShowingValues() {
+		return string(r)
+	}
+	return RedactedMask
+}
+
+func (r Redacted) GoString() string {
+	return `"` + r.String() + `"`
+}
+
+func (r Redacted) LogValue() slog.Value {
+	return slog.StringValue(r.String())
+}
+
+func (r Redacted) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// AddKnownSecrets records values as secrets, so that Redact masks them
+// wherever they appear.
+func AddKnownSecrets(values ...string) {
+	redactor.Lock()
+	defer redactor.Unlock()
+	for _, value := range values {
+		if len(value) < minRedactedLength {
+			continue
+		}
+		if _, ok := redactor.values[value]; !ok {
+			redactor.values[value] = struct{}{}
+			redactor.sorted = nil
+		}
+	}
+}
+
+// SetShowValues turns masking off, for --unsafe-show-values, or back on.
+func SetShowValues(show bool) {
+	redactor.Lock()
+	defer redactor.Unlock()
+	redactor.show = show
+}
+
+// ShowingValues reports whether masking has been turned off.
+func ShowingValues() bool {
+	redactor.RLock()
+	defer redactor.RUnlock()
+	return redactor.show
+}
+
+// Redact masks every known secret value in s, where it's a whole token: a
+// value starting or ending with a letter, digit or underscore isn't masked
+// where it runs into another one, e.g. within a longer word.
+func Redact(s string) string {
+	redactor.RLock()
+	show, sorted := redactor.show, redactor.sorted
+	redactor.RUnlock()
+	if show {
+		return s
+	}
+
+	if sorted == nil {
+		redactor.Lock()
+		if redactor.sorted == nil {
+			redactor.sorted = make([]string, 0, len(redactor.values))
+			for value := range redactor.values {
+				redactor.sorted = append(redactor.sorted, value)
+			}
+			sort.Slice(redactor.sorted, func(i, j int) bool {
+				return len(redactor.sorted[i]) > len(redactor.sorted[j])
+			})
+		}
+		sorted = redactor.sorted
+		redactor.Unlock()
+	}
+
+	for _, value := range sorted {
+		s = redactTokens(s, value)
+	}
+	return s
+}
+
+// redactTokens masks value in s wherever it's a whole token
+func redactTokens(s, value string) string {
+	first, _ := utf8.DecodeRuneInString(value)
+	last, _ := utf8.DecodeLastRuneInString(value)
+	var b strings.Builder
+	written := 0
+	for from := 0; from < len(s); {
+		i := strings.Index(s[from:], value)
+		if i < 0 {
+			break
+		}
+		start, end := from+i, from+i+len(value)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (start > 0 && isWordRune(first) && isWordRune(before)) || (end < len(s) && isWordRune(last) && isWordRune(after)) {
+			// part of a longer token
+			_, size := utf8.DecodeRuneInString(s[start:])
+			from = start + size
+			continue
+		}
+		b.WriteString(s[written:start])
+		b.WriteString(RedactedMask)
+		written, from = end, end
+	}
+	if written == 0 {
+		return s
+	}
+	b.WriteString(s[written:])
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// redactingHandler masks known secret values in the messages and attributes
+// of log records before passing them on
+type redactingHandler struct {
+	slog.Handler
+}
+
+// NewRedactingHandler wraps h so that known secret values are masked in
+// everything it logs.
+func NewRedactingHandler(h slog.Handler) slog.Handler {
+	return &redactingHandler{Handler: h}
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &redactingHandler{Handler: h.Handler.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		redacted := make([]any, len(attrs))
+		for i, a := range attrs {
+			redacted[i] = redactAttr(a)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		return slog.String(attr.Key, Redact(fmt.Sprint(value.Any())))
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedacted(t *testing.T) {
+	r := Redacted("hunter22")
+	assert.Equal(t, RedactedMask, fmt.Sprintf("%s", r))
+	assert.Equal(t, RedactedMask, fmt.Sprintf("%v", r))
+	assert.Equal(t, `"*****"`, fmt.Sprintf("%#v", r))
+	assert.Equal(t, "hunter22", string(r))
+
+	SetShowValues(true)
+	t.Cleanup(func() { SetShowValues(false) })
+	assert.Equal(t, "hunter22", r.String())
+}
+
+func TestRedact(t *testing.T) {
+	AddKnownSecrets("hunter22", "hunter22-longer", "prod", "true", "-----BEGIN KEY-----")
+
+	assert.Equal(t, "password is *****", Redact("password is hunter22"))
+	assert.Equal(t, "both ***** and *****", Redact("both hunter22-longer and hunter22"))
+	assert.Equal(t, "KEY=*****,OTHER=*****", Redact("KEY=hunter22,OTHER=hunter22"))
+	assert.Equal(t, "*****", Redact("hunter22"))
+	assert.Equal(t, "key *****\n", Redact("key -----BEGIN KEY-----\n"))
+	assert.Equal(t, "key x*****x", Redact("key x-----BEGIN KEY-----x"))
+
+	// only whole tokens are masked
+	assert.Equal(t, "hunter222 and xhunter22 but *****", Redact("hunter222 and xhunter22 but hunter22"))
+	assert.Equal(t, "hunter22hunter22", Redact("hunter22hunter22"))
+
+	// short values are too likely to be words
+	assert.Equal(t, "service prod is true", Redact("service prod is true"))
+
+	SetShowValues(true)
+	t.Cleanup(func() { SetShowValues(false) })
+	assert.Equal(t, "password is hunter22", Redact("password is hunter22"))
+}
+
+func TestRedactingHandler(t *testing.T) {
+	AddKnownSecrets("s3cr3t-value")
+
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&buf, nil)))
+	logger.With("preset", "s3cr3t-value").Info("env is KEY=s3cr3t-value",
+		"value", "s3cr3t-value",
+		"err", errors.New("bad s3cr3t-value"),
+		"redacted", Redacted("anything"),
+		slog.Group("group", "nested", "s3cr3t-value"),
+		"count", 3,
+	)
+
+	assert.NotContains(t, buf.String(), "s3cr3t-value")
+	assert.NotContains(t, buf.String(), "anything")
+	assert.Contains(t, buf.String(), `msg="env is KEY=*****"`)
+	assert.Contains(t, buf.String(), "group.nested=*****")
+	assert.Contains(t, buf.String(), "count=3")
+}
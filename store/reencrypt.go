@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Reencrypter is implemented by stores which can move secrets from one KMS
+// key to another. The KMS key of each secret is in its SecretMetadata.
+type Reencrypter interface {
+	// Reencrypt rewrites a secret under kmsKey, keeping its version number.
+	// It does nothing if the secret already uses kmsKey.
+	Reencrypt(ctx context.Context, id SecretId, kmsKey string) error
+}
+
+// kmsKeyIDFormat matches a KMS key ID, single or multi-region
+var kmsKeyIDFormat = regexp.MustCompile(`^(mrk-[0-9a-f]{32}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// NormalizeKMSKey adds the alias/ prefix to a KMS key if it's missing, as for
+// --kms-key-alias, unless the key is an ARN or a key ID.
+func NormalizeKMSKey(kmsKey string) string {
+	if strings.HasPrefix(kmsKey, "arn:") || kmsKeyIDFormat.MatchString(kmsKey) {
+		return kmsKey
+	}
+	return kmsKeyAlias(kmsKey)
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// s3Object is an object in a mock bucket, with the KMS key it's encrypted
+// with
+type s3Object struct {
+	body   []byte
+	kmsKey string
+}
+
+func newTestS3KMSStore(objects map[string]s3Object, kmsKeyAlias string) *S3KMSStore {
+	svc := &apiS3Mock{
+		GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			obj, ok := objects[*params.Key]
+			if !ok {
+				return nil, &s3types.NoSuchKey{}
+			}
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(obj.body))}, nil
+		},
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			body, err := io.ReadAll(params.Body)
+			if err != nil {
+				return nil, err
+			}
+			objects[*params.Key] = s3Object{body: body, kmsKey: aws.ToString(params.SSEKMSKeyId)}
+			return &s3.PutObjectOutput{}, nil
+		},
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			var keys []string
+			for key := range objects {
+				if strings.HasPrefix(key, *params.Prefix) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			output := &s3.ListObjectsV2Output{}
+			for _, key := range keys {
+				output.Contents = append(output.Contents, s3types.Object{Key: aws.String(key)})
+			}
+			return output, nil
+		},
+	}
+	stsSvc := &apiSTSMock{
+		GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{Arn: aws.String("arn:aws:iam::123456789012:user/test")}, nil
+		},
+	}
+	return &S3KMSStore{
+		S3Store:     S3Store{svc: svc, stsSvc: stsSvc, bucket: "bucket"},
+		svc:         svc,
+		stsSvc:      stsSvc,
+		bucket:      "bucket",
+		kmsKeyAlias: kmsKeyAlias,
+	}
+}
+
+func TestSSMReencrypt(t *testing.T) {
+	ctx := context.Background()
+	parameters := map[string]mockParameter{}
+	s := NewTestSSMStore(parameters)
+	id := SecretId{Service: "app", Key: "key"}
+	require.NoError(t, s.Write(ctx, id, "one"))
+	require.NoError(t, s.Write(ctx, id, "two"))
+
+	require.NoError(t, s.Reencrypt(ctx, id, "alias/new"))
+	assert.Equal(t, "alias/new", *parameters["/app/key"].meta.KeyId)
+
+	secret, err := s.Read(ctx, id, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "two", *secret.Value)
+	assert.Equal(t, 2, secret.Meta.Version)
+	assert.Equal(t, "alias/new", secret.Meta.KMSKey)
+
+	// already on the new key
+	require.NoError(t, s.Reencrypt(ctx, id, "alias/new"))
+	assert.Len(t, parameters["/app/key"].history, 3)
+
+	expiring := SecretId{Service: "app", Key: "expiring"}
+	policies := Policies{ExpiresAt: time.Now().Add(24 * time.Hour).Truncate(time.Second), NoChangeNotification: 48 * time.Hour}
+	require.NoError(t, s.WriteWithOptions(ctx, expiring, "value", WriteOptions{Policies: policies}))
+	require.NoError(t, s.Reencrypt(ctx, expiring, "alias/new"))
+	secret, err = s.Read(ctx, expiring, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "alias/new", secret.Meta.KMSKey)
+	assert.Equal(t, TierAdvanced, secret.Meta.Tier)
+	assert.True(t, policies.ExpiresAt.Equal(secret.Meta.Policies.ExpiresAt))
+	assert.Equal(t, policies.NoChangeNotification, secret.Meta.Policies.NoChangeNotification)
+
+	// labels on the old version move to the new one, and a bare alias gets
+	// its prefix
+	labeled := SecretId{Service: "app", Key: "labeled"}
+	require.NoError(t, s.Write(ctx, labeled, "one"))
+	require.NoError(t, s.Write(ctx, labeled, "two"))
+	require.NoError(t, s.LabelVersion(ctx, labeled, 1, []string{"old"}))
+	require.NoError(t, s.LabelVersion(ctx, labeled, 2, []string{"prod", "canary"}))
+	require.NoError(t, s.Reencrypt(ctx, labeled, "new"))
+	assert.Equal(t, "alias/new", *parameters["/app/labeled"].meta.KeyId)
+	history := parameters["/app/labeled"].history
+	require.Len(t, history, 3)
+	assert.Empty(t, history[1].Labels)
+	assert.ElementsMatch(t, []string{"prod", "canary"}, history[2].Labels)
+	assert.Equal(t, []string{"old"}, history[0].Labels)
+	require.NoError(t, s.Reencrypt(ctx, labeled, "new"))
+	assert.Len(t, parameters["/app/labeled"].history, 3)
+
+	plain := SecretId{Service: "app", Key: "plain"}
+	require.NoError(t, s.WriteWithOptions(ctx, plain, "value", WriteOptions{Type: TypeString}))
+	assert.ErrorContains(t, s.Reencrypt(ctx, plain, "alias/new"), "isn't encrypted with KMS")
+}
+
+func TestS3KMSReencrypt(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string]s3Object{}
+	s := newTestS3KMSStore(objects, "alias/old")
+	id := SecretId{Service: "app", Key: "key"}
+	require.NoError(t, s.Write(ctx, id, "one"))
+	require.NoError(t, s.Write(ctx, id, "two"))
+	require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "other"}, "other"))
+
+	require.NoError(t, s.Reencrypt(ctx, id, "alias/new"))
+	assert.Equal(t, "alias/new", objects["app/key.json"].kmsKey)
+	assert.Equal(t, "alias/old", objects["app/other.json"].kmsKey)
+	assert.Equal(t, "alias/new", objects["app/__kms_alias_new__latest.json"].kmsKey)
+	assert.NotContains(t, string(objects["app/__kms_alias_old__latest.json"].body), `"key"`)
+
+	// a store using the new key can now write the secret
+	moved := newTestS3KMSStore(objects, "alias/new")
+	secrets, err := moved.List(ctx, "app", true)
+	require.NoError(t, err)
+	keys := map[string]string{}
+	for _, secret := range secrets {
+		keys[secret.Meta.Key] = secret.Meta.KMSKey
+		if secret.Meta.Key == "/app/key" {
+			assert.Equal(t, "two", *secret.Value)
+			assert.Equal(t, 2, secret.Meta.Version)
+		}
+	}
+	assert.Equal(t, map[string]string{"/app/key": "alias/new", "/app/other": "alias/old"}, keys)
+	require.NoError(t, moved.Write(ctx, id, "three"))
+
+	secret, err := moved.Read(ctx, id, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "one", *secret.Value)
+}
+
+func TestNormalizeKMSKey(t *testing.T) {
+	for kmsKey, expected := range map[string]string{
+		"new":       "alias/new",
+		"alias/new": "alias/new",
+		"arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab": "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		"1234abcd-12ab-34cd-56ef-1234567890ab":                                        "1234abcd-12ab-34cd-56ef-1234567890ab",
+		"mrk-1234abcd12ab34cd56ef1234567890ab":                                        "mrk-1234abcd12ab34cd56ef1234567890ab",
+	} {
+		assert.Equal(t, expected, NormalizeKMSKey(kmsKey), kmsKey)
+	}
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	NullBackend           = "NULL"
+	SSMBackend            = "SSM"
+	SecretsManagerBackend = "SECRETSMANAGER"
+	S3Backend             = "S3"
+	S3KMSBackend          = "S3-KMS"
+
+	// PluginPrefix is the prefix of the name of executables implementing
+	// external backends, e.g. chamber-backend-vault for the backend `vault`.
+	PluginPrefix = "chamber-backend-"
+)
+
+// BackendConfig is the configuration passed to a backend's factory. Backends
+// ignore the fields they have no use for.
+type BackendConfig struct {
+	// Region is the AWS region; empty means $CHAMBER_AWS_REGION or the SDK's
+	// own lookup.
+	Region string `json:"region,omitempty"`
+	// NumRetries is the maximum number of attempts for each request.
+	NumRetries int `json:"numRetries,omitempty"`
+	// RetryMode is the AWS SDK retry mode; empty means DefaultRetryMode.
+	RetryMode aws.RetryMode `json:"retryMode,omitempty"`
+	// KMSKeyAlias is the KMS key alias used to encrypt written secrets.
+	KMSKeyAlias string `json:"kmsKeyAlias,omitempty"`
+	// Bucket is the bucket secrets are stored in.
+	Bucket string `json:"bucket,omitempty"`
+	// Endpoint is a custom endpoint for the backend's AWS service. The
+	// $CHAMBER_AWS_SSM_ENDPOINT and $CHAMBER_AWS_SECRETS_MANAGER_ENDPOINT
+	// environment variables take precedence.
+	Endpoint string `json:"endpoint,omitempty"`
+	// RoleARN is an IAM role to assume, e.g. in another account, using the
+	// credentials found as usual.
+	RoleARN string `json:"roleArn,omitempty"`
+	// Options holds backend specific settings, e.g. for plugins.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Factory creates a store from its configuration.
+type Factory func(ctx context.Context, cfg BackendConfig) (Store, error)
+
+type backend struct {
+	factory Factory
+	caps    Capabilities
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]backend{}
+)
+
+// Register makes a backend available under name, which is compared
+// case-insensitively. It panics if name is already registered, so it's meant
+// to be called from init functions.
+func Register(name string, factory Factory) {
+	RegisterWithCapabilities(name, factory, nil)
+}
+
+// RegisterWithCapabilities is like Register, but also records what the
+// backend's stores support, so it can be reported without creating one.
+func RegisterWithCapabilities(name string, factory Factory, caps Capabilities) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name = strings.ToUpper(name)
+	if factory == nil {
+		panic("store: Register factory is nil for backend " + name)
+	}
+	if _, ok := registry[name]; ok {
+		panic("store: Register called twice for backend " + name)
+	}
+	registry[name] = backend{factory: factory, caps: caps}
+}
+
+// BackendCapabilities returns the capabilities recorded for the backend name,
+// if any.
+func BackendCapabilities(name string) (Capabilities, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	b, ok := registry[strings.ToUpper(name)]
+	if !ok || b.caps == nil {
+		return nil, false
+	}
+	return b.caps, true
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open creates a store for the backend name. Registered backends come first;
+// otherwise an executable named chamber-backend-<name> on $PATH is started as
+// a plugin.
+func Open(ctx context.Context, name string, cfg BackendConfig) (Store, error) {
+	registryMu.RLock()
+	b, ok := registry[strings.ToUpper(name)]
+	registryMu.RUnlock()
+	if ok {
+		return b.factory(ctx, cfg)
+	}
+
+	path, err := exec.LookPath(PluginPrefix + strings.ToLower(name))
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("invalid backend `%s`", name)
+		}
+		return nil, err
+	}
+	return NewPluginStore(ctx, path, cfg)
+}
+
+// Plugins returns the sorted names of the plugin backends found on $PATH.
+func Plugins() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" || entry.IsDir() || seen[name] {
+				continue
+			}
+			if _, err := exec.LookPath(filepath.Join(dir, entry.Name())); err != nil {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterWithCapabilities(NullBackend, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		return NewNullStore(), nil
+	}, &NullStore{})
+	RegisterWithCapabilities(SSMBackend, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		awsCfg, err := awsConfig(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSSMStoreFromConfig(awsCfg, kmsKeyAlias(cfg.KMSKeyAlias)), nil
+	}, &SSMStore{})
+	RegisterWithCapabilities(SecretsManagerBackend, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		if cfg.KMSKeyAlias != "" {
+			return nil, errors.New("Unable to use a KMS key alias with the SECRETSMANAGER backend")
+		}
+		awsCfg, err := awsConfig(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSecretsManagerStoreFromConfig(awsCfg), nil
+	}, &SecretsManagerStore{})
+	RegisterWithCapabilities(S3Backend, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		if cfg.KMSKeyAlias != "" {
+			return nil, errors.New("Unable to use a KMS key alias with the S3 backend")
+		}
+		if cfg.Bucket == "" {
+			return nil, errors.New("Must set bucket for s3 backend")
+		}
+		awsCfg, err := awsConfig(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3StoreFromConfig(awsCfg, cfg.Bucket), nil
+	}, &S3Store{})
+	RegisterWithCapabilities(S3KMSBackend, func(ctx context.Context, cfg BackendConfig) (Store, error) {
+		if cfg.Bucket == "" {
+			return nil, errors.New("Must set bucket for s3 backend")
+		}
+		awsCfg, err := awsConfig(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3KMSStoreFromConfig(awsCfg, cfg.Bucket, kmsKeyAlias(cfg.KMSKeyAlias)), nil
+	}, &S3KMSStore{})
+}
+
+func awsConfig(ctx context.Context, cfg BackendConfig) (aws.Config, error) {
+	retryMode := cfg.RetryMode
+	if retryMode == "" {
+		retryMode = DefaultRetryMode
+	}
+	awsCfg, err := NewConfig(ctx, cfg.Region, cfg.NumRetries, retryMode)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if cfg.RoleARN != "" {
+		awsCfg = withRole(awsCfg, cfg.RoleARN)
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.BaseEndpoint = aws.String(cfg.Endpoint)
+	}
+	return awsCfg, nil
+}
+
+// kmsKeyAlias adds the alias/ prefix to a KMS key alias if it's missing
+func kmsKeyAlias(alias string) string {
+	if alias != "" && !strings.HasPrefix(alias, "alias/") {
+		return fmt.Sprintf("alias/%s", alias)
+	}
+	return alias
+}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Replica is a secondary store, e.g. in another region or account, named for
+// error messages.
+type Replica struct {
+	Name  string
+	Store Store
+}
+
+// ReplicatingStore mirrors every write and delete on a primary store to one or
+// more replicas. Reads, labels and re-encryption only go to the primary, since
+// versions and KMS keys differ between stores.
+//
+// Replicas are written after the primary succeeds. If any replica fails, the
+// error says which, and the primary and the other replicas keep the change;
+// Replicate brings a replica back in line.
+type ReplicatingStore struct {
+	Store
+	replicas []Replica
+}
+
+var (
+	_ Store           = &ReplicatingStore{}
+	_ BatchReader     = &ReplicatingStore{}
+	_ BatchWriter     = &ReplicatingStore{}
+	_ StreamingLister = &ReplicatingStore{}
+	_ OptionsWriter   = &ReplicatingStore{}
+	_ Labeler         = &ReplicatingStore{}
+	_ Reencrypter     = &ReplicatingStore{}
+)
+
+// NewReplicatingStore wraps s, mirroring writes and deletes to replicas.
+func NewReplicatingStore(s Store, replicas ...Replica) *ReplicatingStore {
+	return &ReplicatingStore{Store: s, replicas: replicas}
+}
+
+// Supports reports what the primary store supports.
+func (s *ReplicatingStore) Supports(c Capability) bool {
+	return Supports(s.Store, c)
+}
+
+// replicate runs op on each replica, once the primary has succeeded
+func (s *ReplicatingStore) replicate(primaryErr error, op func(Store) error) error {
+	if primaryErr != nil {
+		return primaryErr
+	}
+	var errs []error
+	for _, replica := range s.replicas {
+		if err := op(replica.Store); err != nil {
+			errs = append(errs, fmt.Errorf("failed to replicate to %s: %w", replica.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ReplicatingStore) Write(ctx context.Context, id SecretId, value string) error {
+	return s.replicate(s.Store.Write(ctx, id, value), func(r Store) error {
+		return r.Write(ctx, id, value)
+	})
+}
+
+func (s *ReplicatingStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+	return s.replicate(s.Store.WriteWithTags(ctx, id, value, tags), func(r Store) error {
+		return r.WriteWithTags(ctx, id, value, tags)
+	})
+}
+
+func (s *ReplicatingStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error {
+	ow, ok := s.Store.(OptionsWriter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+	}
+	return s.replicate(ow.WriteWithOptions(ctx, id, value, opts), func(r Store) error {
+		rw, ok := r.(OptionsWriter)
+		if !ok {
+			return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+		}
+		return rw.WriteWithOptions(ctx, id, value, opts)
+	})
+}
+
+func (s *ReplicatingStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	return s.replicate(WriteMany(ctx, s.Store, values), func(r Store) error {
+		return WriteMany(ctx, r, values)
+	})
+}
+
+func (s *ReplicatingStore) ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error) {
+	return ReadMany(ctx, s.Store, ids)
+}
+
+func (s *ReplicatingStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
+	return s.replicate(s.Store.WriteTags(ctx, id, tags, deleteOtherTags), func(r Store) error {
+		return r.WriteTags(ctx, id, tags, deleteOtherTags)
+	})
+}
+
+func (s *ReplicatingStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
+	return s.replicate(s.Store.DeleteTags(ctx, id, tagKeys), func(r Store) error {
+		return r.DeleteTags(ctx, id, tagKeys)
+	})
+}
+
+// Delete deletes a secret from the primary and every replica. A replica
+// which doesn't have the secret isn't an error.
+func (s *ReplicatingStore) Delete(ctx context.Context, id SecretId) error {
+	return s.replicate(s.Store.Delete(ctx, id), func(r Store) error {
+		if err := r.Delete(ctx, id); err != nil && !errors.Is(err, ErrSecretNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *ReplicatingStore) ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error] {
+	return ListIter(ctx, s.Store, service, includeValues)
+}
+
+func (s *ReplicatingStore) ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error] {
+	return ListRawIter(ctx, s.Store, service)
+}
+
+func (s *ReplicatingStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return ListServicesIter(ctx, s.Store, service, includeSecretName)
+}
+
+func (s *ReplicatingStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.LabelVersion(ctx, id, version, labels)
+}
+
+func (s *ReplicatingStore) Unlabel(ctx context.Context, id SecretId, labels []string) error {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.Unlabel(ctx, id, labels)
+}
+
+func (s *ReplicatingStore) ListLabels(ctx context.Context, id SecretId) (map[int][]string, error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return nil, fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.ListLabels(ctx, id)
+}
+
+func (s *ReplicatingStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	r, ok := s.Store.(Reencrypter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityReencrypt)
+	}
+	return r.Reencrypt(ctx, id, kmsKey)
+}
+
+// ReplicateOptions control Replicate.
+type ReplicateOptions struct {
+	// DryRun reports the drift without changing anything.
+	DryRun bool
+	// Prune deletes secrets from the destination which aren't in the source.
+	Prune bool
+}
+
+// ReplicationReport is the drift found, and fixed unless it was a dry run,
+// between a service in two stores. Each list holds sorted secret keys.
+type ReplicationReport struct {
+	Service string
+	// Created are the secrets missing from the destination.
+	Created []string
+	// Updated are the secrets whose values differ.
+	Updated []string
+	// Extra are the secrets only in the destination, which are deleted if
+	// pruning.
+	Extra []string
+	// Unchanged are the secrets already the same in both stores.
+	Unchanged []string
+}
+
+// Drifted reports whether the stores differed.
+func (r ReplicationReport) Drifted() bool {
+	return len(r.Created) > 0 || len(r.Updated) > 0 || len(r.Extra) > 0
+}
+
+// Replicate makes the latest values of a service in dst match src. It's
+// idempotent: both stores are listed with ListRaw first, and only the secrets
+// which are missing or differ are written, so running it again changes
+// nothing. Secrets only in dst are left alone unless opts.Prune is set.
+func Replicate(ctx context.Context, src, dst Store, service string, opts ReplicateOptions) (ReplicationReport, error) {
+	report := ReplicationReport{Service: service}
+
+	srcSecrets, err := rawSecretsByKey(ctx, src, service)
+	if err != nil {
+		return report, fmt.Errorf("failed to list source: %w", err)
+	}
+	dstSecrets, err := rawSecretsByKey(ctx, dst, service)
+	if err != nil {
+		return report, fmt.Errorf("failed to list destination: %w", err)
+	}
+
+	writes := map[SecretId]string{}
+	for key, value := range srcSecrets {
+		dstValue, ok := dstSecrets[key]
+		switch {
+		case !ok:
+			report.Created = append(report.Created, key)
+		case dstValue != value:
+			report.Updated = append(report.Updated, key)
+		default:
+			report.Unchanged = append(report.Unchanged, key)
+			continue
+		}
+		writes[SecretId{Service: service, Key: key}] = value
+	}
+	for key := range dstSecrets {
+		if _, ok := srcSecrets[key]; !ok {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+	sort.Strings(report.Created)
+	sort.Strings(report.Updated)
+	sort.Strings(report.Extra)
+	sort.Strings(report.Unchanged)
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if len(writes) > 0 {
+		if err := WriteMany(ctx, dst, writes); err != nil {
+			return report, fmt.Errorf("failed to write destination: %w", err)
+		}
+	}
+	if opts.Prune {
+		for _, key := range report.Extra {
+			if err := dst.Delete(ctx, SecretId{Service: service, Key: key}); err != nil {
+				return report, fmt.Errorf("failed to delete %s from destination: %w", key, err)
+			}
+		}
+	}
+	return report, nil
+}
+
+// rawSecretsByKey lists the values of service in s by secret key, treating a
+// service which doesn't exist as empty
+func rawSecretsByKey(ctx context.Context, s Store, service string) (map[string]string, error) {
+	rawSecrets, err := s.ListRaw(ctx, service)
+	if err != nil && !errors.Is(err, ErrSecretNotFound) {
+		return nil, err
+	}
+	secrets := make(map[string]string, len(rawSecrets))
+	for _, rawSecret := range rawSecrets {
+		key := rawSecret.Key
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			key = key[i+1:]
+		}
+		secrets[key] = rawSecret.Value
+	}
+	return secrets, nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicatingStore(t *testing.T) {
+	ctx := context.Background()
+	primary := NewTestSSMStore(map[string]mockParameter{})
+	replica := NewTestSSMStore(map[string]mockParameter{})
+	s := NewReplicatingStore(primary, Replica{Name: "us-west-2", Store: replica})
+	id := SecretId{Service: "app", Key: "db_password"}
+
+	t.Run("writes are mirrored", func(t *testing.T) {
+		require.NoError(t, s.Write(ctx, id, "secret"))
+		require.NoError(t, s.WriteTags(ctx, id, map[string]string{"team": "payments"}, false))
+
+		for _, store := range []Store{primary, replica} {
+			secret, err := store.Read(ctx, id, -1)
+			require.NoError(t, err)
+			assert.Equal(t, "secret", *secret.Value)
+			tags, err := store.ReadTags(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, "payments", tags["team"])
+		}
+	})
+
+	t.Run("deletes are mirrored, and a replica without the secret is fine", func(t *testing.T) {
+		other := SecretId{Service: "app", Key: "primary_only"}
+		require.NoError(t, primary.Write(ctx, other, "x"))
+		require.NoError(t, s.Delete(ctx, other))
+
+		require.NoError(t, s.Delete(ctx, id))
+		_, err := replica.Read(ctx, id, -1)
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+
+	t.Run("replica errors say which replica failed", func(t *testing.T) {
+		failing := NewReplicatingStore(primary, Replica{Name: "dr", Store: &failingWriteStore{Store: replica}})
+		err := failing.Write(ctx, id, "again")
+		assert.EqualError(t, err, "failed to replicate to dr: invalid value again")
+
+		// the primary keeps the write
+		secret, err := primary.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "again", *secret.Value)
+	})
+
+	t.Run("primary errors aren't replicated", func(t *testing.T) {
+		failing := NewReplicatingStore(&failingWriteStore{Store: primary}, Replica{Name: "dr", Store: replica})
+		err := failing.Write(ctx, SecretId{Service: "app", Key: "never"}, "x")
+		assert.EqualError(t, err, "invalid value x")
+		_, err = replica.Read(ctx, SecretId{Service: "app", Key: "never"}, -1)
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+}
+
+func TestReplicate(t *testing.T) {
+	ctx := context.Background()
+	src := NewTestSSMStore(map[string]mockParameter{})
+	dst := NewTestSSMStore(map[string]mockParameter{})
+	require.NoError(t, src.Write(ctx, SecretId{Service: "app", Key: "a"}, "1"))
+	require.NoError(t, src.Write(ctx, SecretId{Service: "app", Key: "b"}, "2"))
+	require.NoError(t, src.Write(ctx, SecretId{Service: "app", Key: "c"}, "3"))
+	require.NoError(t, dst.Write(ctx, SecretId{Service: "app", Key: "b"}, "old"))
+	require.NoError(t, dst.Write(ctx, SecretId{Service: "app", Key: "c"}, "3"))
+	require.NoError(t, dst.Write(ctx, SecretId{Service: "app", Key: "stale"}, "x"))
+
+	t.Run("a dry run only reports drift", func(t *testing.T) {
+		report, err := Replicate(ctx, src, dst, "app", ReplicateOptions{DryRun: true, Prune: true})
+		require.NoError(t, err)
+		assert.Equal(t, ReplicationReport{
+			Service:   "app",
+			Created:   []string{"a"},
+			Updated:   []string{"b"},
+			Extra:     []string{"stale"},
+			Unchanged: []string{"c"},
+		}, report)
+		assert.True(t, report.Drifted())
+
+		_, err = dst.Read(ctx, SecretId{Service: "app", Key: "a"}, -1)
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+
+	t.Run("replicating fixes drift, and is idempotent", func(t *testing.T) {
+		_, err := Replicate(ctx, src, dst, "app", ReplicateOptions{})
+		require.NoError(t, err)
+
+		b, err := dst.Read(ctx, SecretId{Service: "app", Key: "b"}, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "2", *b.Value)
+		assert.Equal(t, 2, b.Meta.Version)
+
+		report, err := Replicate(ctx, src, dst, "app", ReplicateOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, report.Created)
+		assert.Empty(t, report.Updated)
+		assert.Equal(t, []string{"stale"}, report.Extra)
+		assert.Equal(t, []string{"a", "b", "c"}, report.Unchanged)
+
+		// nothing was rewritten
+		b, err = dst.Read(ctx, SecretId{Service: "app", Key: "b"}, -1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, b.Meta.Version)
+	})
+
+	t.Run("pruning deletes secrets only in the destination", func(t *testing.T) {
+		report, err := Replicate(ctx, src, dst, "app", ReplicateOptions{Prune: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"stale"}, report.Extra)
+
+		report, err = Replicate(ctx, src, dst, "app", ReplicateOptions{})
+		require.NoError(t, err)
+		assert.False(t, report.Drifted())
+	})
+
+	t.Run("a missing service is empty", func(t *testing.T) {
+		report, err := Replicate(ctx, src, dst, "nothing", ReplicateOptions{})
+		require.NoError(t, err)
+		assert.False(t, report.Drifted())
+	})
+}
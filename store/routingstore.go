@@ -0,0 +1,320 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Route sends the services under Prefix to Store. A prefix matches a service
+// equal to it, or beginning with it and a slash, so "payments" matches
+// "payments" and "payments/app" but not "payments-old". A trailing "/*" is
+// ignored, so "payments/*" is the same as "payments".
+type Route struct {
+	Prefix string
+	Store  Store
+}
+
+// RoutingStore sends each operation to a store picked by the longest Route
+// prefix matching the secret's service, or to a fallback store if none
+// match. Listing services fans out to every store.
+type RoutingStore struct {
+	routes   []Route
+	fallback Store
+}
+
+var (
+	_ Store           = &RoutingStore{}
+	_ BatchReader     = &RoutingStore{}
+	_ BatchWriter     = &RoutingStore{}
+	_ StreamingLister = &RoutingStore{}
+	_ OptionsWriter   = &RoutingStore{}
+	_ Labeler         = &RoutingStore{}
+	_ Reencrypter     = &RoutingStore{}
+)
+
+// NewRoutingStore returns a store sending services to routes, and services
+// matching none of them to fallback. It returns an error if a prefix is empty
+// or repeated.
+func NewRoutingStore(routes []Route, fallback Store) (*RoutingStore, error) {
+	s := &RoutingStore{fallback: fallback}
+	seen := map[string]bool{}
+	for _, route := range routes {
+		prefix := normalizeRoutePrefix(route.Prefix)
+		if prefix == "" {
+			return nil, fmt.Errorf("invalid route prefix `%s`", route.Prefix)
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("route prefix `%s` is repeated", prefix)
+		}
+		seen[prefix] = true
+		s.routes = append(s.routes, Route{Prefix: prefix, Store: route.Store})
+	}
+	// longest prefix first, so the first match is the best one
+	sort.SliceStable(s.routes, func(i, j int) bool {
+		return len(s.routes[i].Prefix) > len(s.routes[j].Prefix)
+	})
+	return s, nil
+}
+
+func normalizeRoutePrefix(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "*")
+	return strings.Trim(prefix, "/")
+}
+
+// Route returns the store for service, which may have a :label suffix.
+func (s *RoutingStore) Route(service string) Store {
+	if i := s.routeIndex(service); i >= 0 {
+		return s.routes[i].Store
+	}
+	return s.fallback
+}
+
+// routeIndex returns the index of the route for service, or -1 for the
+// fallback
+func (s *RoutingStore) routeIndex(service string) int {
+	service, _, _ = strings.Cut(service, ":")
+	service = strings.Trim(service, "/")
+	for i, route := range s.routes {
+		if service == route.Prefix || strings.HasPrefix(service, route.Prefix+"/") {
+			return i
+		}
+	}
+	return -1
+}
+
+// storeIndex returns the index in s.stores of the store for service
+func (s *RoutingStore) storeIndex(service string) int {
+	if i := s.routeIndex(service); i >= 0 {
+		return i
+	}
+	return len(s.routes)
+}
+
+// stores returns every store, routes first, then the fallback
+func (s *RoutingStore) stores() []Store {
+	stores := make([]Store, 0, len(s.routes)+1)
+	for _, route := range s.routes {
+		stores = append(stores, route.Store)
+	}
+	return append(stores, s.fallback)
+}
+
+// Supports reports whether every store supports c, except for listing
+// services, which is supported if any store supports it.
+func (s *RoutingStore) Supports(c Capability) bool {
+	for _, store := range s.stores() {
+		supported := Supports(store, c)
+		if c == CapabilityListServices && supported {
+			return true
+		}
+		if c != CapabilityListServices && !supported {
+			return false
+		}
+	}
+	return c != CapabilityListServices
+}
+
+// Config returns the configuration of the fallback store.
+func (s *RoutingStore) Config(ctx context.Context) (StoreConfig, error) {
+	return s.fallback.Config(ctx)
+}
+
+// SetConfig sets the configuration of the fallback store.
+func (s *RoutingStore) SetConfig(ctx context.Context, config StoreConfig) error {
+	return s.fallback.SetConfig(ctx, config)
+}
+
+func (s *RoutingStore) Write(ctx context.Context, id SecretId, value string) error {
+	return s.Route(id.Service).Write(ctx, id, value)
+}
+
+func (s *RoutingStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+	return s.Route(id.Service).WriteWithTags(ctx, id, value, tags)
+}
+
+func (s *RoutingStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error {
+	ow, ok := s.Route(id.Service).(OptionsWriter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+	}
+	return ow.WriteWithOptions(ctx, id, value, opts)
+}
+
+// WriteMany writes the secrets of each store together.
+func (s *RoutingStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	stores := s.stores()
+	byStore := make([]map[SecretId]string, len(stores))
+	for id, value := range values {
+		i := s.storeIndex(id.Service)
+		if byStore[i] == nil {
+			byStore[i] = map[SecretId]string{}
+		}
+		byStore[i][id] = value
+	}
+	for i, values := range byStore {
+		if len(values) == 0 {
+			continue
+		}
+		if err := WriteMany(ctx, stores[i], values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RoutingStore) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
+	return s.Route(id.Service).Read(ctx, id, version)
+}
+
+// ReadMany reads the secrets of each store together.
+func (s *RoutingStore) ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error) {
+	stores := s.stores()
+	byStore := make([][]SecretId, len(stores))
+	for _, id := range ids {
+		i := s.storeIndex(id.Service)
+		byStore[i] = append(byStore[i], id)
+	}
+	secrets := make(map[SecretId]Secret, len(ids))
+	for i, ids := range byStore {
+		if len(ids) == 0 {
+			continue
+		}
+		read, err := ReadMany(ctx, stores[i], ids)
+		if err != nil {
+			return nil, err
+		}
+		for id, secret := range read {
+			secrets[id] = secret
+		}
+	}
+	return secrets, nil
+}
+
+func (s *RoutingStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
+	return s.Route(id.Service).WriteTags(ctx, id, tags, deleteOtherTags)
+}
+
+func (s *RoutingStore) ReadTags(ctx context.Context, id SecretId) (map[string]string, error) {
+	return s.Route(id.Service).ReadTags(ctx, id)
+}
+
+func (s *RoutingStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
+	return s.Route(id.Service).DeleteTags(ctx, id, tagKeys)
+}
+
+func (s *RoutingStore) History(ctx context.Context, id SecretId) ([]ChangeEvent, error) {
+	return s.Route(id.Service).History(ctx, id)
+}
+
+func (s *RoutingStore) Delete(ctx context.Context, id SecretId) error {
+	return s.Route(id.Service).Delete(ctx, id)
+}
+
+func (s *RoutingStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
+	return s.Route(service).List(ctx, service, includeValues)
+}
+
+func (s *RoutingStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	return s.Route(service).ListRaw(ctx, service)
+}
+
+func (s *RoutingStore) ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error] {
+	return ListIter(ctx, s.Route(service), service, includeValues)
+}
+
+func (s *RoutingStore) ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error] {
+	return ListRawIter(ctx, s.Route(service), service)
+}
+
+func (s *RoutingStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
+	return collect(s.ListServicesIter(ctx, service, includeSecretName))
+}
+
+// ListServicesIter lists services from every store which supports it, keeping
+// only the names routed to the store they were listed from, so that stores
+// with overlapping contents don't list a service twice.
+func (s *RoutingStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		listed := false
+		seen := map[string]bool{}
+		for i, store := range s.stores() {
+			if !Supports(store, CapabilityListServices) || !s.mayRouteTo(i, service) {
+				continue
+			}
+			listed = true
+			for name, err := range ListServicesIter(ctx, store, service, includeSecretName) {
+				if err != nil {
+					yield("", err)
+					return
+				}
+				nameService := strings.TrimPrefix(name, "/")
+				if includeSecretName {
+					nameService, _, _ = cutLast(nameService, "/")
+				}
+				if s.storeIndex(nameService) != i || seen[name] {
+					continue
+				}
+				seen[name] = true
+				if !yield(name, nil) {
+					return
+				}
+			}
+		}
+		if !listed {
+			yield("", fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityListServices))
+		}
+	}
+}
+
+// mayRouteTo returns whether a service beginning with prefix may be routed to
+// the store at index i of s.stores
+func (s *RoutingStore) mayRouteTo(i int, prefix string) bool {
+	if i == len(s.routes) {
+		return true
+	}
+	route := s.routes[i].Prefix
+	prefix = strings.TrimPrefix(prefix, "/")
+	return strings.HasPrefix(route, prefix) || strings.HasPrefix(prefix, route)
+}
+
+func (s *RoutingStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error {
+	l, ok := s.Route(id.Service).(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.LabelVersion(ctx, id, version, labels)
+}
+
+func (s *RoutingStore) Unlabel(ctx context.Context, id SecretId, labels []string) error {
+	l, ok := s.Route(id.Service).(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.Unlabel(ctx, id, labels)
+}
+
+func (s *RoutingStore) ListLabels(ctx context.Context, id SecretId) (map[int][]string, error) {
+	l, ok := s.Route(id.Service).(Labeler)
+	if !ok {
+		return nil, fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	return l.ListLabels(ctx, id)
+}
+
+func (s *RoutingStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	r, ok := s.Route(id.Service).(Reencrypter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityReencrypt)
+	}
+	return r.Reencrypt(ctx, id, kmsKey)
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
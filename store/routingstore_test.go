@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingStore(t *testing.T) {
+	ctx := context.Background()
+	legacy := NewTestSSMStore(map[string]mockParameter{})
+	payments := NewTestSSMStore(map[string]mockParameter{})
+	fallback := NewTestSSMStore(map[string]mockParameter{})
+	s, err := NewRoutingStore([]Route{
+		{Prefix: "legacy/*", Store: legacy},
+		{Prefix: "payments", Store: payments},
+		{Prefix: "payments/batch", Store: legacy},
+	}, fallback)
+	require.NoError(t, err)
+
+	t.Run("routes by longest prefix", func(t *testing.T) {
+		assert.Same(t, legacy, s.Route("legacy"))
+		assert.Same(t, legacy, s.Route("legacy/app"))
+		assert.Same(t, payments, s.Route("payments/app"))
+		assert.Same(t, payments, s.Route("payments/app:current"))
+		assert.Same(t, legacy, s.Route("payments/batch/nightly"))
+		assert.Same(t, fallback, s.Route("legacy-app"))
+		assert.Same(t, fallback, s.Route("app"))
+	})
+
+	t.Run("writes and reads go to the routed store", func(t *testing.T) {
+		require.NoError(t, s.Write(ctx, SecretId{Service: "legacy/app", Key: "a"}, "1"))
+		require.NoError(t, s.Write(ctx, SecretId{Service: "payments/app", Key: "b"}, "2"))
+		require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "d"}, "0"))
+		require.NoError(t, WriteMany(ctx, s, map[SecretId]string{
+			{Service: "payments/batch/nightly", Key: "c"}: "3",
+			{Service: "app", Key: "d"}:                    "4",
+		}))
+
+		_, err := legacy.Read(ctx, SecretId{Service: "legacy/app", Key: "a"}, -1)
+		assert.NoError(t, err)
+		_, err = legacy.Read(ctx, SecretId{Service: "payments/batch/nightly", Key: "c"}, -1)
+		assert.NoError(t, err)
+		_, err = payments.Read(ctx, SecretId{Service: "payments/app", Key: "b"}, -1)
+		assert.NoError(t, err)
+		d, err := fallback.Read(ctx, SecretId{Service: "app", Key: "d"}, -1)
+		require.NoError(t, err)
+		assert.Equal(t, "4", *d.Value)
+		_, err = fallback.Read(ctx, SecretId{Service: "legacy/app", Key: "a"}, -1)
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+
+		secrets, err := ReadMany(ctx, s, []SecretId{
+			{Service: "legacy/app", Key: "a"},
+			{Service: "payments/app", Key: "b"},
+			{Service: "app", Key: "d"},
+		})
+		require.NoError(t, err)
+		assert.Len(t, secrets, 3)
+		assert.Equal(t, "2", *secrets[SecretId{Service: "payments/app", Key: "b"}].Value)
+
+		list, err := s.List(ctx, "payments/app", true)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "/payments/app/b", list[0].Meta.Key)
+	})
+
+	t.Run("listing services fans out", func(t *testing.T) {
+		// a stray copy in the fallback store isn't listed, since it isn't
+		// routed there
+		require.NoError(t, fallback.Write(ctx, SecretId{Service: "payments/app", Key: "stray"}, "x"))
+
+		services, err := s.ListServices(ctx, "", false)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"legacy/app", "payments/app", "payments/batch/nightly", "app"}, services)
+
+		names, err := s.ListServices(ctx, "payments", true)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"/payments/app/b", "/payments/batch/nightly/c"}, names)
+	})
+
+	t.Run("capabilities", func(t *testing.T) {
+		assert.True(t, s.Supports(CapabilityLabels))
+
+		withNull, err := NewRoutingStore([]Route{{Prefix: "legacy", Store: legacy}}, NewNullStore())
+		require.NoError(t, err)
+		assert.False(t, withNull.Supports(CapabilityLabels))
+		assert.True(t, withNull.Supports(CapabilityListServices))
+	})
+}
+
+func TestNewRoutingStoreErrors(t *testing.T) {
+	_, err := NewRoutingStore([]Route{{Prefix: "/*", Store: NewNullStore()}}, NewNullStore())
+	assert.EqualError(t, err, "invalid route prefix `/*`")
+
+	_, err = NewRoutingStore([]Route{
+		{Prefix: "app", Store: NewNullStore()},
+		{Prefix: "app/*", Store: NewNullStore()},
+	}, NewNullStore())
+	assert.EqualError(t, err, "route prefix `app` is repeated")
+}
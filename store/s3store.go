@@ -60,7 +60,12 @@ func NewS3StoreWithBucket(ctx context.Context, numRetries int, bucket string) (*
 	if err != nil {
 		return nil, err
 	}
+	return NewS3StoreFromConfig(config, bucket), nil
+}
 
+// NewS3StoreFromConfig creates a new S3Store for bucket using an existing AWS
+// config.
+func NewS3StoreFromConfig(config aws.Config, bucket string) *S3Store {
 	svc := s3.NewFromConfig(config)
 
 	stsSvc := sts.NewFromConfig(config)
@@ -69,7 +74,13 @@ func NewS3StoreWithBucket(ctx context.Context, numRetries int, bucket string) (*
 		svc:    svc,
 		stsSvc: stsSvc,
 		bucket: bucket,
-	}, nil
+	}
+}
+
+// Supports reports the optional features of the S3 Store, which only keeps
+// the history of secrets.
+func (s *S3Store) Supports(c Capability) bool {
+	return c == CapabilityHistory
 }
 
 func (s *S3Store) Config(ctx context.Context) (StoreConfig, error) {
@@ -79,21 +90,80 @@ func (s *S3Store) Config(ctx context.Context) (StoreConfig, error) {
 }
 
 func (s *S3Store) SetConfig(ctx context.Context, config StoreConfig) error {
-	return errors.New("Not implemented for S3 Store")
+	return fmt.Errorf("%w for S3 Store", ErrNotImplemented)
 }
 
 func (s *S3Store) Write(ctx context.Context, id SecretId, value string) error {
-	index, err := s.readLatest(ctx, id.Service)
+	return s.writeService(ctx, id.Service, map[string]string{id.Key: value})
+}
+
+// WriteMany writes new versions of many secrets, updating the index of each
+// service once.
+func (s *S3Store) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	services := groupByService(values)
+	for _, service := range sortedKeys(services) {
+		if err := s.writeService(ctx, service, services[service]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) writeService(ctx context.Context, service string, values map[string]string) error {
+	index, err := s.readLatest(ctx, service)
 	if err != nil {
 		return err
 	}
 
-	objPath := getObjectPath(id)
-	existing, ok, err := s.readObjectById(ctx, id)
+	user, err := s.getCurrentUser(ctx)
 	if err != nil {
 		return err
 	}
 
+	written := map[SecretId]int{}
+	for _, key := range sortedKeys(values) {
+		id := SecretId{Service: service, Key: key}
+		objPath := getObjectPath(id)
+		obj, thisVersion, err := s.addVersion(ctx, id, values[key], user)
+		if err != nil {
+			return err
+		}
+
+		contents, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		putObjectInput := &s3.PutObjectInput{
+			Bucket:               aws.String(s.bucket),
+			ServerSideEncryption: types.ServerSideEncryptionAes256,
+			Key:                  aws.String(objPath),
+			Body:                 bytes.NewReader(contents),
+		}
+
+		_, err = s.svc.PutObject(ctx, putObjectInput)
+		if err != nil {
+			return classifyError(err, s3URL(s.bucket, objPath))
+		}
+
+		index.Latest[key] = values[key]
+		written[id] = thisVersion
+	}
+	if err := s.writeLatest(ctx, service, index); err != nil {
+		return err
+	}
+	reportWrittenVersions(ctx, written)
+	return nil
+}
+
+// addVersion reads the object holding a secret, or starts a new one, and adds
+// value to it as the next version
+func (s *S3Store) addVersion(ctx context.Context, id SecretId, value string, user string) (secretObject, int, error) {
+	existing, ok, err := s.readObjectById(ctx, id)
+	if err != nil {
+		return secretObject{}, 0, err
+	}
+
 	var obj secretObject
 	if ok {
 		obj = existing
@@ -106,10 +176,6 @@ func (s *S3Store) Write(ctx context.Context, id SecretId, value string) error {
 	}
 
 	thisVersion := getLatestVersion(obj.Values) + 1
-	user, err := s.getCurrentUser(ctx)
-	if err != nil {
-		return err
-	}
 	obj.Values[thisVersion] = secretVersion{
 		Version:   thisVersion,
 		Value:     value,
@@ -118,31 +184,11 @@ func (s *S3Store) Write(ctx context.Context, id SecretId, value string) error {
 	}
 
 	pruneOldVersions(obj.Values)
-
-	contents, err := json.Marshal(obj)
-	if err != nil {
-		return err
-	}
-
-	putObjectInput := &s3.PutObjectInput{
-		Bucket:               aws.String(s.bucket),
-		ServerSideEncryption: types.ServerSideEncryptionAes256,
-		Key:                  aws.String(objPath),
-		Body:                 bytes.NewReader(contents),
-	}
-
-	_, err = s.svc.PutObject(ctx, putObjectInput)
-	if err != nil {
-		// TODO: catch specific awserr
-		return err
-	}
-
-	index.Latest[id.Key] = value
-	return s.writeLatest(ctx, id.Service, index)
+	return obj, thisVersion, nil
 }
 
 func (s *S3Store) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
-	return errors.New("Not implemented for S3 Store")
+	return fmt.Errorf("%w for S3 Store", ErrNotImplemented)
 }
 
 func (s *S3Store) Read(ctx context.Context, id SecretId, version int) (Secret, error) {
@@ -175,15 +221,15 @@ func (s *S3Store) Read(ctx context.Context, id SecretId, version int) (Secret, e
 }
 
 func (s *S3Store) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
-	return errors.New("Not implemented for S3 Store")
+	return fmt.Errorf("%w for S3 Store", ErrNotImplemented)
 }
 
 func (s *S3Store) ReadTags(ctx context.Context, id SecretId) (map[string]string, error) {
-	return nil, errors.New("Not implemented for S3 Store")
+	return nil, fmt.Errorf("%w for S3 Store", ErrNotImplemented)
 }
 
 func (s *S3Store) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
-	return nil, fmt.Errorf("S3 Backend is experimental and does not implement this command")
+	return nil, fmt.Errorf("%w: S3 Backend is experimental and does not implement this command", ErrNotImplemented)
 }
 
 func (s *S3Store) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
@@ -290,7 +336,7 @@ func (s *S3Store) Delete(ctx context.Context, id SecretId) error {
 }
 
 func (s *S3Store) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
-	return errors.New("Not implemented for S3 Store")
+	return fmt.Errorf("%w for S3 Store", ErrNotImplemented)
 }
 
 // getCurrentUser uses the STS API to get the current caller identity,
@@ -299,7 +345,7 @@ func (s *S3Store) DeleteTags(ctx context.Context, id SecretId, tagKeys []string)
 func (s *S3Store) getCurrentUser(ctx context.Context) (string, error) {
 	resp, err := s.stsSvc.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return "", err
+		return "", classifyError(err, "sts:GetCallerIdentity")
 	}
 
 	return *resp.Arn, nil
@@ -317,7 +363,7 @@ func (s *S3Store) deleteObject(ctx context.Context, path string) error {
 	}
 
 	_, err := s.svc.DeleteObject(ctx, deleteObjectInput)
-	return err
+	return classifyError(err, s3URL(s.bucket, path))
 }
 
 func (s *S3Store) readObject(ctx context.Context, path string) (secretObject, bool, error) {
@@ -338,7 +384,7 @@ func (s *S3Store) readObject(ctx context.Context, path string) (secretObject, bo
 			return secretObject{}, false, nil
 		}
 		// generic errors
-		return secretObject{}, false, err
+		return secretObject{}, false, classifyError(err, s3URL(s.bucket, path))
 	}
 
 	raw, err := io.ReadAll(resp.Body)
@@ -355,6 +401,11 @@ func (s *S3Store) readObject(ctx context.Context, path string) (secretObject, bo
 
 }
 
+// s3URL is the resource path of an object, for errors
+func s3URL(bucket, key string) string {
+	return fmt.Sprintf("s3://%s/%s", bucket, key)
+}
+
 func (s *S3Store) readObjectById(ctx context.Context, id SecretId) (secretObject, bool, error) {
 	path := getObjectPath(id)
 	return s.readObject(ctx, path)
@@ -369,7 +420,7 @@ func (s *S3Store) puts3raw(ctx context.Context, path string, contents []byte) er
 	}
 
 	_, err := s.svc.PutObject(ctx, putObjectInput)
-	return err
+	return classifyError(err, s3URL(s.bucket, path))
 }
 
 func (s *S3Store) readLatest(ctx context.Context, service string) (latest, error) {
@@ -387,7 +438,7 @@ func (s *S3Store) readLatest(ctx context.Context, service string) (latest, error
 			// Index doesn't exist yet, return an empty index
 			return latest{Latest: map[string]string{}}, nil
 		}
-		return latest{}, err
+		return latest{}, classifyError(err, s3URL(s.bucket, path))
 	}
 
 	raw, err := io.ReadAll(resp.Body)
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -32,6 +31,7 @@ type LatestValue struct {
 }
 
 var _ Store = &S3KMSStore{}
+var _ Reencrypter = &S3KMSStore{}
 
 type S3KMSStore struct {
 	S3Store
@@ -46,7 +46,12 @@ func NewS3KMSStore(ctx context.Context, numRetries int, bucket string, kmsKeyAli
 	if err != nil {
 		return nil, err
 	}
+	return NewS3KMSStoreFromConfig(config, bucket, kmsKeyAlias), nil
+}
 
+// NewS3KMSStoreFromConfig creates a new S3KMSStore for bucket using an existing
+// AWS config. An empty kmsKeyAlias means DefaultKeyID.
+func NewS3KMSStoreFromConfig(config aws.Config, bucket string, kmsKeyAlias string) *S3KMSStore {
 	svc := s3.NewFromConfig(config)
 
 	stsSvc := sts.NewFromConfig(config)
@@ -67,83 +72,96 @@ func NewS3KMSStore(ctx context.Context, numRetries int, bucket string, kmsKeyAli
 		stsSvc:      stsSvc,
 		bucket:      bucket,
 		kmsKeyAlias: kmsKeyAlias,
-	}, nil
+	}
+}
+
+// Supports reports that the S3 KMS Store supports history and moving secrets
+// between KMS keys.
+func (s *S3KMSStore) Supports(c Capability) bool {
+	return c == CapabilityHistory || c == CapabilityReencrypt
 }
 
 func (s *S3KMSStore) Write(ctx context.Context, id SecretId, value string) error {
-	index, err := s.readLatest(ctx, id.Service)
-	if err != nil {
-		return err
-	}
+	return s.writeService(ctx, id.Service, map[string]string{id.Key: value})
+}
 
-	if val, ok := index.Latest[id.Key]; val.KMSAlias != s.kmsKeyAlias && ok {
-		return fmt.Errorf("Unable to overwrite secret %s using new KMS key %s; mismatch with existing key %s", id.Key, s.kmsKeyAlias, val.KMSAlias)
+// WriteMany writes new versions of many secrets, updating the index of each
+// service once.
+func (s *S3KMSStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	services := groupByService(values)
+	for _, service := range sortedKeys(services) {
+		if err := s.writeService(ctx, service, services[service]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	objPath := getObjectPath(id)
-	existing, ok, err := s.readObjectById(ctx, id)
+func (s *S3KMSStore) writeService(ctx context.Context, service string, values map[string]string) error {
+	index, err := s.readLatest(ctx, service)
 	if err != nil {
 		return err
 	}
 
-	var obj secretObject
-	if ok {
-		obj = existing
-	} else {
-		obj = secretObject{
-			Service: id.Service,
-			Key:     fmt.Sprintf("/%s/%s", id.Service, id.Key),
-			Values:  map[int]secretVersion{},
+	keys := sortedKeys(values)
+	for _, key := range keys {
+		if val, ok := index.Latest[key]; val.KMSAlias != s.kmsKeyAlias && ok {
+			return fmt.Errorf("Unable to overwrite secret %s using new KMS key %s; mismatch with existing key %s", key, s.kmsKeyAlias, val.KMSAlias)
 		}
 	}
 
-	thisVersion := getLatestVersion(obj.Values) + 1
 	user, err := s.getCurrentUser(ctx)
 	if err != nil {
 		return err
 	}
-	obj.Values[thisVersion] = secretVersion{
-		Version:   thisVersion,
-		Value:     value,
-		Created:   time.Now().UTC(),
-		CreatedBy: user,
-	}
 
-	pruneOldVersions(obj.Values)
+	written := map[SecretId]int{}
+	for _, key := range keys {
+		id := SecretId{Service: service, Key: key}
+		objPath := getObjectPath(id)
+		obj, thisVersion, err := s.addVersion(ctx, id, values[key], user)
+		if err != nil {
+			return err
+		}
 
-	contents, err := json.Marshal(obj)
-	if err != nil {
-		return err
-	}
+		contents, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
 
-	putObjectInput := &s3.PutObjectInput{
-		Bucket:               aws.String(s.bucket),
-		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
-		SSEKMSKeyId:          aws.String(s.kmsKeyAlias),
-		Key:                  aws.String(objPath),
-		Body:                 bytes.NewReader(contents),
-	}
+		putObjectInput := &s3.PutObjectInput{
+			Bucket:               aws.String(s.bucket),
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          aws.String(s.kmsKeyAlias),
+			Key:                  aws.String(objPath),
+			Body:                 bytes.NewReader(contents),
+		}
 
-	_, err = s.svc.PutObject(ctx, putObjectInput)
-	if err != nil {
-		// TODO: catch specific awserr
-		return err
-	}
+		_, err = s.svc.PutObject(ctx, putObjectInput)
+		if err != nil {
+			return classifyError(err, s3URL(s.bucket, objPath))
+		}
 
-	index.Latest[id.Key] = LatestValue{
-		Version:  thisVersion,
-		Value:    value,
-		KMSAlias: s.kmsKeyAlias,
+		index.Latest[key] = LatestValue{
+			Version:  thisVersion,
+			Value:    values[key],
+			KMSAlias: s.kmsKeyAlias,
+		}
+		written[id] = thisVersion
 	}
-	return s.writeLatest(ctx, id.Service, index)
+	if err := s.writeLatest(ctx, service, index); err != nil {
+		return err
+	}
+	reportWrittenVersions(ctx, written)
+	return nil
 }
 
 func (s *S3KMSStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
-	return errors.New("Not implemented for S3 KMS Store")
+	return fmt.Errorf("%w for S3 KMS Store", ErrNotImplemented)
 }
 
 func (s *S3KMSStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
-	return nil, fmt.Errorf("S3KMS Backend is experimental and does not implement this command")
+	return nil, fmt.Errorf("%w: S3KMS Backend is experimental and does not implement this command", ErrNotImplemented)
 }
 
 func (s *S3KMSStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
@@ -174,6 +192,7 @@ func (s *S3KMSStore) List(ctx context.Context, service string, includeValues boo
 				CreatedBy: val.CreatedBy,
 				Version:   val.Version,
 				Key:       obj.Key,
+				KMSKey:    index.Latest[key].KMSAlias,
 			},
 		}
 
@@ -228,17 +247,89 @@ func (s *S3KMSStore) Delete(ctx context.Context, id SecretId) error {
 	return s.writeLatest(ctx, id.Service, index)
 }
 
+// Reencrypt rewrites a secret, with every version, under kmsKey, and moves
+// it from the index file of its old key to the one for kmsKey.
+func (s *S3KMSStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	kmsKey = NormalizeKMSKey(kmsKey)
+	index, err := s.readLatest(ctx, id.Service)
+	if err != nil {
+		return err
+	}
+	latest, ok := index.Latest[id.Key]
+	if !ok {
+		return ErrSecretNotFound
+	}
+	oldKey := latest.KMSAlias
+	if oldKey == kmsKey {
+		return nil
+	}
+
+	obj, ok, err := s.readObjectById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSecretNotFound
+	}
+	contents, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if err := s.puts3rawWithKey(ctx, getObjectPath(id), contents, kmsKey); err != nil {
+		return err
+	}
+
+	// add the secret to the new index before removing it from the old one,
+	// so that it's always listed
+	if err := s.updateLatestFile(ctx, id.Service, kmsKey, func(latestFile LatestIndexFile) {
+		latest.KMSAlias = kmsKey
+		latestFile.Latest[id.Key] = latest
+	}); err != nil {
+		return err
+	}
+	if err := s.updateLatestFile(ctx, id.Service, oldKey, func(latestFile LatestIndexFile) {
+		delete(latestFile.Latest, id.Key)
+	}); err != nil {
+		return err
+	}
+	reportWrittenVersion(ctx, id, latest.Version)
+	return nil
+}
+
+// updateLatestFile changes the index file of a service for one KMS key
+func (s *S3KMSStore) updateLatestFile(ctx context.Context, service string, kmsKey string, update func(LatestIndexFile)) error {
+	path := fmt.Sprintf("%s/%s", service, latestFileKeyName(kmsKey))
+	latestFile, err := s.readLatestFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	if latestFile.Latest == nil {
+		latestFile.Latest = map[string]LatestValue{}
+	}
+	update(latestFile)
+
+	raw, err := json.Marshal(latestFile)
+	if err != nil {
+		return err
+	}
+	return s.puts3rawWithKey(ctx, path, raw, kmsKey)
+}
+
 func (s *S3KMSStore) puts3raw(ctx context.Context, path string, contents []byte) error {
+	return s.puts3rawWithKey(ctx, path, contents, s.kmsKeyAlias)
+}
+
+func (s *S3KMSStore) puts3rawWithKey(ctx context.Context, path string, contents []byte, kmsKey string) error {
 	putObjectInput := &s3.PutObjectInput{
 		Bucket:               aws.String(s.bucket),
 		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
-		SSEKMSKeyId:          aws.String(s.kmsKeyAlias),
+		SSEKMSKeyId:          aws.String(kmsKey),
 		Key:                  aws.String(path),
 		Body:                 bytes.NewReader(contents),
 	}
 
 	_, err := s.svc.PutObject(ctx, putObjectInput)
-	return err
+	return classifyError(err, s3URL(s.bucket, path))
 }
 
 func (s *S3KMSStore) readLatestFile(ctx context.Context, path string) (LatestIndexFile, error) {
@@ -263,7 +354,7 @@ func (s *S3KMSStore) readLatestFile(ctx context.Context, path string) (LatestInd
 				return LatestIndexFile{Latest: map[string]LatestValue{}}, nil
 			}
 		}
-		return LatestIndexFile{}, err
+		return LatestIndexFile{}, classifyError(err, s3URL(s.bucket, path))
 	}
 
 	raw, err := io.ReadAll(resp.Body)
@@ -294,7 +385,7 @@ func (s *S3KMSStore) readLatest(ctx context.Context, service string) (LatestInde
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return latestResult, err
+			return latestResult, classifyError(err, s3URL(s.bucket, *params.Prefix))
 		}
 
 		for index := range page.Contents {
@@ -330,7 +421,11 @@ func (s *S3KMSStore) readLatest(ctx context.Context, service string) (LatestInde
 }
 
 func (s *S3KMSStore) latestFileKeyNameByKMSKey() string {
-	return fmt.Sprintf("__kms_%s__latest.json", strings.Replace(s.kmsKeyAlias, "/", "_", -1))
+	return latestFileKeyName(s.kmsKeyAlias)
+}
+
+func latestFileKeyName(kmsKeyAlias string) string {
+	return fmt.Sprintf("__kms_%s__latest.json", strings.Replace(kmsKeyAlias, "/", "_", -1))
 }
 
 func (s *S3KMSStore) writeLatest(ctx context.Context, service string, index LatestIndexFile) error {
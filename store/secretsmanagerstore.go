@@ -92,6 +92,12 @@ func NewSecretsManagerStore(ctx context.Context, numRetries int) (*SecretsManage
 	if err != nil {
 		return nil, err
 	}
+	return NewSecretsManagerStoreFromConfig(cfg), nil
+}
+
+// NewSecretsManagerStoreFromConfig creates a new SecretsManagerStore using an
+// existing AWS config.
+func NewSecretsManagerStoreFromConfig(cfg aws.Config) *SecretsManagerStore {
 	customSecretsManagerEndpoint, ok := os.LookupEnv(CustomSecretsManagerEndpointEnvVar)
 	if ok {
 		cfg.BaseEndpoint = aws.String(customSecretsManagerEndpoint)
@@ -111,7 +117,13 @@ func NewSecretsManagerStore(ctx context.Context, numRetries int) (*SecretsManage
 		svc:    svc,
 		stsSvc: stsSvc,
 		config: cfg,
-	}, nil
+	}
+}
+
+// Supports reports the optional features of the Secrets Manager Store, which
+// only keeps the history of secrets.
+func (s *SecretsManagerStore) Supports(c Capability) bool {
+	return c == CapabilityHistory
 }
 
 func (s *SecretsManagerStore) Config(ctx context.Context) (StoreConfig, error) {
@@ -121,17 +133,41 @@ func (s *SecretsManagerStore) Config(ctx context.Context) (StoreConfig, error) {
 }
 
 func (s *SecretsManagerStore) SetConfig(ctx context.Context, config StoreConfig) error {
-	return errors.New("Not implemented for Secrets Manager Store")
+	return fmt.Errorf("%w for Secrets Manager Store", ErrNotImplemented)
 }
 
 // Write writes a given value to a secret identified by id. If the secret
 // already exists, then write a new version.
 func (s *SecretsManagerStore) Write(ctx context.Context, id SecretId, value string) error {
-	version := 1
-	// first read to get the current version
-	latest, err := s.readLatest(ctx, id.Service)
+	return s.writeService(ctx, id.Service, map[string]string{id.Key: value})
+}
+
+// WriteMany writes new versions of many secrets, with a single
+// PutSecretValue for each service.
+func (s *SecretsManagerStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	services := groupByService(values)
+	for _, service := range sortedKeys(services) {
+		if err := s.writeService(ctx, service, services[service]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeService applies values, keyed by secret key, to the secret holding a
+// service in a single write. An empty value deletes the key.
+func (s *SecretsManagerStore) writeService(ctx context.Context, service string, values map[string]string) error {
+	// first read to get the current versions
+	latest, err := s.readLatest(ctx, service)
 	mustCreate := false
-	deleteKeyFromSecret := len(value) == 0
+	deleteKeyFromSecret, writeKeyToSecret := false, false
+	for _, value := range values {
+		if len(value) == 0 {
+			deleteKeyFromSecret = true
+		} else {
+			writeKeyToSecret = true
+		}
+	}
 
 	// Failure to readLatest may be a true error or an expected error.
 	// We expect that when we write a secret that it may not already exist:
@@ -152,53 +188,55 @@ func (s *SecretsManagerStore) Write(ctx context.Context, id SecretId, value stri
 			}
 		}
 	}
+	if latest == nil {
+		latest = secretValueObject{}
+	}
 
-	if deleteKeyFromSecret {
-		if _, ok := latest[id.Key]; ok {
-			delete(latest, id.Key)
-		} else {
-			return ErrSecretNotFound
-		}
-		metadata, err := getHydratedMetadata(&latest)
-		if err != nil {
-			return err
-		}
-		delete(metadata, id.Key)
+	metadata, err := getHydratedMetadata(&latest)
+	if err != nil {
+		return err
+	}
 
-		rawMetadata, err := dehydrateMetadata(&metadata)
-		if err != nil {
-			return err
-		}
-		latest[metadataKey] = rawMetadata
-	} else {
-		user, err := s.getCurrentUser(ctx)
-		if err != nil {
+	var user string
+	if writeKeyToSecret {
+		if user, err = s.getCurrentUser(ctx); err != nil {
 			return err
 		}
+	}
 
-		metadata, err := getHydratedMetadata(&latest)
-		if err != nil {
-			return err
+	stageVersion := 1
+	written := map[SecretId]int{}
+	for _, key := range sortedKeys(values) {
+		value := values[key]
+		if len(value) == 0 {
+			if _, ok := latest[key]; !ok {
+				return ErrSecretNotFound
+			}
+			delete(latest, key)
+			delete(metadata, key)
+			continue
 		}
 
-		if keyMetadata, ok := metadata[id.Key]; ok {
+		version := 1
+		if keyMetadata, ok := metadata[key]; ok {
 			version = keyMetadata.Version + 1
 		}
+		stageVersion = max(stageVersion, version)
+		written[SecretId{Service: service, Key: key}] = version
 
-		metadata[id.Key] = secretMetadata{
+		metadata[key] = secretMetadata{
 			Version:   version,
 			Created:   time.Now().UTC(),
 			CreatedBy: user,
 		}
+		latest[key] = value
+	}
 
-		rawMetadata, err := dehydrateMetadata(&metadata)
-		if err != nil {
-			return err
-		}
-
-		latest[id.Key] = value
-		latest[metadataKey] = rawMetadata
+	rawMetadata, err := dehydrateMetadata(&metadata)
+	if err != nil {
+		return err
 	}
+	latest[metadataKey] = rawMetadata
 
 	contents, err := json.Marshal(latest)
 	if err != nil {
@@ -207,43 +245,44 @@ func (s *SecretsManagerStore) Write(ctx context.Context, id SecretId, value stri
 
 	if mustCreate {
 		createSecretValueInput := &secretsmanager.CreateSecretInput{
-			Name:         aws.String(id.Service),
+			Name:         aws.String(service),
 			SecretString: aws.String(string(contents)),
 		}
 		_, err = s.svc.CreateSecret(ctx, createSecretValueInput)
 		if err != nil {
-			return err
+			return classifyError(err, service)
 		}
 	} else {
 		// Check that rotation is not enabled. We refuse to write to secrets with
 		// rotation enabled.
 		describeSecretInput := &secretsmanager.DescribeSecretInput{
-			SecretId: aws.String(id.Service),
+			SecretId: aws.String(service),
 		}
 		details, err := s.svc.DescribeSecret(ctx, describeSecretInput)
 		if err != nil {
-			return err
+			return classifyError(err, service)
 		}
 		if details.RotationEnabled != nil && *details.RotationEnabled {
 			return fmt.Errorf("Cannot write to a secret with rotation enabled")
 		}
 
 		putSecretValueInput := &secretsmanager.PutSecretValueInput{
-			SecretId:      aws.String(id.Service),
+			SecretId:      aws.String(service),
 			SecretString:  aws.String(string(contents)),
-			VersionStages: []string{"AWSCURRENT", "CHAMBER" + fmt.Sprint(version)},
+			VersionStages: []string{"AWSCURRENT", "CHAMBER" + fmt.Sprint(stageVersion)},
 		}
 		_, err = s.svc.PutSecretValue(ctx, putSecretValueInput)
 		if err != nil {
-			return err
+			return classifyError(err, service)
 		}
 	}
 
+	reportWrittenVersions(ctx, written)
 	return nil
 }
 
 func (s *SecretsManagerStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
-	return errors.New("tags on write not implemented for Secrets Manager Store")
+	return fmt.Errorf("tags on write %w for Secrets Manager Store", ErrNotImplemented)
 }
 
 // Read reads a secret at a specific version.
@@ -286,7 +325,7 @@ func (s *SecretsManagerStore) Delete(ctx context.Context, id SecretId) error {
 }
 
 func (s *SecretsManagerStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
-	return errors.New("Not implemented for Secrets Manager Store")
+	return fmt.Errorf("%w for Secrets Manager Store", ErrNotImplemented)
 }
 
 func (s *SecretsManagerStore) readVersion(ctx context.Context, id SecretId, version int) (Secret, error) {
@@ -298,7 +337,7 @@ func (s *SecretsManagerStore) readVersion(ctx context.Context, id SecretId, vers
 	var result Secret
 	resp, err := s.svc.ListSecretVersionIds(ctx, listSecretVersionIdsInput)
 	if err != nil {
-		return Secret{}, err
+		return Secret{}, classifyError(err, id.Service)
 	}
 
 	for _, history := range resp.Versions {
@@ -313,7 +352,7 @@ func (s *SecretsManagerStore) readVersion(ctx context.Context, id SecretId, vers
 		resp, err := s.svc.GetSecretValue(ctx, getSecretValueInput)
 
 		if err != nil {
-			return Secret{}, err
+			return Secret{}, classifyError(err, id.Service)
 		}
 
 		if len(*resp.SecretString) == 0 {
@@ -365,7 +404,7 @@ func (s *SecretsManagerStore) readLatest(ctx context.Context, service string) (s
 	resp, err := s.svc.GetSecretValue(ctx, getSecretValueInput)
 
 	if err != nil {
-		return secretValueObject{}, err
+		return secretValueObject{}, classifyError(err, service)
 	}
 
 	if len(*resp.SecretString) == 0 {
@@ -381,16 +420,16 @@ func (s *SecretsManagerStore) readLatest(ctx context.Context, service string) (s
 }
 
 func (s *SecretsManagerStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) error {
-	return errors.New("Not implemented for Secrets Manager Store")
+	return fmt.Errorf("%w for Secrets Manager Store", ErrNotImplemented)
 }
 
 func (s *SecretsManagerStore) ReadTags(ctx context.Context, id SecretId) (map[string]string, error) {
-	return nil, errors.New("Not implemented for Secrets Manager Store")
+	return nil, fmt.Errorf("%w for Secrets Manager Store", ErrNotImplemented)
 }
 
 // ListServices (not implemented)
 func (s *SecretsManagerStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
-	return nil, fmt.Errorf("Secrets Manager Backend is experimental and does not implement this command")
+	return nil, fmt.Errorf("%w: Secrets Manager Backend is experimental and does not implement this command", ErrNotImplemented)
 }
 
 // List lists all secrets for a given service.  If includeValues is true,
@@ -471,7 +510,7 @@ func (s *SecretsManagerStore) History(ctx context.Context, id SecretId) ([]Chang
 
 	resp, err := s.svc.ListSecretVersionIds(ctx, listSecretVersionIdsInput)
 	if err != nil {
-		return events, err
+		return events, classifyError(err, id.Service)
 	}
 
 	// m is a temporary map to allow us to (1) deduplicate ChangeEvents, since
@@ -489,7 +528,7 @@ func (s *SecretsManagerStore) History(ctx context.Context, id SecretId) ([]Chang
 		resp, err := s.svc.GetSecretValue(ctx, getSecretValueInput)
 
 		if err != nil {
-			return events, err
+			return events, classifyError(err, id.Service)
 		}
 
 		if len(*resp.SecretString) == 0 {
@@ -542,7 +581,7 @@ func (s *SecretsManagerStore) History(ctx context.Context, id SecretId) ([]Chang
 func (s *SecretsManagerStore) getCurrentUser(ctx context.Context) (string, error) {
 	resp, err := s.stsSvc.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return "", err
+		return "", classifyError(err, "sts:GetCallerIdentity")
 	}
 
 	return *resp.Arn, nil
@@ -6,16 +6,28 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 const (
 	RegionEnvVar = "CHAMBER_AWS_REGION"
 )
 
+// NewConfig loads the AWS configuration used by chamber's stores. A non-empty
+// region takes precedence over $CHAMBER_AWS_REGION and the SDK's own lookup.
+func NewConfig(ctx context.Context, region string, numRetries int, retryMode aws.RetryMode) (aws.Config, error) {
+	cfg, _, err := getConfigWithRegion(ctx, region, numRetries, retryMode)
+	return cfg, err
+}
+
 func getConfig(ctx context.Context, numRetries int, retryMode aws.RetryMode) (aws.Config, string, error) {
-	var region string
-	if regionOverride, ok := os.LookupEnv(RegionEnvVar); ok {
+	return getConfigWithRegion(ctx, "", numRetries, retryMode)
+}
+
+func getConfigWithRegion(ctx context.Context, region string, numRetries int, retryMode aws.RetryMode) (aws.Config, string, error) {
+	if regionOverride, ok := os.LookupEnv(RegionEnvVar); ok && region == "" {
 		region = regionOverride
 	}
 
@@ -40,9 +52,22 @@ func getConfig(ctx context.Context, numRetries int, retryMode aws.RetryMode) (aw
 		}
 	}
 
+	// record AWS API calls when tracing is on; see TracingStore
+	cfg.APIOptions = append(cfg.APIOptions, addTracingMiddleware)
+
 	return cfg, region, err
 }
 
+// withRole returns cfg with the credentials of roleARN, assumed with cfg's own
+// credentials. They're cached, and assumed again before they expire.
+func withRole(cfg aws.Config, roleARN string) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "chamber"
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg
+}
+
 func uniqueStringSlice(slice []string) []string {
 	unique := make(map[string]struct{}, len(slice))
 	j := 0
@@ -26,3 +26,20 @@ func TestGetConfig(t *testing.T) {
 	assert.Equal(t, 3, config.RetryMaxAttempts)
 	assert.Equal(t, aws.RetryModeStandard, config.RetryMode)
 }
+
+func TestNewConfigRegion(t *testing.T) {
+	originalRegion, ok := os.LookupEnv(RegionEnvVar)
+	os.Setenv(RegionEnvVar, "us-west-2")
+	if ok {
+		defer os.Setenv(RegionEnvVar, originalRegion)
+	} else {
+		defer os.Unsetenv(RegionEnvVar)
+	}
+
+	config, err := NewConfig(context.Background(), "eu-west-1", 2, aws.RetryModeAdaptive)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", config.Region)
+	assert.Equal(t, 2, config.RetryMaxAttempts)
+	assert.Equal(t, aws.RetryModeAdaptive, config.RetryMode)
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Values too large for an SSM parameter are gzipped and base64 encoded, and
+// stored in the parameter with compressedPrefix if that's small enough.
+// Otherwise the encoded value is split across <name>/__chunk_<i> parameters,
+// and the parameter itself holds a manifest, with chunkedPrefix, listing the
+// SSM version of each chunk. The manifest is written last, so readers see
+// either the old value or the new one, and old manifests in the history still
+// point at the chunk versions they were written with. Values which happen to
+// start with either prefix are always compressed, so they're never mistaken
+// for an encoded value when read.
+const (
+	compressedPrefix = "chamber:gzip:"
+	chunkedPrefix    = "chamber:chunked:"
+	chunkPrefix      = "__chunk_"
+)
+
+// chunkManifest is the JSON form of a chunked value's manifest
+type chunkManifest struct {
+	// Chunks are the SSM versions of each chunk, in order.
+	Chunks []int64 `json:"chunks"`
+}
+
+// maxValueSize is the largest value SSM accepts for a tier
+func maxValueSize(tier types.ParameterTier) int {
+	if tier == types.ParameterTierAdvanced {
+		return 8192
+	}
+	return 4096
+}
+
+// isChunkName reports whether name is a chunk of a larger parameter
+func isChunkName(name string) bool {
+	return strings.HasPrefix(name[strings.LastIndex(name, "/")+1:], chunkPrefix)
+}
+
+func chunkName(name string, i int) string {
+	return fmt.Sprintf("%s/%s%d", name, chunkPrefix, i)
+}
+
+// hasEncodedPrefix reports whether value starts like an encoded value
+func hasEncodedPrefix(value string) bool {
+	return strings.HasPrefix(value, compressedPrefix) || strings.HasPrefix(value, chunkedPrefix)
+}
+
+// encodeValue returns the value to store in the parameter named by input,
+// writing chunks first if the value needs them. input is otherwise ready to
+// put.
+func (s *SSMStore) encodeValue(ctx context.Context, input *ssm.PutParameterInput) error {
+	value := aws.ToString(input.Value)
+	maxSize := maxValueSize(input.Tier)
+	if len(value) <= maxSize && !hasEncodedPrefix(value) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(value)); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(compressedPrefix)+len(encoded) <= maxSize {
+		input.Value = aws.String(compressedPrefix + encoded)
+		return nil
+	}
+
+	name := aws.ToString(input.Name)
+	var manifest chunkManifest
+	for i := 0; len(encoded) > 0; i++ {
+		chunk := encoded[:min(maxSize, len(encoded))]
+		encoded = encoded[len(chunk):]
+
+		putParameterInput := &ssm.PutParameterInput{
+			KeyId:       input.KeyId,
+			Name:        aws.String(chunkName(name, i)),
+			Type:        input.Type,
+			Value:       aws.String(chunk),
+			Overwrite:   aws.Bool(true),
+			Description: aws.String(fmt.Sprintf("chunk %d of %s", i, name)),
+			Tier:        input.Tier,
+		}
+		resp, err := s.svc.PutParameter(ctx, putParameterInput)
+		if err != nil {
+			return classifyError(err, chunkName(name, i))
+		}
+		manifest.Chunks = append(manifest.Chunks, resp.Version)
+	}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	input.Value = aws.String(chunkedPrefix + string(b))
+	return nil
+}
+
+// decodeValue returns the value stored in the parameter called name, which
+// may be compressed, or a manifest of chunks
+func (s *SSMStore) decodeValue(ctx context.Context, name string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var encoded string
+	switch {
+	case strings.HasPrefix(*value, compressedPrefix):
+		encoded = strings.TrimPrefix(*value, compressedPrefix)
+	case strings.HasPrefix(*value, chunkedPrefix):
+		var manifest chunkManifest
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(*value, chunkedPrefix)), &manifest); err != nil {
+			return nil, fmt.Errorf("invalid chunk manifest for %s: %w", name, err)
+		}
+		var err error
+		if encoded, err = s.readChunks(ctx, name, manifest); err != nil {
+			return nil, err
+		}
+	default:
+		return value, nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed value for %s: %w", name, err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed value for %s: %w", name, err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed value for %s: %w", name, err)
+	}
+	return aws.String(string(decoded)), nil
+}
+
+// readChunks reads the versions of the chunks in manifest, 10 at a time, and
+// joins them together
+func (s *SSMStore) readChunks(ctx context.Context, name string, manifest chunkManifest) (string, error) {
+	selectors := make([]string, len(manifest.Chunks))
+	for i, version := range manifest.Chunks {
+		selectors[i] = chunkName(name, i) + ":" + strconv.FormatInt(version, 10)
+	}
+
+	chunks := make(map[string]string, len(selectors))
+	for i := 0; i < len(selectors); i += 10 {
+		getParametersInput := &ssm.GetParametersInput{
+			Names:          selectors[i:min(i+10, len(selectors))],
+			WithDecryption: aws.Bool(true),
+		}
+		resp, err := s.svc.GetParameters(ctx, getParametersInput)
+		if err != nil {
+			return "", classifyError(err, name)
+		}
+		for _, param := range resp.Parameters {
+			chunks[aws.ToString(param.Name)+aws.ToString(param.Selector)] = aws.ToString(param.Value)
+		}
+	}
+
+	var joined strings.Builder
+	for _, selector := range selectors {
+		chunk, ok := chunks[selector]
+		if !ok {
+			return "", fmt.Errorf("missing chunk %s of %s: %w", selector, name, ErrSecretNotFound)
+		}
+		joined.WriteString(chunk)
+	}
+	return joined.String(), nil
+}
+
+// deleteChunks deletes every chunk of the parameter called name
+func (s *SSMStore) deleteChunks(ctx context.Context, name string) error {
+	describeParametersInput := &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{
+			{
+				Key:    aws.String("Name"),
+				Option: aws.String("BeginsWith"),
+				Values: []string{name + "/" + chunkPrefix},
+			},
+		},
+	}
+
+	var chunks []string
+	paginator := ssm.NewDescribeParametersPaginator(s.svc, describeParametersInput)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return classifyError(err, name)
+		}
+		for _, meta := range resp.Parameters {
+			chunks = append(chunks, *meta.Name)
+		}
+	}
+
+	for _, chunk := range chunks {
+		deleteParameterInput := &ssm.DeleteParameterInput{
+			Name: aws.String(chunk),
+		}
+		if _, err := s.svc.DeleteParameter(ctx, deleteParameterInput); err != nil {
+			return classifyError(err, chunk)
+		}
+	}
+	return nil
+}
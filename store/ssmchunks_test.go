@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomValue(t *testing.T, n int) string {
+	b := make([]byte, n/2)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return hex.EncodeToString(b)
+}
+
+func TestSSMChunking(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("small values are stored as they are", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s := NewTestSSMStore(parameters)
+		id := SecretId{Service: "app", Key: "small"}
+		require.NoError(t, s.Write(ctx, id, "value"))
+		assert.Equal(t, "value", *parameters["/app/small"].currentParam.Value)
+	})
+
+	t.Run("values starting with a prefix are compressed", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s := NewTestSSMStore(parameters)
+		for i, value := range []string{compressedPrefix + "value", chunkedPrefix + `{"chunks":[1]}`, compressedPrefix} {
+			id := SecretId{Service: "app", Key: fmt.Sprintf("key%d", i)}
+			require.NoError(t, s.Write(ctx, id, value))
+			assert.NotEqual(t, value, *parameters["/app/"+id.Key].currentParam.Value)
+
+			secret, err := s.Read(ctx, id, -1)
+			require.NoError(t, err)
+			assert.Equal(t, value, *secret.Value)
+		}
+	})
+
+	t.Run("compressible values are compressed", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s := NewTestSSMStore(parameters)
+		id := SecretId{Service: "app", Key: "big"}
+		value := strings.Repeat("abcdefgh", 2000)
+		require.NoError(t, s.Write(ctx, id, value))
+
+		assert.True(t, strings.HasPrefix(*parameters["/app/big"].currentParam.Value, compressedPrefix))
+		assert.Len(t, parameters, 1)
+
+		secret, err := s.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, value, *secret.Value)
+	})
+
+	t.Run("large values are chunked", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s := NewTestSSMStore(parameters)
+		id := SecretId{Service: "app", Key: "huge"}
+		first := randomValue(t, 10000)
+		second := randomValue(t, 12000)
+		require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "small"}, "value"))
+		require.NoError(t, s.Write(ctx, id, first))
+		require.NoError(t, s.Write(ctx, id, second))
+
+		assert.True(t, strings.HasPrefix(*parameters["/app/huge"].currentParam.Value, chunkedPrefix))
+		assert.Contains(t, parameters, "/app/huge/__chunk_0")
+
+		secret, err := s.Read(ctx, id, -1)
+		require.NoError(t, err)
+		assert.Equal(t, second, *secret.Value)
+		assert.Equal(t, 2, secret.Meta.Version)
+
+		secret, err = s.Read(ctx, id, 1)
+		require.NoError(t, err)
+		assert.Equal(t, first, *secret.Value, "old manifests read the chunk versions they were written with")
+
+		secrets, err := s.List(ctx, "app", true)
+		require.NoError(t, err)
+		require.Len(t, secrets, 2)
+		for _, secret := range secrets {
+			if secret.Meta.Key == "/app/huge" {
+				assert.Equal(t, second, *secret.Value)
+			}
+		}
+
+		rawSecrets, err := s.ListRaw(ctx, "app")
+		require.NoError(t, err)
+		require.Len(t, rawSecrets, 2)
+		for _, rawSecret := range rawSecrets {
+			if rawSecret.Key == "/app/huge" {
+				assert.Equal(t, second, rawSecret.Value)
+			}
+		}
+
+		read, err := s.ReadMany(ctx, []SecretId{id})
+		require.NoError(t, err)
+		assert.Equal(t, second, *read[id].Value)
+
+		names, err := s.ListServices(ctx, "app", true)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"/app/huge", "/app/small"}, names)
+	})
+
+	t.Run("delete removes every chunk", func(t *testing.T) {
+		parameters := map[string]mockParameter{}
+		s := NewTestSSMStore(parameters)
+		id := SecretId{Service: "app", Key: "huge"}
+		require.NoError(t, s.Write(ctx, id, randomValue(t, 10000)))
+		require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "small"}, "value"))
+
+		require.NoError(t, s.Delete(ctx, id))
+		assert.Len(t, parameters, 1)
+		assert.Contains(t, parameters, "/app/small")
+	})
+
+	t.Run("chunk keys are reserved", func(t *testing.T) {
+		s := NewTestSSMStore(map[string]mockParameter{})
+		err := s.Write(ctx, SecretId{Service: "app", Key: "__chunk_0"}, "value")
+		assert.Error(t, err)
+	})
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +36,9 @@ var validPathKeyFormat = regexp.MustCompile(`^(\/[\w\-\.]+)+$`)
 
 // ensure SSMStore confirms to Store interface
 var _ Store = &SSMStore{}
+var _ Labeler = &SSMStore{}
+var _ OptionsWriter = &SSMStore{}
+var _ Reencrypter = &SSMStore{}
 
 // label check regexp
 var labelMatchRegex = regexp.MustCompile(`^(\/[\w\-\.]+)+:(.+)$`)
@@ -41,8 +46,9 @@ var labelMatchRegex = regexp.MustCompile(`^(\/[\w\-\.]+)+:(.+)$`)
 // SSMStore implements the Store interface for storing secrets in SSM Parameter
 // Store
 type SSMStore struct {
-	svc    apiSSM
-	config aws.Config
+	svc         apiSSM
+	config      aws.Config
+	kmsKeyAlias string
 }
 
 // NewSSMStore creates a new SSMStore
@@ -69,6 +75,12 @@ func ssmStoreUsingRetryer(ctx context.Context, numRetries int, retryMode aws.Ret
 	if err != nil {
 		return nil, err
 	}
+	return NewSSMStoreFromConfig(cfg, ""), nil
+}
+
+// NewSSMStoreFromConfig creates a new SSMStore using an existing AWS config.
+// An empty kmsKeyAlias means $CHAMBER_KMS_KEY_ALIAS, or else DefaultKeyID.
+func NewSSMStoreFromConfig(cfg aws.Config, kmsKeyAlias string) *SSMStore {
 	customSsmEndpoint, ok := os.LookupEnv(CustomSSMEndpointEnvVar)
 	if ok {
 		cfg.BaseEndpoint = aws.String(customSsmEndpoint)
@@ -77,12 +89,21 @@ func ssmStoreUsingRetryer(ctx context.Context, numRetries int, retryMode aws.Ret
 	svc := ssm.NewFromConfig(cfg)
 
 	return &SSMStore{
-		svc:    svc,
-		config: cfg,
-	}, nil
+		svc:         svc,
+		config:      cfg,
+		kmsKeyAlias: kmsKeyAlias,
+	}
+}
+
+// Supports reports that the SSM Store supports every optional feature.
+func (s *SSMStore) Supports(c Capability) bool {
+	return true
 }
 
 func (s *SSMStore) KMSKey() string {
+	if s.kmsKeyAlias != "" {
+		return s.kmsKeyAlias
+	}
 	fromEnv, ok := os.LookupEnv("CHAMBER_KMS_KEY_ALIAS")
 	if !ok {
 		return DefaultKeyID
@@ -130,7 +151,7 @@ func (s *SSMStore) SetConfig(ctx context.Context, config StoreConfig) error {
 		return fmt.Errorf("failed to marshal store config: %w", err)
 	}
 
-	err = s.write(ctx, storeConfigID, string(configBytes), nil)
+	err = s.WriteWithOptions(ctx, storeConfigID, string(configBytes), WriteOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to write store config: %w", err)
 	}
@@ -140,14 +161,17 @@ func (s *SSMStore) SetConfig(ctx context.Context, config StoreConfig) error {
 // Write writes a given value to a secret identified by id.  If the secret
 // already exists, then write a new version.
 func (s *SSMStore) Write(ctx context.Context, id SecretId, value string) error {
-	return s.write(ctx, id, value, nil)
+	return s.WriteWithOptions(ctx, id, value, WriteOptions{})
 }
 
 func (s *SSMStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) error {
-	return s.write(ctx, id, value, tags)
+	return s.WriteWithOptions(ctx, id, value, WriteOptions{Tags: tags})
 }
 
-func (s *SSMStore) write(ctx context.Context, id SecretId, value string, tags map[string]string) error {
+// WriteWithOptions writes a secret with tags, a tier and policies. Policies
+// need the Advanced tier, which is used if no tier is given.
+func (s *SSMStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error {
+	tags := opts.Tags
 	version := 1
 	// first read to get the current version
 	current, err := s.Read(ctx, id, -1)
@@ -158,6 +182,10 @@ func (s *SSMStore) write(ctx context.Context, id SecretId, value string, tags ma
 		version = current.Meta.Version + 1
 	}
 
+	if isChunkName(s.idToName(id)) {
+		return fmt.Errorf("key %s is reserved for chunks of larger values", id.Key)
+	}
+
 	if len(tags) > 0 && version != 1 {
 		return errors.New("tags on write only supported for new secrets")
 	}
@@ -167,20 +195,43 @@ func (s *SSMStore) write(ctx context.Context, id SecretId, value string, tags ma
 		return err
 	}
 
-	putParameterInput := &ssm.PutParameterInput{
-		KeyId:       aws.String(s.KMSKey()),
-		Name:        aws.String(s.idToName(id)),
-		Type:        types.ParameterTypeSecureString,
-		Value:       aws.String(value),
-		Overwrite:   aws.Bool(true),
-		Description: aws.String(strconv.Itoa(version)),
+	policies, err := ssmPolicies(opts.Policies)
+	if err != nil {
+		return err
+	}
+	tier := opts.Tier
+	if tier == "" && policies != "" {
+		tier = TierAdvanced
+	}
+	if tier == TierStandard && policies != "" {
+		return errors.New("policies are not supported for the Standard tier")
+	}
+	if tier == "" && current.Meta.Tier == TierAdvanced {
+		// SSM can't move a parameter back down to the default tier
+		tier = TierAdvanced
+	}
+
+	// an existing parameter keeps its type unless another is given
+	paramType := opts.Type
+	if paramType == "" {
+		paramType = current.Meta.Type
+	}
+
+	putParameterInput := s.putParameterInput(id, value, version, paramType)
+	putParameterInput.Tier = types.ParameterTier(tier)
+	if policies != "" {
+		putParameterInput.Policies = aws.String(policies)
+	}
+	if err := s.encodeValue(ctx, putParameterInput); err != nil {
+		return err
 	}
 
 	// This API call returns an empty struct
 	_, err = s.svc.PutParameter(ctx, putParameterInput)
 	if err != nil {
-		return err
+		return classifyError(err, s.idToName(id))
 	}
+	reportWrittenVersion(ctx, id, version)
 
 	if len(tags) > 0 {
 		if err := s.WriteTags(ctx, id, tags, false); err != nil {
@@ -191,6 +242,72 @@ func (s *SSMStore) write(ctx context.Context, id SecretId, value string, tags ma
 	return nil
 }
 
+// WriteMany writes new versions of many secrets. The current versions are
+// read in batches rather than one secret at a time.
+func (s *SSMStore) WriteMany(ctx context.Context, values map[SecretId]string) error {
+	ids := sortedIds(values)
+	current, err := s.ReadMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if isChunkName(s.idToName(id)) {
+			return fmt.Errorf("key %s is reserved for chunks of larger values", id.Key)
+		}
+	}
+
+	for _, id := range ids {
+		if _, ok := current[id]; !ok {
+			if err := s.checkForRequiredTags(ctx, nil, 1); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	for _, id := range ids {
+		version := 1
+		var tier types.ParameterTier
+		if secret, ok := current[id]; ok {
+			version = secret.Meta.Version + 1
+			if secret.Meta.Tier == TierAdvanced {
+				tier = types.ParameterTierAdvanced
+			}
+		}
+
+		putParameterInput := s.putParameterInput(id, values[id], version, current[id].Meta.Type)
+		putParameterInput.Tier = tier
+		if err := s.encodeValue(ctx, putParameterInput); err != nil {
+			return err
+		}
+		if _, err := s.svc.PutParameter(ctx, putParameterInput); err != nil {
+			return classifyError(err, s.idToName(id))
+		}
+		reportWrittenVersion(ctx, id, version)
+	}
+	return nil
+}
+
+// putParameterInput writes a version of a parameter of the given type, or a
+// SecureString if paramType is empty. Only SecureStrings use the KMS key.
+func (s *SSMStore) putParameterInput(id SecretId, value string, version int, paramType string) *ssm.PutParameterInput {
+	putParameterInput := &ssm.PutParameterInput{
+		Name:        aws.String(s.idToName(id)),
+		Type:        types.ParameterTypeSecureString,
+		Value:       aws.String(value),
+		Overwrite:   aws.Bool(true),
+		Description: aws.String(strconv.Itoa(version)),
+	}
+	if paramType != "" {
+		putParameterInput.Type = types.ParameterType(paramType)
+	}
+	if putParameterInput.Type == types.ParameterTypeSecureString {
+		putParameterInput.KeyId = aws.String(s.KMSKey())
+	}
+	return putParameterInput
+}
+
 func (s *SSMStore) checkForRequiredTags(ctx context.Context, tags map[string]string, version int) error {
 	if version != 1 {
 		return nil
@@ -271,7 +388,7 @@ func (s *SSMStore) WriteTags(ctx context.Context, id SecretId, tags map[string]s
 	}
 	_, err := s.svc.AddTagsToResource(ctx, addTagsInput)
 	if err != nil {
-		return err
+		return classifyError(err, s.idToName(id))
 	}
 
 	return nil
@@ -316,7 +433,7 @@ func (s *SSMStore) ReadTags(ctx context.Context, id SecretId) (map[string]string
 		if errors.As(err, &iri) {
 			return nil, ErrSecretNotFound
 		}
-		return nil, err
+		return nil, classifyError(err, s.idToName(id))
 	}
 
 	tags := make(map[string]string, len(resp.TagList))
@@ -341,10 +458,11 @@ func (s *SSMStore) Delete(ctx context.Context, id SecretId) error {
 
 	_, err = s.svc.DeleteParameter(ctx, deleteParameterInput)
 	if err != nil {
-		return err
+		return classifyError(err, s.idToName(id))
 	}
 
-	return nil
+	// chunks go after the manifest, so readers never see a partial value
+	return s.deleteChunks(ctx, s.idToName(id))
 }
 
 func (s *SSMStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) error {
@@ -360,7 +478,7 @@ func (s *SSMStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string
 	}
 	_, err = s.svc.RemoveTagsFromResource(ctx, removeTagsInput)
 	if err != nil {
-		return err
+		return classifyError(err, s.idToName(id))
 	}
 
 	return nil
@@ -400,7 +518,7 @@ func (s *SSMStore) readVersion(ctx context.Context, id SecretId, version int) (S
 	for paginator.HasMorePages() {
 		o, err := paginator.NextPage(ctx)
 		if err != nil {
-			return Secret{}, ErrSecretNotFound
+			return Secret{}, historyError(err, s.idToName(id))
 		}
 		for _, history := range o.Parameters {
 			thisVersion := 0
@@ -422,6 +540,11 @@ func (s *SSMStore) readVersion(ctx context.Context, id SecretId, version int) (S
 		}
 	}
 	if result.Value != nil {
+		value, err := s.decodeValue(ctx, s.idToName(id), result.Value)
+		if err != nil {
+			return Secret{}, err
+		}
+		result.Value = value
 		return result, nil
 	}
 
@@ -436,7 +559,7 @@ func (s *SSMStore) readLatest(ctx context.Context, id SecretId) (Secret, error)
 
 	resp, err := s.svc.GetParameters(ctx, getParametersInput)
 	if err != nil {
-		return Secret{}, err
+		return Secret{}, classifyError(err, s.idToName(id))
 	}
 
 	if len(resp.Parameters) == 0 {
@@ -463,7 +586,7 @@ func (s *SSMStore) readLatest(ctx context.Context, id SecretId) (Secret, error)
 	for paginator.HasMorePages() {
 		o, err := paginator.NextPage(ctx)
 		if err != nil {
-			return Secret{}, err
+			return Secret{}, classifyError(err, basePath(s.idToName(id)))
 		}
 		for _, param := range o.Parameters {
 			if *param.Name == s.idToName(id) {
@@ -479,170 +602,363 @@ func (s *SSMStore) readLatest(ctx context.Context, id SecretId) (Secret, error)
 
 	secretMeta := parameterMetaToSecretMeta(*parameter)
 
+	value, err := s.decodeValue(ctx, s.idToName(id), param.Value)
+	if err != nil {
+		return Secret{}, err
+	}
+
 	return Secret{
-		Value: param.Value,
+		Value: value,
 		Meta:  secretMeta,
 	}, nil
 }
 
-func (s *SSMStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
-	secrets := map[string]Secret{}
-
-	describeParametersInput := &ssm.DescribeParametersInput{
-		MaxResults: aws.Int32(50),
-		ParameterFilters: []types.ParameterStringFilter{
-			{
-				Key:    aws.String("Name"),
-				Option: aws.String("BeginsWith"),
-				Values: []string{"/" + service},
-			},
-		},
+// ReadMany reads the latest versions of many secrets, with GetParameters in
+// batches of 10 and the metadata from one listing of each service.
+func (s *SSMStore) ReadMany(ctx context.Context, ids []SecretId) (map[SecretId]Secret, error) {
+	idsByName := map[string]SecretId{}
+	var names []string
+	for _, id := range ids {
+		name := s.idToName(id)
+		if _, ok := idsByName[name]; ok {
+			continue
+		}
+		idsByName[name] = id
+		names = append(names, name)
 	}
 
-	paginator := ssm.NewDescribeParametersPaginator(s.svc, describeParametersInput)
-	for paginator.HasMorePages() {
-		resp, err := paginator.NextPage(ctx)
+	params := map[string]types.Parameter{}
+	for i := 0; i < len(names); i += 10 {
+		batch := names[i:min(i+10, len(names))]
+
+		getParametersInput := &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: aws.Bool(true),
+		}
+
+		resp, err := s.svc.GetParameters(ctx, getParametersInput)
 		if err != nil {
-			return nil, err
+			return nil, classifyError(err, basePath(batch[0]))
 		}
-		for _, meta := range resp.Parameters {
-			if !s.validateName(*meta.Name) {
-				continue
-			}
-			secretMeta := parameterMetaToSecretMeta(meta)
-			secrets[secretMeta.Key] = Secret{
-				Value: nil,
-				Meta:  secretMeta,
-			}
+		for _, param := range resp.Parameters {
+			params[*param.Name] = param
 		}
 	}
 
-	if includeSecretName {
-		return keys(secrets), nil
+	// As in readLatest, the metadata can only be had by listing each path
+	var paths []string
+	for name := range params {
+		paths = append(paths, basePath(name))
 	}
 
-	var services []string
-	for key := range secrets {
-		services = append(services, serviceName(key))
+	secrets := map[SecretId]Secret{}
+	for _, path := range uniqueStringSlice(paths) {
+		describeParametersInput := &ssm.DescribeParametersInput{
+			ParameterFilters: []types.ParameterStringFilter{
+				{
+					Key:    aws.String("Path"),
+					Option: aws.String("OneLevel"),
+					Values: []string{path},
+				},
+			},
+		}
+		paginator := ssm.NewDescribeParametersPaginator(s.svc, describeParametersInput)
+		for paginator.HasMorePages() {
+			o, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, classifyError(err, path)
+			}
+			for _, meta := range o.Parameters {
+				param, ok := params[*meta.Name]
+				if !ok {
+					continue
+				}
+				value, err := s.decodeValue(ctx, *meta.Name, param.Value)
+				if err != nil {
+					return nil, err
+				}
+				secrets[idsByName[*meta.Name]] = Secret{
+					Value: value,
+					Meta:  parameterMetaToSecretMeta(meta),
+				}
+			}
+		}
 	}
+	return secrets, nil
+}
+
+func (s *SSMStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
+	return collect(s.ListServicesIter(ctx, service, includeSecretName))
+}
+
+// ListServicesIter yields the services, or the secret names if
+// includeSecretName is true, beginning with service, a page at a time.
+func (s *SSMStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		describeParametersInput := &ssm.DescribeParametersInput{
+			MaxResults: aws.Int32(50),
+			ParameterFilters: []types.ParameterStringFilter{
+				{
+					Key:    aws.String("Name"),
+					Option: aws.String("BeginsWith"),
+					Values: []string{"/" + service},
+				},
+			},
+		}
 
-	return uniqueStringSlice(services), nil
+		// parameter names are unique, but many share a service
+		seen := map[string]struct{}{}
+		paginator := ssm.NewDescribeParametersPaginator(s.svc, describeParametersInput)
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				yield("", classifyError(err, "/"+service))
+				return
+			}
+			for _, meta := range resp.Parameters {
+				if !s.validateName(*meta.Name) {
+					continue
+				}
+				name := *meta.Name
+				if !includeSecretName {
+					name = serviceName(name)
+					if _, ok := seen[name]; ok {
+						continue
+					}
+					seen[name] = struct{}{}
+				}
+				if !yield(name, nil) {
+					return
+				}
+			}
+		}
+	}
 }
 
 // List lists all secrets for a given service.  If includeValues is true,
 // then those secrets are decrypted and returned, otherwise only the metadata
 // about a secret is returned.
 func (s *SSMStore) List(ctx context.Context, serviceName string, includeValues bool) ([]Secret, error) {
-	secrets := map[string]Secret{}
-
-	var describeParametersInput *ssm.DescribeParametersInput
+	return collect(s.ListIter(ctx, serviceName, includeValues))
+}
 
+// ListIter yields the secrets for a given service a page at a time. If
+// includeValues is true, each page of metadata is decrypted while the next
+// one is fetched.
+func (s *SSMStore) ListIter(ctx context.Context, serviceName string, includeValues bool) iter.Seq2[Secret, error] {
 	service, _ := parseServiceLabel(serviceName)
 
-	describeParametersInput = &ssm.DescribeParametersInput{
-		ParameterFilters: []types.ParameterStringFilter{
-			{
-				Key:    aws.String("Path"),
-				Option: aws.String("OneLevel"),
-				Values: []string{"/" + service},
+	return func(yield func(Secret, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		describeParametersInput := &ssm.DescribeParametersInput{
+			ParameterFilters: []types.ParameterStringFilter{
+				{
+					Key:    aws.String("Path"),
+					Option: aws.String("OneLevel"),
+					Values: []string{"/" + service},
+				},
 			},
-		},
-	}
-
-	paginator := ssm.NewDescribeParametersPaginator(s.svc, describeParametersInput)
-	for paginator.HasMorePages() {
-		resp, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, err
 		}
-		for _, meta := range resp.Parameters {
-			if !s.validateName(*meta.Name) {
-				continue
+
+		for page := range s.describePages(ctx, describeParametersInput, "/"+service) {
+			if page.err != nil {
+				yield(Secret{}, page.err)
+				return
 			}
-			secretMeta := parameterMetaToSecretMeta(meta)
-			secrets[secretMeta.Key] = Secret{
-				Value: nil,
-				Meta:  secretMeta,
+			if includeValues {
+				if err := s.readValues(ctx, page.secrets, "/"+service); err != nil {
+					yield(Secret{}, err)
+					return
+				}
+			}
+			for _, secret := range page.secrets {
+				if !yield(secret, nil) {
+					return
+				}
 			}
 		}
 	}
+}
 
-	if includeValues {
-		secretKeys := keys(secrets)
-		for i := 0; i < len(secretKeys); i += 10 {
-			batchEnd := i + 10
-			if i+10 > len(secretKeys) {
-				batchEnd = len(secretKeys)
-			}
-			batch := secretKeys[i:batchEnd]
-
-			getParametersInput := &ssm.GetParametersInput{
-				Names:          batch,
-				WithDecryption: aws.Bool(true),
-			}
+// metadataPage is one page of DescribeParameters results
+type metadataPage struct {
+	secrets []Secret
+	err     error
+}
 
-			resp, err := s.svc.GetParameters(ctx, getParametersInput)
+// describePages fetches pages of parameter metadata in the background, one
+// page ahead of the reader, until the pages run out, an error is sent, or ctx
+// is done.
+func (s *SSMStore) describePages(ctx context.Context, input *ssm.DescribeParametersInput, resource string) <-chan metadataPage {
+	pages := make(chan metadataPage, 1)
+	go func() {
+		defer close(pages)
+		paginator := ssm.NewDescribeParametersPaginator(s.svc, input)
+		for paginator.HasMorePages() {
+			var page metadataPage
+			resp, err := paginator.NextPage(ctx)
 			if err != nil {
-				return nil, err
+				page.err = classifyError(err, resource)
+			} else {
+				for _, meta := range resp.Parameters {
+					if !s.validateName(*meta.Name) {
+						continue
+					}
+					page.secrets = append(page.secrets, Secret{
+						Value: nil,
+						Meta:  parameterMetaToSecretMeta(meta),
+					})
+				}
 			}
 
-			for _, param := range resp.Parameters {
-				secret := secrets[*param.Name]
-				secret.Value = param.Value
-				secrets[*param.Name] = secret
+			select {
+			case pages <- page:
+			case <-ctx.Done():
+				return
+			}
+			if page.err != nil {
+				return
 			}
 		}
+	}()
+	return pages
+}
+
+// readValues decrypts the values of secrets, 10 at a time
+func (s *SSMStore) readValues(ctx context.Context, secrets []Secret, resource string) error {
+	byName := make(map[string]int, len(secrets))
+	names := make([]string, 0, len(secrets))
+	for i, secret := range secrets {
+		byName[secret.Meta.Key] = i
+		names = append(names, secret.Meta.Key)
 	}
 
-	return values(secrets), nil
+	for i := 0; i < len(names); i += 10 {
+		getParametersInput := &ssm.GetParametersInput{
+			Names:          names[i:min(i+10, len(names))],
+			WithDecryption: aws.Bool(true),
+		}
+
+		resp, err := s.svc.GetParameters(ctx, getParametersInput)
+		if err != nil {
+			return classifyError(err, resource)
+		}
+
+		for _, param := range resp.Parameters {
+			value, err := s.decodeValue(ctx, *param.Name, param.Value)
+			if err != nil {
+				return err
+			}
+			secrets[byName[*param.Name]].Value = value
+		}
+	}
+	return nil
 }
 
 // ListRaw lists all secrets keys and values for a given service. Does not include any
 // other meta-data. Uses faster AWS APIs with much higher rate-limits. Suitable for
 // use in production environments.
 func (s *SSMStore) ListRaw(ctx context.Context, serviceName string) ([]RawSecret, error) {
+	return collect(s.ListRawIter(ctx, serviceName))
+}
+
+// ListRawIter yields the keys and values for a given service a page at a
+// time.
+func (s *SSMStore) ListRawIter(ctx context.Context, serviceName string) iter.Seq2[RawSecret, error] {
 	service, label := parseServiceLabel(serviceName)
-	secrets := map[string]RawSecret{}
-	getParametersByPathInput := &ssm.GetParametersByPathInput{
-		Path:           aws.String("/" + service + "/"),
-		WithDecryption: aws.Bool(true),
-	}
-	if label != "" {
-		getParametersByPathInput.ParameterFilters = []types.ParameterStringFilter{
-			{
-				Key:    aws.String("Label"),
-				Option: aws.String("Equals"),
-				Values: []string{label},
-			},
-		}
-	}
 
-	paginator := ssm.NewGetParametersByPathPaginator(s.svc, getParametersByPathInput)
-	for paginator.HasMorePages() {
-		resp, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, err
+	return func(yield func(RawSecret, error) bool) {
+		getParametersByPathInput := &ssm.GetParametersByPathInput{
+			Path:           aws.String("/" + service + "/"),
+			WithDecryption: aws.Bool(true),
+		}
+		if label != "" {
+			getParametersByPathInput.ParameterFilters = []types.ParameterStringFilter{
+				{
+					Key:    aws.String("Label"),
+					Option: aws.String("Equals"),
+					Values: []string{label},
+				},
+			}
 		}
 
-		for _, param := range resp.Parameters {
-			if !s.validateName(*param.Name) {
-				continue
+		paginator := ssm.NewGetParametersByPathPaginator(s.svc, getParametersByPathInput)
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				yield(RawSecret{}, classifyError(err, *getParametersByPathInput.Path))
+				return
 			}
 
-			secrets[*param.Name] = RawSecret{
-				Value: *param.Value,
-				Key:   *param.Name,
+			for _, param := range resp.Parameters {
+				if !s.validateName(*param.Name) {
+					continue
+				}
+
+				value, err := s.decodeValue(ctx, *param.Name, param.Value)
+				if err != nil {
+					yield(RawSecret{}, err)
+					return
+				}
+				if !yield(RawSecret{Value: *value, Key: *param.Name}, nil) {
+					return
+				}
 			}
 		}
 	}
+}
 
-	rawSecrets := make([]RawSecret, len(secrets))
-	i := 0
-	for _, rawSecret := range secrets {
-		rawSecrets[i] = rawSecret
-		i += 1
+// Reencrypt writes the latest value of a SecureString parameter again under
+// kmsKey. PutParameter makes this a new parameter version, though chamber's
+// version, kept in the description, stays the same, as do the tier and
+// policies; a no change notification counts from the new version, and labels
+// on the old version are moved to it. Earlier versions in the parameter's
+// history stay encrypted with the keys they were written with.
+func (s *SSMStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) error {
+	kmsKey = NormalizeKMSKey(kmsKey)
+	current, err := s.readLatest(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current.Meta.Type != TypeSecureString {
+		return fmt.Errorf("%s is a %s parameter, which isn't encrypted with KMS", s.idToName(id), current.Meta.Type)
+	}
+	if current.Meta.KMSKey == kmsKey {
+		return nil
+	}
+	labels, err := s.ListLabels(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// a put without policies would drop them
+	policies, err := ssmPolicies(current.Meta.Policies)
+	if err != nil {
+		return err
 	}
-	return rawSecrets, nil
+	putParameterInput := s.putParameterInput(id, *current.Value, current.Meta.Version, current.Meta.Type)
+	putParameterInput.KeyId = aws.String(kmsKey)
+	putParameterInput.Tier = types.ParameterTier(current.Meta.Tier)
+	if policies != "" {
+		putParameterInput.Policies = aws.String(policies)
+	}
+	if err := s.encodeValue(ctx, putParameterInput); err != nil {
+		return err
+	}
+	if _, err := s.svc.PutParameter(ctx, putParameterInput); err != nil {
+		return classifyError(err, s.idToName(id))
+	}
+	reportWrittenVersion(ctx, id, current.Meta.Version)
+	// the old version is still under the old key, so labels left on it
+	// would stop being readable once that key is disabled
+	if moved := labels[current.Meta.Version]; len(moved) > 0 {
+		if err := s.LabelVersion(ctx, id, -1, moved); err != nil {
+			return fmt.Errorf("failed to move labels to the re-encrypted version of %s: %w", s.idToName(id), err)
+		}
+	}
+	return nil
 }
 
 // History returns a list of events that have occurred regarding the given
@@ -659,7 +975,7 @@ func (s *SSMStore) History(ctx context.Context, id SecretId) ([]ChangeEvent, err
 	for paginator.HasMorePages() {
 		o, err := paginator.NextPage(ctx)
 		if err != nil {
-			return events, ErrSecretNotFound
+			return events, historyError(err, s.idToName(id))
 		}
 
 		for _, history := range o.Parameters {
@@ -681,12 +997,148 @@ func (s *SSMStore) History(ctx context.Context, id SecretId) ([]ChangeEvent, err
 	return events, nil
 }
 
+// LabelVersion attaches labels to a version of a secret, moving them from any
+// other version. Use -1 for the latest version.
+func (s *SSMStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) error {
+	name := s.idToName(id)
+	labelParameterVersionInput := &ssm.LabelParameterVersionInput{
+		Name:   aws.String(name),
+		Labels: labels,
+	}
+	if version != -1 {
+		// SSM has its own version numbers, which don't match chamber's if a
+		// parameter was written outside of chamber
+		history, err := s.parameterHistory(ctx, id)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, h := range history {
+			if h.Description != nil && *h.Description == strconv.Itoa(version) {
+				labelParameterVersionInput.ParameterVersion = aws.Int64(h.Version)
+				found = true
+			}
+		}
+		if !found {
+			return ErrSecretNotFound
+		}
+	}
+
+	resp, err := s.svc.LabelParameterVersion(ctx, labelParameterVersionInput)
+	if err != nil {
+		var pvnf *types.ParameterVersionNotFound
+		if errors.As(err, &pvnf) {
+			return ErrSecretNotFound
+		}
+		return historyError(err, name)
+	}
+	if len(resp.InvalidLabels) > 0 {
+		return &Error{
+			Kind:     ErrValidation,
+			Resource: name,
+			Err:      fmt.Errorf("invalid labels %s", strings.Join(resp.InvalidLabels, ", ")),
+		}
+	}
+	return nil
+}
+
+// Unlabel removes labels from whichever versions of a secret they're on.
+func (s *SSMStore) Unlabel(ctx context.Context, id SecretId, labels []string) error {
+	name := s.idToName(id)
+	history, err := s.parameterHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	for _, h := range history {
+		var remove []string
+		for _, label := range h.Labels {
+			if slices.Contains(labels, label) {
+				remove = append(remove, label)
+			}
+		}
+		if len(remove) == 0 {
+			continue
+		}
+
+		unlabelParameterVersionInput := &ssm.UnlabelParameterVersionInput{
+			Name:             aws.String(name),
+			ParameterVersion: aws.Int64(h.Version),
+			Labels:           remove,
+		}
+		if _, err := s.svc.UnlabelParameterVersion(ctx, unlabelParameterVersionInput); err != nil {
+			return historyError(err, name)
+		}
+		for _, label := range remove {
+			removed[label] = true
+		}
+	}
+
+	for _, label := range labels {
+		if !removed[label] {
+			return fmt.Errorf("%w: %s on %s", ErrLabelNotFound, label, name)
+		}
+	}
+	return nil
+}
+
+// ListLabels returns the labels on each labeled version of a secret.
+func (s *SSMStore) ListLabels(ctx context.Context, id SecretId) (map[int][]string, error) {
+	history, err := s.parameterHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[int][]string{}
+	for _, h := range history {
+		if len(h.Labels) == 0 {
+			continue
+		}
+		version := 0
+		if h.Description != nil {
+			version, _ = strconv.Atoi(*h.Description)
+		}
+		labels[version] = append(labels[version], h.Labels...)
+	}
+	return labels, nil
+}
+
+func (s *SSMStore) parameterHistory(ctx context.Context, id SecretId) ([]types.ParameterHistory, error) {
+	var history []types.ParameterHistory
+
+	getParameterHistoryInput := &ssm.GetParameterHistoryInput{
+		Name:           aws.String(s.idToName(id)),
+		WithDecryption: aws.Bool(false),
+	}
+
+	paginator := ssm.NewGetParameterHistoryPaginator(s.svc, getParameterHistoryInput)
+	for paginator.HasMorePages() {
+		o, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, historyError(err, s.idToName(id))
+		}
+		history = append(history, o.Parameters...)
+	}
+	return history, nil
+}
+
+// historyError converts an error reading a parameter's history, where a
+// missing parameter is an error rather than an empty history.
+func historyError(err error, name string) error {
+	var pnf *types.ParameterNotFound
+	if errors.As(err, &pnf) {
+		return ErrSecretNotFound
+	}
+	return classifyError(err, name)
+}
+
 func (s *SSMStore) idToName(id SecretId) string {
 	return fmt.Sprintf("/%s/%s", id.Service, id.Key)
 }
 
 func (s *SSMStore) validateName(name string) bool {
-	return validPathKeyFormat.MatchString(name)
+	return validPathKeyFormat.MatchString(name) && !isChunkName(name)
 }
 
 func basePath(key string) string {
@@ -712,22 +1164,24 @@ func parameterMetaToSecretMeta(p types.ParameterMetadata) SecretMetadata {
 	if p.Description != nil {
 		version, _ = strconv.Atoi(*p.Description)
 	}
+	var policies Policies
+	for _, policy := range p.Policies {
+		if policy.PolicyText != nil {
+			parseSSMPolicy(*policy.PolicyText, &policies)
+		}
+	}
 	return SecretMetadata{
 		Created:   *p.LastModifiedDate,
 		CreatedBy: *p.LastModifiedUser,
 		Version:   version,
 		Key:       *p.Name,
+		Type:      string(p.Type),
+		Tier:      string(p.Tier),
+		Policies:  policies,
+		KMSKey:    aws.ToString(p.KeyId),
 	}
 }
 
-func keys(m map[string]Secret) []string {
-	keys := []string{}
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
-}
-
 func values(m map[string]Secret) []Secret {
 	values := []Secret{}
 	for _, v := range m {
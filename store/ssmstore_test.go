@@ -2,10 +2,13 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -37,12 +40,28 @@ func mockPutParameter(i *ssm.PutParameterInput, parameters map[string]mockParame
 		Type:  i.Type,
 		Value: i.Value,
 	}
+	tier := i.Tier
+	if tier == "" {
+		tier = types.ParameterTierStandard
+	}
+	var policies []types.ParameterInlinePolicy
+	if i.Policies != nil {
+		var texts []json.RawMessage
+		if err := json.Unmarshal([]byte(*i.Policies), &texts); err != nil {
+			return nil, &types.InvalidPolicyAttributeException{Message: aws.String(err.Error())}
+		}
+		for _, text := range texts {
+			policies = append(policies, types.ParameterInlinePolicy{PolicyText: aws.String(string(text))})
+		}
+	}
 	current.meta = &types.ParameterMetadata{
 		Description:      i.Description,
 		KeyId:            i.KeyId,
 		LastModifiedDate: aws.Time(time.Now()),
 		LastModifiedUser: aws.String("test"),
 		Name:             i.Name,
+		Policies:         policies,
+		Tier:             tier,
 		Type:             i.Type,
 	}
 	history := types.ParameterHistory{
@@ -53,12 +72,60 @@ func mockPutParameter(i *ssm.PutParameterInput, parameters map[string]mockParame
 		Name:             current.meta.Name,
 		Type:             current.meta.Type,
 		Value:            current.currentParam.Value,
+		Version:          int64(len(current.history) + 1),
 	}
 	current.history = append(current.history, history)
 
 	parameters[*i.Name] = current
 
-	return &ssm.PutParameterOutput{}, nil
+	return &ssm.PutParameterOutput{Version: history.Version}, nil
+}
+
+func mockLabelParameterVersion(i *ssm.LabelParameterVersionInput, parameters map[string]mockParameter) (*ssm.LabelParameterVersionOutput, error) {
+	current, ok := parameters[*i.Name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String("parameter not found")}
+	}
+
+	version := int64(len(current.history))
+	if i.ParameterVersion != nil {
+		version = *i.ParameterVersion
+	}
+	if version < 1 || version > int64(len(current.history)) {
+		return nil, &types.ParameterVersionNotFound{Message: aws.String("parameter version not found")}
+	}
+
+	// a label is only ever on one version, so labeling moves it
+	for j := range current.history {
+		current.history[j].Labels = slices.DeleteFunc(current.history[j].Labels, func(label string) bool {
+			return slices.Contains(i.Labels, label)
+		})
+	}
+	current.history[version-1].Labels = append(current.history[version-1].Labels, i.Labels...)
+
+	return &ssm.LabelParameterVersionOutput{ParameterVersion: version}, nil
+}
+
+func mockUnlabelParameterVersion(i *ssm.UnlabelParameterVersionInput, parameters map[string]mockParameter) (*ssm.UnlabelParameterVersionOutput, error) {
+	current, ok := parameters[*i.Name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String("parameter not found")}
+	}
+	if *i.ParameterVersion < 1 || *i.ParameterVersion > int64(len(current.history)) {
+		return nil, &types.ParameterVersionNotFound{Message: aws.String("parameter version not found")}
+	}
+
+	h := &current.history[*i.ParameterVersion-1]
+	var removed []string
+	h.Labels = slices.DeleteFunc(h.Labels, func(label string) bool {
+		if slices.Contains(i.Labels, label) {
+			removed = append(removed, label)
+			return true
+		}
+		return false
+	})
+
+	return &ssm.UnlabelParameterVersionOutput{RemovedLabels: removed}, nil
 }
 
 func mockGetParameters(i *ssm.GetParametersInput, parameters map[string]mockParameter) (*ssm.GetParametersOutput, error) {
@@ -77,6 +144,29 @@ func mockGetParameters(i *ssm.GetParametersInput, parameters map[string]mockPara
 		}
 	}
 
+	// names may select a version, as name:version
+	for _, selector := range i.Names {
+		name, version, ok := strings.Cut(selector, ":")
+		if !ok {
+			continue
+		}
+		param, ok := parameters[name]
+		if !ok {
+			continue
+		}
+		for _, history := range param.history {
+			if strconv.FormatInt(history.Version, 10) == version {
+				returnParameters = append(returnParameters, types.Parameter{
+					Name:     history.Name,
+					Selector: aws.String(":" + version),
+					Type:     history.Type,
+					Value:    history.Value,
+					Version:  history.Version,
+				})
+			}
+		}
+	}
+
 	if len(parameters) == 0 {
 		return &ssm.GetParametersOutput{
 			Parameters: returnParameters,
@@ -96,7 +186,7 @@ func mockGetParameterHistory(i *ssm.GetParameterHistoryInput, parameters map[str
 		return &ssm.GetParameterHistoryOutput{
 			NextToken:  nil,
 			Parameters: history,
-		}, errors.New("parameter not found")
+		}, &types.ParameterNotFound{Message: aws.String("parameter not found")}
 	}
 
 	if *i.WithDecryption == true {
@@ -110,11 +200,13 @@ func mockGetParameterHistory(i *ssm.GetParameterHistoryInput, parameters map[str
 		history = append(history, types.ParameterHistory{
 			Description:      hist.Description,
 			KeyId:            hist.KeyId,
+			Labels:           hist.Labels,
 			LastModifiedDate: hist.LastModifiedDate,
 			LastModifiedUser: hist.LastModifiedUser,
 			Name:             hist.Name,
 			Type:             hist.Type,
 			Value:            nil,
+			Version:          hist.Version,
 		})
 	}
 	return &ssm.GetParameterHistoryOutput{
@@ -250,11 +342,12 @@ func anyPrefixInValue(val *string, prefixes []string) bool {
 }
 
 func pathInSlice(val *string, paths []string) bool {
-	tokens := strings.Split(*val, "/")
-	if len(tokens) < 2 {
+	// OneLevel matches the parameters directly under a path
+	i := strings.LastIndex(*val, "/")
+	if i < 1 {
 		return false
 	}
-	matchPath := "/" + tokens[1]
+	matchPath := (*val)[:i]
 	for _, path := range paths {
 		if matchPath == path {
 			return true
@@ -289,11 +382,10 @@ func matchStringFilters(filters []types.ParameterStringFilter, param mockParamet
 		var compareTo *string
 		switch *filter.Key {
 		case "Path":
-			tokens := strings.Split(*param.meta.Name, "/")
-			if len(tokens) < 2 {
+			if !strings.HasPrefix(*param.meta.Name, "/") {
 				return false, errors.New("path filter used on non path value")
 			}
-			compareTo = aws.String("/" + tokens[1] + "/")
+			compareTo = param.meta.Name
 
 			if !pathInSlice(compareTo, filter.Values) {
 				return false, nil
@@ -339,12 +431,18 @@ func NewTestSSMStore(parameters map[string]mockParameter) *SSMStore {
 			ListTagsForResourceFunc: func(ctx context.Context, params *ssm.ListTagsForResourceInput, optFns ...func(*ssm.Options)) (*ssm.ListTagsForResourceOutput, error) {
 				return mockListTagsForResource(params, parameters)
 			},
+			LabelParameterVersionFunc: func(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error) {
+				return mockLabelParameterVersion(params, parameters)
+			},
 			PutParameterFunc: func(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
 				return mockPutParameter(params, parameters)
 			},
 			RemoveTagsFromResourceFunc: func(ctx context.Context, params *ssm.RemoveTagsFromResourceInput, optFns ...func(*ssm.Options)) (*ssm.RemoveTagsFromResourceOutput, error) {
 				return mockRemoveTagsFromResource(params, parameters)
 			},
+			UnlabelParameterVersionFunc: func(ctx context.Context, params *ssm.UnlabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.UnlabelParameterVersionOutput, error) {
+				return mockUnlabelParameterVersion(params, parameters)
+			},
 		},
 	}
 }
@@ -74,6 +74,13 @@ type SecretMetadata struct {
 	CreatedBy string
 	Version   int
 	Key       string
+	// Type, Tier and Policies are only set by backends which support them
+	Type     string
+	Tier     string
+	Policies Policies
+	// KMSKey is the KMS key the secret is encrypted with, for backends
+	// which support more than one
+	KMSKey string
 }
 
 type ChangeEvent struct {
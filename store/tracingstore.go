@@ -0,0 +1,454 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names chamber's store instrumentation to OpenTelemetry
+const instrumentationName = "github.com/segmentio/chamber/v3/store"
+
+// durationBounds are the histogram bucket boundaries for durations in
+// seconds
+var durationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// TracingOptions control what a TracingStore records.
+type TracingOptions struct {
+	// RecordNames adds the service and key of each operation to its span.
+	// They're left out by default, since secret paths would then reach
+	// whatever collector is configured.
+	RecordNames bool
+}
+
+// TracingStore records a span and metrics for every operation on another
+// store, with the global OpenTelemetry providers. AWS API calls made by the
+// built-in backends are recorded as child spans, with their retries and
+// throttling.
+type TracingStore struct {
+	Store
+	backend string
+	options TracingOptions
+
+	tracer            trace.Tracer
+	operationDuration metric.Float64Histogram
+	operationErrors   metric.Int64Counter
+}
+
+var (
+	_ Store           = &TracingStore{}
+	_ BatchReader     = &TracingStore{}
+	_ BatchWriter     = &TracingStore{}
+	_ StreamingLister = &TracingStore{}
+	_ OptionsWriter   = &TracingStore{}
+	_ Labeler         = &TracingStore{}
+	_ Reencrypter     = &TracingStore{}
+)
+
+// NewTracingStore wraps s, recording backend with each operation, to the
+// global tracer and meter providers at the time it's called.
+func NewTracingStore(s Store, backend string, options TracingOptions) *TracingStore {
+	meter := otel.Meter(instrumentationName)
+	operationDuration, err := meter.Float64Histogram("chamber.store.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of chamber store operations"),
+		metric.WithExplicitBucketBoundaries(durationBounds...),
+	)
+	handleInstrumentError(err)
+	operationErrors, err := meter.Int64Counter("chamber.store.operation.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Failed chamber store operations, by error class"),
+	)
+	handleInstrumentError(err)
+	return &TracingStore{
+		Store:             s,
+		backend:           backend,
+		options:           options,
+		tracer:            otel.Tracer(instrumentationName),
+		operationDuration: operationDuration,
+		operationErrors:   operationErrors,
+	}
+}
+
+// handleInstrumentError reports a failure to create an instrument to the
+// OpenTelemetry error handler
+func handleInstrumentError(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// Supports reports what the wrapped store supports.
+func (s *TracingStore) Supports(c Capability) bool {
+	return Supports(s.Store, c)
+}
+
+// start starts a span for operation, returning a function to end it and
+// record its metrics
+func (s *TracingStore) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span, func(error)) {
+	started := time.Now()
+	metricAttributes := []attribute.KeyValue{attribute.String("chamber.operation", operation), attribute.String("chamber.backend", s.backend)}
+	ctx, span := s.tracer.Start(ctx, "chamber."+operation, trace.WithAttributes(append(attributes, metricAttributes...)...))
+	return ctx, span, func(err error) {
+		if err != nil {
+			errorType := attribute.String("error.type", errorClass(err))
+			span.SetAttributes(errorType)
+			metricAttributes = append(metricAttributes, errorType)
+			s.operationErrors.Add(ctx, 1, metric.WithAttributes(metricAttributes...))
+			span.SetStatus(codes.Error, s.errorDescription(err))
+		}
+		span.End()
+		s.operationDuration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(metricAttributes...))
+	}
+}
+
+// endSpan ends span, marking it failed if err isn't nil, with any secret
+// values masked in the error message
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, Redact(err.Error()))
+	}
+	span.End()
+}
+
+// errorDescription describes err for a span's status: its message, with any
+// secret values masked, if names are recorded, or else just its class, since
+// messages often name the secret
+func (s *TracingStore) errorDescription(err error) string {
+	if !s.options.RecordNames {
+		return errorClass(err)
+	}
+	return Redact(err.Error())
+}
+
+// secretAttributes returns the span attributes naming id, if names are
+// recorded
+func (s *TracingStore) secretAttributes(id SecretId) []attribute.KeyValue {
+	if !s.options.RecordNames {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("chamber.service", id.Service), attribute.String("chamber.key", id.Key)}
+}
+
+// serviceAttributes returns the span attributes naming service, if names are
+// recorded
+func (s *TracingStore) serviceAttributes(service string) []attribute.KeyValue {
+	if !s.options.RecordNames {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("chamber.service", service)}
+}
+
+func (s *TracingStore) SetConfig(ctx context.Context, config StoreConfig) (err error) {
+	ctx, _, end := s.start(ctx, "SetConfig")
+	defer func() { end(err) }()
+	return s.Store.SetConfig(ctx, config)
+}
+
+func (s *TracingStore) Config(ctx context.Context) (config StoreConfig, err error) {
+	ctx, _, end := s.start(ctx, "Config")
+	defer func() { end(err) }()
+	return s.Store.Config(ctx)
+}
+
+func (s *TracingStore) Write(ctx context.Context, id SecretId, value string) (err error) {
+	ctx, _, end := s.start(ctx, "Write", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.Write(ctx, id, value)
+}
+
+func (s *TracingStore) WriteWithTags(ctx context.Context, id SecretId, value string, tags map[string]string) (err error) {
+	ctx, _, end := s.start(ctx, "WriteWithTags", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.WriteWithTags(ctx, id, value, tags)
+}
+
+func (s *TracingStore) WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) (err error) {
+	ow, ok := s.Store.(OptionsWriter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support write options", ErrNotImplemented)
+	}
+	ctx, _, end := s.start(ctx, "WriteWithOptions", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return ow.WriteWithOptions(ctx, id, value, opts)
+}
+
+func (s *TracingStore) WriteMany(ctx context.Context, values map[SecretId]string) (err error) {
+	ctx, _, end := s.start(ctx, "WriteMany", attribute.Int("chamber.secrets", len(values)))
+	defer func() { end(err) }()
+	return WriteMany(ctx, s.Store, values)
+}
+
+func (s *TracingStore) Read(ctx context.Context, id SecretId, version int) (secret Secret, err error) {
+	ctx, _, end := s.start(ctx, "Read", append(s.secretAttributes(id), attribute.Int("chamber.version", version))...)
+	defer func() { end(err) }()
+	return s.Store.Read(ctx, id, version)
+}
+
+func (s *TracingStore) ReadMany(ctx context.Context, ids []SecretId) (secrets map[SecretId]Secret, err error) {
+	ctx, _, end := s.start(ctx, "ReadMany", attribute.Int("chamber.secrets", len(ids)))
+	defer func() { end(err) }()
+	return ReadMany(ctx, s.Store, ids)
+}
+
+func (s *TracingStore) WriteTags(ctx context.Context, id SecretId, tags map[string]string, deleteOtherTags bool) (err error) {
+	ctx, _, end := s.start(ctx, "WriteTags", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.WriteTags(ctx, id, tags, deleteOtherTags)
+}
+
+func (s *TracingStore) ReadTags(ctx context.Context, id SecretId) (tags map[string]string, err error) {
+	ctx, _, end := s.start(ctx, "ReadTags", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.ReadTags(ctx, id)
+}
+
+func (s *TracingStore) DeleteTags(ctx context.Context, id SecretId, tagKeys []string) (err error) {
+	ctx, _, end := s.start(ctx, "DeleteTags", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.DeleteTags(ctx, id, tagKeys)
+}
+
+func (s *TracingStore) History(ctx context.Context, id SecretId) (events []ChangeEvent, err error) {
+	ctx, _, end := s.start(ctx, "History", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.History(ctx, id)
+}
+
+func (s *TracingStore) Delete(ctx context.Context, id SecretId) (err error) {
+	ctx, _, end := s.start(ctx, "Delete", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return s.Store.Delete(ctx, id)
+}
+
+func (s *TracingStore) List(ctx context.Context, service string, includeValues bool) ([]Secret, error) {
+	return collect(s.ListIter(ctx, service, includeValues))
+}
+
+func (s *TracingStore) ListRaw(ctx context.Context, service string) ([]RawSecret, error) {
+	return collect(s.ListRawIter(ctx, service))
+}
+
+func (s *TracingStore) ListServices(ctx context.Context, service string, includeSecretName bool) ([]string, error) {
+	return collect(s.ListServicesIter(ctx, service, includeSecretName))
+}
+
+func (s *TracingStore) ListIter(ctx context.Context, service string, includeValues bool) iter.Seq2[Secret, error] {
+	return traceIter(s, ctx, "List", func(ctx context.Context) iter.Seq2[Secret, error] {
+		return ListIter(ctx, s.Store, service, includeValues)
+	}, append(s.serviceAttributes(service), attribute.Bool("chamber.include_values", includeValues))...)
+}
+
+func (s *TracingStore) ListRawIter(ctx context.Context, service string) iter.Seq2[RawSecret, error] {
+	return traceIter(s, ctx, "ListRaw", func(ctx context.Context) iter.Seq2[RawSecret, error] {
+		return ListRawIter(ctx, s.Store, service)
+	}, s.serviceAttributes(service)...)
+}
+
+func (s *TracingStore) ListServicesIter(ctx context.Context, service string, includeSecretName bool) iter.Seq2[string, error] {
+	return traceIter(s, ctx, "ListServices", func(ctx context.Context) iter.Seq2[string, error] {
+		return ListServicesIter(ctx, s.Store, service, includeSecretName)
+	}, s.serviceAttributes(service)...)
+}
+
+// traceIter records a span covering iteration over the sequence returned by
+// list, with the number of items yielded
+func traceIter[T any](s *TracingStore, ctx context.Context, operation string, list func(context.Context) iter.Seq2[T, error], attributes ...attribute.KeyValue) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		ctx, span, end := s.start(ctx, operation, attributes...)
+		var err error
+		count := 0
+		defer func() {
+			span.SetAttributes(attribute.Int("chamber.items", count))
+			end(err)
+		}()
+		for item, listErr := range list(ctx) {
+			err = listErr
+			if listErr == nil {
+				count++
+			}
+			if !yield(item, listErr) || listErr != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *TracingStore) LabelVersion(ctx context.Context, id SecretId, version int, labels []string) (err error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	ctx, _, end := s.start(ctx, "LabelVersion", append(s.secretAttributes(id), attribute.Int("chamber.version", version))...)
+	defer func() { end(err) }()
+	return l.LabelVersion(ctx, id, version, labels)
+}
+
+func (s *TracingStore) Unlabel(ctx context.Context, id SecretId, labels []string) (err error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	ctx, _, end := s.start(ctx, "Unlabel", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return l.Unlabel(ctx, id, labels)
+}
+
+func (s *TracingStore) ListLabels(ctx context.Context, id SecretId) (labels map[int][]string, err error) {
+	l, ok := s.Store.(Labeler)
+	if !ok {
+		return nil, fmt.Errorf("%w: this backend does not support labels", ErrNotImplemented)
+	}
+	ctx, _, end := s.start(ctx, "ListLabels", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return l.ListLabels(ctx, id)
+}
+
+func (s *TracingStore) Reencrypt(ctx context.Context, id SecretId, kmsKey string) (err error) {
+	r, ok := s.Store.(Reencrypter)
+	if !ok {
+		return fmt.Errorf("%w: this backend does not support %s", ErrNotImplemented, CapabilityReencrypt)
+	}
+	ctx, _, end := s.start(ctx, "Reencrypt", s.secretAttributes(id)...)
+	defer func() { end(err) }()
+	return r.Reencrypt(ctx, id, kmsKey)
+}
+
+// errorClass names the kind of err for metrics: one of the classified errors,
+// not_found, not_implemented, canceled, or other. It's empty for nil.
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrSecretNotFound):
+		return "not_found"
+	case errors.Is(err, ErrNotImplemented):
+		return "not_implemented"
+	case errors.Is(err, ErrKMSAccessDenied):
+		return "kms_access_denied"
+	case errors.Is(err, ErrAccessDenied):
+		return "access_denied"
+	case errors.Is(err, ErrThrottled):
+		return "throttled"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrQuotaExceeded):
+		return "quota_exceeded"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	}
+	return "other"
+}
+
+// tracingMiddleware records a span for each AWS API call, covering all of its
+// attempts, with how many were retried or throttled
+type tracingMiddleware struct{}
+
+func (tracingMiddleware) ID() string {
+	return "ChamberTracing"
+}
+
+func (tracingMiddleware) HandleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+	callAttributes := []attribute.KeyValue{attribute.String("rpc.service", service), attribute.String("rpc.method", operation)}
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, service+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(callAttributes, attribute.String("rpc.system", "aws-api"))...),
+	)
+	started := time.Now()
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	attempts, throttles := 0, 0
+	if results, ok := retry.GetAttemptResults(metadata); ok {
+		attempts = len(results.Results)
+		for i, result := range results.Results {
+			if result.Err == nil {
+				continue
+			}
+			if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(result.Err) == aws.TrueTernary {
+				throttles++
+				span.AddEvent("throttled", trace.WithAttributes(attribute.Int("aws.attempt", i+1), attribute.String("error.type", apiErrorCode(result.Err))))
+			}
+		}
+	}
+	retries := max(attempts-1, 0)
+	span.SetAttributes(attribute.Int("aws.attempts", attempts), attribute.Int("aws.retries", retries), attribute.Int("aws.throttles", throttles))
+	if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		span.SetAttributes(attribute.String("aws.request_id", requestID))
+	}
+	durationAttributes := callAttributes
+	if err != nil {
+		errorType := attribute.String("error.type", apiErrorCode(err))
+		span.SetAttributes(errorType)
+		durationAttributes = append(durationAttributes[:len(durationAttributes):len(durationAttributes)], errorType)
+		// AWS error messages often name the resource, e.g. the parameter
+		// access was denied to, so only the code is recorded
+		span.SetStatus(codes.Error, apiErrorCode(err))
+	}
+	span.End()
+
+	awsCallDuration, awsRetries, awsThrottles := awsInstruments()
+	awsCallDuration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(durationAttributes...))
+	if retries > 0 {
+		awsRetries.Add(ctx, int64(retries), metric.WithAttributes(callAttributes...))
+	}
+	if throttles > 0 {
+		awsThrottles.Add(ctx, int64(throttles), metric.WithAttributes(callAttributes...))
+	}
+	return out, metadata, err
+}
+
+// awsInstruments returns the instruments for AWS API calls from the global
+// meter provider, which returns the same instruments each time
+func awsInstruments() (metric.Float64Histogram, metric.Int64Counter, metric.Int64Counter) {
+	meter := otel.Meter(instrumentationName)
+	callDuration, err := meter.Float64Histogram("chamber.aws.call.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of AWS API calls, including retries"),
+		metric.WithExplicitBucketBoundaries(durationBounds...),
+	)
+	handleInstrumentError(err)
+	retries, err := meter.Int64Counter("chamber.aws.call.retries",
+		metric.WithUnit("{retry}"),
+		metric.WithDescription("Retried AWS API call attempts"),
+	)
+	handleInstrumentError(err)
+	throttles, err := meter.Int64Counter("chamber.aws.call.throttles",
+		metric.WithUnit("{throttle}"),
+		metric.WithDescription("Throttled AWS API call attempts"),
+	)
+	handleInstrumentError(err)
+	return callDuration, retries, throttles
+}
+
+// addTracingMiddleware adds tracingMiddleware to an AWS client's stack, after
+// the operation's metadata is set and before retries
+func addTracingMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(tracingMiddleware{}, middleware.After)
+}
+
+// apiErrorCode returns the AWS error code of err, or "other" if it isn't an
+// AWS error
+func apiErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return "other"
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startTestTracing sets global otel providers recording in memory, and
+// returns the span recorder and metric reader
+func startTestTracing(t *testing.T) (*tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	spans := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	previousTracerProvider, previousMeterProvider := otel.GetTracerProvider(), otel.GetMeterProvider()
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousTracerProvider)
+		otel.SetMeterProvider(previousMeterProvider)
+	})
+	return spans, reader
+}
+
+// spanAttribute returns the value of the attribute key of span, or nil
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) any {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.AsInterface()
+		}
+	}
+	return nil
+}
+
+// metricNames returns the names of the metrics collected by reader
+func metricNames(t *testing.T, reader *sdkmetric.ManualReader) []string {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	var names []string
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+func TestTracingStore(t *testing.T) {
+	recorder, reader := startTestTracing(t)
+	s := NewTracingStore(NewTestSSMStore(map[string]mockParameter{}), "ssm", TracingOptions{RecordNames: true})
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "chamber exec")
+	id := SecretId{Service: "app", Key: "key"}
+	require.NoError(t, s.Write(ctx, id, "secret"))
+	_, err := s.Read(ctx, id, -1)
+	require.NoError(t, err)
+	secrets, err := s.List(ctx, "app", true)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	_, err = s.Read(ctx, SecretId{Service: "app", Key: "missing"}, -1)
+	require.ErrorIs(t, err, ErrSecretNotFound)
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+	rootSpan := spans[4]
+	for _, span := range spans[:4] {
+		assert.Equal(t, rootSpan.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, "ssm", spanAttribute(span, "chamber.backend"))
+		assert.Equal(t, "app", spanAttribute(span, "chamber.service"))
+	}
+
+	assert.Equal(t, "chamber.Write", spans[0].Name())
+	assert.Equal(t, "key", spanAttribute(spans[0], "chamber.key"))
+	assert.Equal(t, "chamber.Read", spans[1].Name())
+	assert.Equal(t, "chamber.List", spans[2].Name())
+	assert.Equal(t, int64(1), spanAttribute(spans[2], "chamber.items"))
+	assert.Equal(t, "chamber.Read", spans[3].Name())
+	assert.Equal(t, "not_found", spanAttribute(spans[3], "error.type"))
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+	assert.Equal(t, ErrSecretNotFound.Error(), spans[3].Status().Description)
+
+	assert.ElementsMatch(t, []string{"chamber.store.operation.duration", "chamber.store.operation.errors"}, metricNames(t, reader))
+}
+
+func TestTracingStoreWithoutNames(t *testing.T) {
+	recorder, _ := startTestTracing(t)
+	s := NewTracingStore(NewTestSSMStore(map[string]mockParameter{}), "ssm", TracingOptions{})
+
+	id := SecretId{Service: "app", Key: "key"}
+	require.NoError(t, s.Write(context.Background(), id, "secret"))
+	_, err := s.List(context.Background(), "app", false)
+	require.NoError(t, err)
+	_, err = s.Read(context.Background(), SecretId{Service: "app", Key: "missing"}, -1)
+	require.ErrorIs(t, err, ErrSecretNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans {
+		assert.Equal(t, "ssm", spanAttribute(span, "chamber.backend"))
+		assert.Nil(t, spanAttribute(span, "chamber.service"))
+		assert.Nil(t, spanAttribute(span, "chamber.key"))
+	}
+	assert.Equal(t, "not_found", spans[2].Status().Description)
+}
+
+// sequenceHTTPClient returns each of its responses in turn
+type sequenceHTTPClient struct {
+	responses []*http.Response
+}
+
+func (c *sequenceHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/x-amz-json-1.1"}, "X-Amzn-Requestid": []string{"request-1"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder, reader := startTestTracing(t)
+
+	client := ssm.New(ssm.Options{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient: &sequenceHTTPClient{responses: []*http.Response{
+			jsonResponse(400, `{"__type":"ThrottlingException","message":"Rate exceeded"}`),
+			jsonResponse(200, `{"Parameter":{"Name":"/app/key","Value":"secret","Version":1}}`),
+		}},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.RateLimiter = ratelimit.None
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+		APIOptions: []func(*middleware.Stack) error{addTracingMiddleware},
+	})
+
+	_, err := client.GetParameter(context.Background(), &ssm.GetParameterInput{Name: aws.String("/app/key")})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "SSM.GetParameter", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, "aws-api", spanAttribute(span, "rpc.system"))
+	assert.Equal(t, "SSM", spanAttribute(span, "rpc.service"))
+	assert.Equal(t, "GetParameter", spanAttribute(span, "rpc.method"))
+	assert.Equal(t, int64(2), spanAttribute(span, "aws.attempts"))
+	assert.Equal(t, int64(1), spanAttribute(span, "aws.retries"))
+	assert.Equal(t, int64(1), spanAttribute(span, "aws.throttles"))
+	assert.Equal(t, "request-1", spanAttribute(span, "aws.request_id"))
+	require.Len(t, span.Events(), 1)
+	assert.Equal(t, "throttled", span.Events()[0].Name)
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	assert.ElementsMatch(t, []string{"chamber.aws.call.duration", "chamber.aws.call.retries", "chamber.aws.call.throttles"}, metricNames(t, reader))
+}
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "", errorClass(nil))
+	assert.Equal(t, "not_found", errorClass(ErrSecretNotFound))
+	assert.Equal(t, "throttled", errorClass(&Error{Kind: ErrThrottled}))
+	assert.Equal(t, "kms_access_denied", errorClass(&Error{Kind: ErrKMSAccessDenied}))
+	assert.Equal(t, "canceled", errorClass(context.Canceled))
+	assert.Equal(t, "other", errorClass(io.EOF))
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storage tiers for SSM parameters. Values over 4KB and parameter policies
+// need the Advanced tier.
+const (
+	TierStandard           = "Standard"
+	TierAdvanced           = "Advanced"
+	TierIntelligentTiering = "Intelligent-Tiering"
+)
+
+// Policies are SSM parameter policies. A zero field means no such policy.
+type Policies struct {
+	// ExpiresAt is when the secret is deleted.
+	ExpiresAt time.Time `json:",omitempty"`
+	// ExpirationNotification is how long before ExpiresAt to send an
+	// EventBridge notification.
+	ExpirationNotification time.Duration `json:",omitempty"`
+	// NoChangeNotification is how long after the secret was last changed to
+	// send an EventBridge notification.
+	NoChangeNotification time.Duration `json:",omitempty"`
+}
+
+// IsZero reports whether no policies are set.
+func (p Policies) IsZero() bool {
+	return p.ExpiresAt.IsZero() && p.ExpirationNotification == 0 && p.NoChangeNotification == 0
+}
+
+func (p Policies) String() string {
+	var policies []string
+	if !p.ExpiresAt.IsZero() {
+		policies = append(policies, "expires "+p.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if p.ExpirationNotification != 0 {
+		policies = append(policies, "notify "+formatPolicyDuration(p.ExpirationNotification)+" before expiry")
+	}
+	if p.NoChangeNotification != 0 {
+		policies = append(policies, "notify if unchanged for "+formatPolicyDuration(p.NoChangeNotification))
+	}
+	return strings.Join(policies, ", ")
+}
+
+// Types of SSM parameters. Only SecureString values are encrypted with KMS.
+const (
+	TypeString       = "String"
+	TypeStringList   = "StringList"
+	TypeSecureString = "SecureString"
+)
+
+// WriteOptions are the options for writing a secret beyond its value.
+type WriteOptions struct {
+	// Tags are set on new secrets.
+	Tags map[string]string
+	// Type is one of the Type constants, or empty to keep the current type
+	// or use SecureString for new secrets.
+	Type string
+	// Tier is one of the Tier constants, or empty to keep the current tier
+	// or use the default for new secrets.
+	Tier string
+	// Policies replace the secret's policies, if any are set.
+	Policies Policies
+}
+
+// OptionsWriter is implemented by stores which can write secrets with a type,
+// tier and policies.
+type OptionsWriter interface {
+	WriteWithOptions(ctx context.Context, id SecretId, value string, opts WriteOptions) error
+}
+
+// ssmPolicy is the JSON form of an SSM parameter policy
+type ssmPolicy struct {
+	Type       string            `json:"Type"`
+	Version    string            `json:"Version"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// ssmPolicies returns the JSON for the SSM parameter policies in p, or "" if
+// there are none
+func ssmPolicies(p Policies) (string, error) {
+	var policies []ssmPolicy
+	if !p.ExpiresAt.IsZero() {
+		policies = append(policies, ssmPolicy{
+			Type:       "Expiration",
+			Version:    "1.0",
+			Attributes: map[string]string{"Timestamp": p.ExpiresAt.UTC().Format(time.RFC3339)},
+		})
+	}
+	if p.ExpirationNotification != 0 {
+		n, unit, err := policyDuration(p.ExpirationNotification)
+		if err != nil {
+			return "", fmt.Errorf("invalid expiration notification: %w", err)
+		}
+		policies = append(policies, ssmPolicy{
+			Type:       "ExpirationNotification",
+			Version:    "1.0",
+			Attributes: map[string]string{"Before": n, "Unit": unit},
+		})
+	}
+	if p.NoChangeNotification != 0 {
+		n, unit, err := policyDuration(p.NoChangeNotification)
+		if err != nil {
+			return "", fmt.Errorf("invalid no change notification: %w", err)
+		}
+		policies = append(policies, ssmPolicy{
+			Type:       "NoChangeNotification",
+			Version:    "1.0",
+			Attributes: map[string]string{"After": n, "Unit": unit},
+		})
+	}
+	if len(policies) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(policies)
+	return string(b), err
+}
+
+// parseSSMPolicy adds the SSM parameter policy in text to p. Policies chamber
+// doesn't know about are ignored.
+func parseSSMPolicy(text string, p *Policies) {
+	var policy ssmPolicy
+	if err := json.Unmarshal([]byte(text), &policy); err != nil {
+		return
+	}
+	switch policy.Type {
+	case "Expiration":
+		p.ExpiresAt, _ = time.Parse(time.RFC3339, policy.Attributes["Timestamp"])
+	case "ExpirationNotification":
+		p.ExpirationNotification = parsePolicyDuration(policy.Attributes["Before"], policy.Attributes["Unit"])
+	case "NoChangeNotification":
+		p.NoChangeNotification = parsePolicyDuration(policy.Attributes["After"], policy.Attributes["Unit"])
+	}
+}
+
+// policyDuration converts d to the whole days or hours that SSM policies use
+func policyDuration(d time.Duration) (string, string, error) {
+	switch {
+	case d <= 0 || d%time.Hour != 0:
+		return "", "", fmt.Errorf("%s is not a positive whole number of hours", d)
+	case d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d / (24 * time.Hour))), "Days", nil
+	default:
+		return strconv.Itoa(int(d / time.Hour)), "Hours", nil
+	}
+}
+
+func parsePolicyDuration(n, unit string) time.Duration {
+	i, err := strconv.Atoi(n)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case "Days":
+		return time.Duration(i) * 24 * time.Hour
+	case "Hours":
+		return time.Duration(i) * time.Hour
+	}
+	return 0
+}
+
+func formatPolicyDuration(d time.Duration) string {
+	n, unit, err := policyDuration(d)
+	if err != nil {
+		return d.String()
+	}
+	return n + " " + strings.ToLower(unit)
+}
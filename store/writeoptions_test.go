@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMPolicies(t *testing.T) {
+	policies, err := ssmPolicies(Policies{})
+	require.NoError(t, err)
+	assert.Equal(t, "", policies)
+
+	policies, err = ssmPolicies(Policies{
+		ExpiresAt:              time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		ExpirationNotification: 7 * 24 * time.Hour,
+		NoChangeNotification:   12 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"Type": "Expiration", "Version": "1.0", "Attributes": {"Timestamp": "2030-01-02T03:04:05Z"}},
+		{"Type": "ExpirationNotification", "Version": "1.0", "Attributes": {"Before": "7", "Unit": "Days"}},
+		{"Type": "NoChangeNotification", "Version": "1.0", "Attributes": {"After": "12", "Unit": "Hours"}}
+	]`, policies)
+
+	_, err = ssmPolicies(Policies{NoChangeNotification: 90 * time.Minute})
+	assert.Error(t, err)
+}
+
+func TestSSMWriteWithOptions(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestSSMStore(map[string]mockParameter{})
+	id := SecretId{Service: "contractors", Key: "api_key"}
+	expiresAt := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	err := s.WriteWithOptions(ctx, id, "value", WriteOptions{
+		Policies: Policies{
+			ExpiresAt:              expiresAt,
+			ExpirationNotification: 3 * 24 * time.Hour,
+		},
+	})
+	require.NoError(t, err)
+
+	secret, err := s.Read(ctx, id, -1)
+	require.NoError(t, err)
+	assert.Equal(t, TierAdvanced, secret.Meta.Tier)
+	assert.Equal(t, expiresAt, secret.Meta.Policies.ExpiresAt)
+	assert.Equal(t, 3*24*time.Hour, secret.Meta.Policies.ExpirationNotification)
+	assert.Equal(t, "expires 2030-01-02T00:00:00Z, notify 3 days before expiry", secret.Meta.Policies.String())
+
+	// a later write without a tier keeps the parameter in the Advanced tier
+	require.NoError(t, s.Write(ctx, id, "value2"))
+	secrets, err := s.List(ctx, "contractors", false)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	assert.Equal(t, TierAdvanced, secrets[0].Meta.Tier)
+
+	err = s.WriteWithOptions(ctx, id, "value3", WriteOptions{
+		Tier:     TierStandard,
+		Policies: Policies{ExpiresAt: expiresAt},
+	})
+	assert.EqualError(t, err, "policies are not supported for the Standard tier")
+}
+
+func TestSSMWriteTypes(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestSSMStore(map[string]mockParameter{})
+	mock := s.svc.(*apiSSMMock)
+	id := SecretId{Service: "app", Key: "log_level"}
+
+	require.NoError(t, s.WriteWithOptions(ctx, id, "debug", WriteOptions{Type: TypeString}))
+	put := mock.PutParameterCalls()[len(mock.PutParameterCalls())-1].Params
+	assert.Equal(t, types.ParameterTypeString, put.Type)
+	assert.Nil(t, put.KeyId)
+
+	// updates keep the type, in single and batch writes
+	require.NoError(t, s.Write(ctx, id, "info"))
+	require.NoError(t, s.WriteMany(ctx, map[SecretId]string{id: "warn"}))
+	secret, err := s.Read(ctx, id, -1)
+	require.NoError(t, err)
+	assert.Equal(t, TypeString, secret.Meta.Type)
+	assert.Equal(t, 3, secret.Meta.Version)
+
+	// new secrets are secure strings
+	require.NoError(t, s.Write(ctx, SecretId{Service: "app", Key: "password"}, "hunter2"))
+	put = mock.PutParameterCalls()[len(mock.PutParameterCalls())-1].Params
+	assert.Equal(t, types.ParameterTypeSecureString, put.Type)
+	assert.NotNil(t, put.KeyId)
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// Backends report the version each write creates through the context, so
+// that AuditingStore can record it without reading the secret back, through
+// any decorators in between. Only the first version reported for a secret
+// counts, which is the primary's when writes are replicated.
+
+type writtenVersionsKey struct{}
+
+// writtenVersions collects the versions written during one operation
+type writtenVersions struct {
+	mu       sync.Mutex
+	versions map[SecretId]int
+}
+
+// withWrittenVersions returns a context in which backends report the versions
+// they write to the returned writtenVersions
+func withWrittenVersions(ctx context.Context) (context.Context, *writtenVersions) {
+	w := &writtenVersions{versions: map[SecretId]int{}}
+	return context.WithValue(ctx, writtenVersionsKey{}, w), w
+}
+
+// get returns the version written of id, or 0 if none was reported
+func (w *writtenVersions) get(id SecretId) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.versions[id]
+}
+
+// reportWrittenVersion reports that version of id was written, if anything
+// in ctx is asking
+func reportWrittenVersion(ctx context.Context, id SecretId, version int) {
+	w, ok := ctx.Value(writtenVersionsKey{}).(*writtenVersions)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.versions[id]; !ok {
+		w.versions[id] = version
+	}
+}
+
+// reportWrittenVersions reports each version in written, as
+// reportWrittenVersion
+func reportWrittenVersions(ctx context.Context, written map[SecretId]int) {
+	for id, version := range written {
+		reportWrittenVersion(ctx, id, version)
+	}
+}
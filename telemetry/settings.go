@@ -0,0 +1,157 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/segmentio/chamber/v3/config"
+)
+
+const (
+	// NoAnalyticsEnvVar turns analytics off when set to anything true.
+	NoAnalyticsEnvVar = "CHAMBER_NO_ANALYTICS"
+	// FileEnvVar names a file to record events to.
+	FileEnvVar = "CHAMBER_TELEMETRY_FILE"
+	// SaltFileName is the file next to the user's config file holding the
+	// salt for hashing names.
+	SaltFileName = "telemetry-salt"
+)
+
+// Settings decide where analytics go, if anywhere.
+type Settings struct {
+	// WriteKey is the Segment write key compiled into chamber, if any.
+	WriteKey string
+	// File is where events are recorded locally, if anywhere.
+	File string
+	// OptedIn is whether the config file opts in to sending analytics to
+	// Segment, which is off by default.
+	OptedIn bool
+	// Disabled is whether analytics were turned off, and DisabledBy says by
+	// what.
+	Disabled   bool
+	DisabledBy string
+	// HashNames is whether service and key names are hashed in File. They're
+	// always hashed for Segment.
+	HashNames bool
+	// ConfigFile is the config file read, which may not exist.
+	ConfigFile string
+	// SaltFile holds the salt for hashing names, or is empty to use a new
+	// salt each run.
+	SaltFile string
+}
+
+// fileConfig is the analytics section of the config file
+type fileConfig struct {
+	Analytics struct {
+		Enabled   *bool `yaml:"enabled"`
+		HashNames *bool `yaml:"hash_names"`
+	} `yaml:"analytics"`
+}
+
+// UserConfigFile returns the path of the user's chamber config file:
+// $XDG_CONFIG_HOME/chamber/config.yaml, or ~/.config/chamber/config.yaml.
+func UserConfigFile() string {
+	return config.UserFile()
+}
+
+// LoadSettings works out where analytics go, given the compiled in write key
+// and the --telemetry-file flag. Segment is only used if the config file sets
+// analytics.enabled to true. $CHAMBER_NO_ANALYTICS takes precedence over the
+// config file, and file over $CHAMBER_TELEMETRY_FILE.
+func LoadSettings(writeKey, file string) (Settings, error) {
+	settings := Settings{
+		WriteKey:   writeKey,
+		File:       file,
+		HashNames:  true,
+		ConfigFile: UserConfigFile(),
+	}
+	if settings.ConfigFile != "" {
+		settings.SaltFile = filepath.Join(filepath.Dir(settings.ConfigFile), SaltFileName)
+	}
+	if settings.File == "" {
+		settings.File = os.Getenv(FileEnvVar)
+	}
+
+	if settings.ConfigFile != "" {
+		contents, err := os.ReadFile(settings.ConfigFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return settings, fmt.Errorf("failed to read %s: %w", settings.ConfigFile, err)
+		}
+		var config fileConfig
+		if err := yaml.Unmarshal(contents, &config); err != nil {
+			return settings, fmt.Errorf("failed to parse %s: %w", settings.ConfigFile, err)
+		}
+		if config.Analytics.Enabled != nil {
+			settings.OptedIn = *config.Analytics.Enabled
+			settings.Disabled = !*config.Analytics.Enabled
+			if settings.Disabled {
+				settings.DisabledBy = settings.ConfigFile
+			}
+		}
+		if config.Analytics.HashNames != nil {
+			settings.HashNames = *config.Analytics.HashNames
+		}
+	}
+
+	if value := os.Getenv(NoAnalyticsEnvVar); value != "" {
+		noAnalytics, err := strconv.ParseBool(value)
+		if err != nil {
+			return settings, fmt.Errorf("cannot parse $%s to a boolean", NoAnalyticsEnvVar)
+		}
+		settings.Disabled = noAnalytics
+		settings.DisabledBy = ""
+		if noAnalytics {
+			settings.DisabledBy = "$" + NoAnalyticsEnvVar
+		}
+	}
+
+	return settings, nil
+}
+
+// SegmentEnabled reports whether events are sent to Segment.
+func (s Settings) SegmentEnabled() bool {
+	return !s.Disabled && s.OptedIn && s.WriteKey != ""
+}
+
+// FileEnabled reports whether events are recorded to File.
+func (s Settings) FileEnabled() bool {
+	return !s.Disabled && s.File != ""
+}
+
+// NewClient returns a client sending as set in s, on behalf of userID, or nil
+// if analytics are off.
+func (s Settings) NewClient(userID string) (*Client, error) {
+	if !s.SegmentEnabled() && !s.FileEnabled() {
+		return nil, nil
+	}
+	hasher, err := LoadHasher(s.SaltFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load salt for hashing names: %w", err)
+	}
+
+	var sinks []Sink
+	if s.SegmentEnabled() {
+		sink, err := NewSegmentSink(s.WriteKey, hasher)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if s.FileEnabled() {
+		fileSink, err := NewFileSink(s.File)
+		if err != nil {
+			return nil, err
+		}
+		var sink Sink = fileSink
+		if s.HashNames {
+			sink = NewHashingSink(sink, hasher)
+		}
+		sinks = append(sinks, sink)
+	}
+	return New(userID, sinks...), nil
+}
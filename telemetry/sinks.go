@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	analytics "github.com/segmentio/analytics-go/v3"
+)
+
+// SegmentSink sends events to Segment. Names and the user ID are always
+// hashed first, since they mustn't reach a third party as they are.
+type SegmentSink struct {
+	client analytics.Client
+	hasher *Hasher
+}
+
+var _ Sink = &SegmentSink{}
+
+// NewSegmentSink returns a sink sending to Segment with writeKey, hashing
+// names with hasher.
+func NewSegmentSink(writeKey string, hasher *Hasher) (*SegmentSink, error) {
+	if hasher == nil {
+		return nil, errors.New("names must be hashed for Segment")
+	}
+	client, err := analytics.NewWithConfig(writeKey, analytics.Config{
+		BatchSize: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SegmentSink{client: client, hasher: hasher}, nil
+}
+
+func (s *SegmentSink) Send(event Event) error {
+	event.UserID = s.hasher.Hash(event.UserID)
+	event.Properties = s.hasher.Properties(event.Properties)
+	switch event.Type {
+	case TypeIdentify:
+		return s.client.Enqueue(analytics.Identify{
+			UserId:    event.UserID,
+			Timestamp: event.Time,
+			Traits:    analytics.Traits(event.Properties),
+		})
+	default:
+		return s.client.Enqueue(analytics.Track{
+			UserId:     event.UserID,
+			Event:      event.Event,
+			Timestamp:  event.Time,
+			Properties: analytics.Properties(event.Properties),
+		})
+	}
+}
+
+func (s *SegmentSink) Close() error {
+	return s.client.Close()
+}
+
+// FileSink appends events to a file, one JSON object per line, so that what
+// chamber would send can be inspected.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ Sink = &FileSink{}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Send(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,222 @@
+// Package telemetry sends chamber's usage analytics. Events go to Segment only
+// when a write key is compiled in and the user opts in with the config file,
+// and to a local file when one is set, so that what would be sent can be
+// inspected. Service and key names are always hashed for Segment, with a
+// per-install salt, and only the local file can be set to record them as they
+// are. Analytics can be turned off with $CHAMBER_NO_ANALYTICS or the config
+// file; see LoadSettings.
+package telemetry
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Event types
+const (
+	TypeIdentify = "identify"
+	TypeTrack    = "track"
+)
+
+// saltSize is the size in bytes of the salt for hashing names
+const saltSize = 32
+
+// nameProperties are the properties holding service or key names, or who
+// performed an operation, which are hashed by a Hasher
+var nameProperties = map[string]bool{
+	"service":  true,
+	"services": true,
+	"key":      true,
+	"identity": true,
+}
+
+// Properties are the details of an event.
+type Properties map[string]any
+
+// Event is an analytics event, as sent to each sink.
+type Event struct {
+	Type   string    `json:"type"`
+	UserID string    `json:"userId"`
+	Time   time.Time `json:"timestamp"`
+	// Event is the name of a track event, e.g. "Ran Command".
+	Event string `json:"event,omitempty"`
+	// Properties are a track event's properties, or an identify event's
+	// traits.
+	Properties Properties `json:"properties,omitempty"`
+}
+
+// Sink sends events somewhere.
+type Sink interface {
+	Send(event Event) error
+	// Close sends anything buffered.
+	Close() error
+}
+
+// Client sends events to its sinks. All of its methods do nothing on a nil
+// Client, which is what New returns when analytics are off.
+type Client struct {
+	sinks  []Sink
+	userID string
+}
+
+// New returns a client sending to sinks on behalf of userID, or nil if there
+// are no sinks. Sinks hash names themselves, if they should; see
+// NewHashingSink.
+func New(userID string, sinks ...Sink) *Client {
+	if len(sinks) == 0 {
+		return nil
+	}
+	return &Client{sinks: sinks, userID: userID}
+}
+
+// Identify sends an identify event with traits.
+func (c *Client) Identify(traits Properties) {
+	if c == nil {
+		return
+	}
+	c.send(Event{Type: TypeIdentify, Properties: traits})
+}
+
+// Track sends a track event.
+func (c *Client) Track(event string, properties Properties) {
+	if c == nil {
+		return
+	}
+	c.send(Event{Type: TypeTrack, Event: event, Properties: properties})
+}
+
+// Close closes every sink.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	for _, sink := range c.sinks {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// send sends event to every sink. Analytics are best effort, so errors are
+// dropped.
+func (c *Client) send(event Event) {
+	event.UserID = c.userID
+	event.Time = time.Now().UTC()
+	for _, sink := range c.sinks {
+		_ = sink.Send(event)
+	}
+}
+
+// Hasher hashes service and key names with HMAC-SHA256, keyed by a salt
+// chosen at random for each install, so that names can't be recovered by
+// hashing guesses like "prod/db". The same name always hashes the same way on
+// one install, so events can still be grouped by name.
+type Hasher struct {
+	salt []byte
+}
+
+// NewHasher returns a hasher keyed by salt.
+func NewHasher(salt []byte) *Hasher {
+	return &Hasher{salt: salt}
+}
+
+// LoadHasher returns a hasher keyed by the salt in path, creating the file
+// with a new random salt if it doesn't exist. If path is empty, the salt is
+// random and only lasts as long as the process.
+func LoadHasher(path string) (*Hasher, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return NewHasher(salt), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		defer file.Close()
+		if _, err := file.WriteString(hex.EncodeToString(salt) + "\n"); err != nil {
+			return nil, err
+		}
+		return NewHasher(salt), nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	salt, err = hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(salt) < saltSize {
+		return nil, fmt.Errorf("invalid salt in %s", path)
+	}
+	return NewHasher(salt), nil
+}
+
+// Hash returns a one-way hash of a service or key name.
+func (h *Hasher) Hash(name string) string {
+	mac := hmac.New(sha256.New, h.salt)
+	mac.Write([]byte(name))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Properties returns a copy of properties with names hashed.
+func (h *Hasher) Properties(properties Properties) Properties {
+	if properties == nil {
+		return nil
+	}
+	hashed := make(Properties, len(properties))
+	for key, value := range properties {
+		if !nameProperties[key] {
+			hashed[key] = value
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			hashed[key] = h.Hash(v)
+		case []string:
+			names := make([]string, len(v))
+			for i, name := range v {
+				names[i] = h.Hash(name)
+			}
+			hashed[key] = names
+		default:
+			// never send a name property which can't be hashed
+			hashed[key] = nil
+		}
+	}
+	return hashed
+}
+
+// HashingSink hashes the names in events before sending them to another sink.
+type HashingSink struct {
+	Sink
+	hasher *Hasher
+}
+
+var _ Sink = &HashingSink{}
+
+// NewHashingSink returns a sink hashing names with hasher before sending
+// events to sink.
+func NewHashingSink(sink Sink, hasher *Hasher) *HashingSink {
+	return &HashingSink{Sink: sink, hasher: hasher}
+}
+
+func (s *HashingSink) Send(event Event) error {
+	event.Properties = s.hasher.Properties(event.Properties)
+	return s.Sink.Send(event)
+}
@@ -0,0 +1,300 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	analytics "github.com/segmentio/analytics-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink collects events in memory
+type memorySink struct {
+	events []Event
+	closed bool
+}
+
+func (s *memorySink) Send(event Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+var testHasher = NewHasher([]byte("salt"))
+
+func TestClient(t *testing.T) {
+	t.Run("names are hashed", func(t *testing.T) {
+		sink := &memorySink{}
+		c := New("alice", NewHashingSink(sink, testHasher))
+		c.Track("Ran Command", Properties{
+			"command":  "exec",
+			"service":  "app/prod",
+			"services": []string{"app", "app/prod"},
+			"key":      "db_password",
+		})
+		require.NoError(t, c.Close())
+
+		require.Len(t, sink.events, 1)
+		event := sink.events[0]
+		assert.Equal(t, TypeTrack, event.Type)
+		assert.Equal(t, "alice", event.UserID)
+		assert.Equal(t, "Ran Command", event.Event)
+		assert.Equal(t, "exec", event.Properties["command"])
+		assert.Equal(t, testHasher.Hash("app/prod"), event.Properties["service"])
+		assert.Equal(t, []string{testHasher.Hash("app"), testHasher.Hash("app/prod")}, event.Properties["services"])
+		assert.Equal(t, testHasher.Hash("db_password"), event.Properties["key"])
+		assert.True(t, sink.closed)
+	})
+
+	t.Run("names can be sent as they are", func(t *testing.T) {
+		sink := &memorySink{}
+		New("alice", sink).Track("Ran Command", Properties{"service": "app"})
+		assert.Equal(t, "app", sink.events[0].Properties["service"])
+	})
+
+	t.Run("names of unexpected types are dropped", func(t *testing.T) {
+		sink := &memorySink{}
+		New("alice", NewHashingSink(sink, testHasher)).Track("Ran Command", Properties{"key": 42})
+		assert.Nil(t, sink.events[0].Properties["key"])
+	})
+
+	t.Run("no sinks", func(t *testing.T) {
+		c := New("alice")
+		assert.Nil(t, c)
+		// none of these should panic
+		c.Identify(nil)
+		c.Track("Ran Command", nil)
+		assert.NoError(t, c.Close())
+	})
+}
+
+func TestHasher(t *testing.T) {
+	assert.Equal(t, testHasher.Hash("app"), testHasher.Hash("app"))
+	assert.NotEqual(t, testHasher.Hash("app"), testHasher.Hash("app2"))
+	assert.Regexp(t, `^hmac-sha256:[0-9a-f]{32}$`, testHasher.Hash("app"))
+	// without the salt, a name can't be found by hashing guesses
+	assert.NotEqual(t, testHasher.Hash("app"), NewHasher([]byte("other")).Hash("app"))
+
+	t.Run("the salt is kept for the install", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chamber", SaltFileName)
+		first, err := LoadHasher(path)
+		require.NoError(t, err)
+		second, err := LoadHasher(path)
+		require.NoError(t, err)
+		assert.Equal(t, first.Hash("app"), second.Hash("app"))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("invalid salts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), SaltFileName)
+		require.NoError(t, os.WriteFile(path, []byte("abc\n"), 0600))
+		_, err := LoadHasher(path)
+		assert.EqualError(t, err, "invalid salt in "+path)
+	})
+
+	t.Run("no salt file", func(t *testing.T) {
+		first, err := LoadHasher("")
+		require.NoError(t, err)
+		second, err := LoadHasher("")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Hash("app"), second.Hash("app"))
+	})
+}
+
+// memorySegmentClient collects messages meant for Segment in memory
+type memorySegmentClient struct {
+	messages []analytics.Message
+}
+
+func (c *memorySegmentClient) Enqueue(message analytics.Message) error {
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *memorySegmentClient) Close() error {
+	return nil
+}
+
+func TestSegmentSink(t *testing.T) {
+	client := &memorySegmentClient{}
+	sink := &SegmentSink{client: client, hasher: testHasher}
+	New("alice", sink).Track("Ran Command", Properties{"command": "read", "service": "app"})
+
+	require.Len(t, client.messages, 1)
+	track := client.messages[0].(analytics.Track)
+	assert.Equal(t, testHasher.Hash("alice"), track.UserId)
+	assert.Equal(t, "read", track.Properties["command"])
+	assert.Equal(t, testHasher.Hash("app"), track.Properties["service"])
+
+	_, err := NewSegmentSink("key", nil)
+	assert.EqualError(t, err, "names must be hashed for Segment")
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	c := New("alice", NewHashingSink(sink, testHasher))
+	c.Identify(Properties{"chamber-version": "v3.0.0"})
+	c.Track("Ran Command", Properties{"command": "read", "service": "app"})
+	require.NoError(t, c.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, TypeIdentify, events[0].Type)
+	assert.Equal(t, "v3.0.0", events[0].Properties["chamber-version"])
+	assert.Equal(t, TypeTrack, events[1].Type)
+	assert.Equal(t, testHasher.Hash("app"), events[1].Properties["service"])
+}
+
+func TestLoadSettings(t *testing.T) {
+	writeConfig := func(t *testing.T, contents string) string {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		path := filepath.Join(dir, "chamber", "config.yaml")
+		if contents != "" {
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+			require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		}
+		return path
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		path := writeConfig(t, "")
+		t.Setenv(NoAnalyticsEnvVar, "")
+		t.Setenv(FileEnvVar, "")
+
+		settings, err := LoadSettings("key", "")
+		require.NoError(t, err)
+		assert.False(t, settings.Disabled)
+		assert.False(t, settings.OptedIn)
+		assert.True(t, settings.HashNames)
+		assert.False(t, settings.SegmentEnabled())
+		assert.False(t, settings.FileEnabled())
+		assert.Equal(t, path, settings.ConfigFile)
+		assert.Equal(t, filepath.Join(filepath.Dir(path), SaltFileName), settings.SaltFile)
+
+		client, err := settings.NewClient("alice")
+		require.NoError(t, err)
+		assert.Nil(t, client)
+
+		settings, err = LoadSettings("", "")
+		require.NoError(t, err)
+		assert.False(t, settings.SegmentEnabled())
+	})
+
+	t.Run("the file can come from the environment", func(t *testing.T) {
+		writeConfig(t, "")
+		t.Setenv(NoAnalyticsEnvVar, "")
+		t.Setenv(FileEnvVar, "/tmp/from-env")
+
+		settings, err := LoadSettings("", "")
+		require.NoError(t, err)
+		assert.True(t, settings.FileEnabled())
+		assert.Equal(t, "/tmp/from-env", settings.File)
+
+		settings, err = LoadSettings("", "/tmp/from-flag")
+		require.NoError(t, err)
+		assert.Equal(t, "/tmp/from-flag", settings.File)
+	})
+
+	t.Run("opting in to Segment with the config file", func(t *testing.T) {
+		writeConfig(t, "analytics:\n  enabled: true\n  hash_names: false\n")
+		t.Setenv(NoAnalyticsEnvVar, "")
+
+		settings, err := LoadSettings("key", "")
+		require.NoError(t, err)
+		assert.True(t, settings.OptedIn)
+		assert.True(t, settings.SegmentEnabled())
+		assert.False(t, settings.HashNames)
+
+		settings, err = LoadSettings("", "")
+		require.NoError(t, err)
+		assert.False(t, settings.SegmentEnabled())
+	})
+
+	t.Run("only the file may have names as they are", func(t *testing.T) {
+		writeConfig(t, "analytics:\n  hash_names: false\n")
+		t.Setenv(NoAnalyticsEnvVar, "")
+		file := filepath.Join(t.TempDir(), "telemetry.jsonl")
+
+		settings, err := LoadSettings("", file)
+		require.NoError(t, err)
+		client, err := settings.NewClient("alice")
+		require.NoError(t, err)
+		client.Track("Ran Command", Properties{"service": "app"})
+		require.NoError(t, client.Close())
+
+		contents, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), `"service":"app"`)
+	})
+
+	t.Run("opting out with the environment", func(t *testing.T) {
+		writeConfig(t, "analytics:\n  enabled: true\n")
+		t.Setenv(NoAnalyticsEnvVar, "1")
+
+		settings, err := LoadSettings("key", "/tmp/file")
+		require.NoError(t, err)
+		assert.True(t, settings.Disabled)
+		assert.Equal(t, "$CHAMBER_NO_ANALYTICS", settings.DisabledBy)
+		assert.False(t, settings.SegmentEnabled())
+		assert.False(t, settings.FileEnabled())
+
+		client, err := settings.NewClient("alice")
+		require.NoError(t, err)
+		assert.Nil(t, client)
+	})
+
+	t.Run("opting out with the config file", func(t *testing.T) {
+		path := writeConfig(t, "analytics:\n  enabled: false\n  hash_names: false\n")
+		t.Setenv(NoAnalyticsEnvVar, "")
+
+		settings, err := LoadSettings("key", "")
+		require.NoError(t, err)
+		assert.True(t, settings.Disabled)
+		assert.Equal(t, path, settings.DisabledBy)
+		assert.False(t, settings.HashNames)
+
+		// the environment takes precedence, but doesn't opt in to Segment
+		t.Setenv(NoAnalyticsEnvVar, "false")
+		settings, err = LoadSettings("key", "")
+		require.NoError(t, err)
+		assert.False(t, settings.Disabled)
+		assert.False(t, settings.SegmentEnabled())
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		writeConfig(t, "analytics: [")
+		t.Setenv(NoAnalyticsEnvVar, "")
+		_, err := LoadSettings("key", "")
+		assert.Error(t, err)
+
+		writeConfig(t, "")
+		t.Setenv(NoAnalyticsEnvVar, "maybe")
+		_, err = LoadSettings("key", "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,188 @@
+// Package tracing sets up OpenTelemetry tracing and metrics for chamber, with
+// the OpenTelemetry SDK and its OTLP exporters, from the standard OTEL_*
+// environment variables. Instrumented code uses the OpenTelemetry API, via
+// otel.Tracer and otel.Meter, which records nothing until Start sets up the
+// global providers.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultServiceName is the service.name used unless OTEL_SERVICE_NAME or
+// OTEL_RESOURCE_ATTRIBUTES set one.
+const DefaultServiceName = "chamber"
+
+// OTLP protocols, as set with OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolGRPC         = "grpc"
+)
+
+// signal describes the environment variables for traces or metrics
+type signal struct {
+	name string
+}
+
+var (
+	traces  = signal{name: "TRACES"}
+	metrics = signal{name: "METRICS"}
+)
+
+// enabled reports whether the signal is exported: an endpoint must be set,
+// so that chamber doesn't try to reach a collector which isn't there, and
+// OTEL_<SIGNAL>_EXPORTER mustn't be none.
+func (s signal) enabled() (bool, error) {
+	switch exporter := os.Getenv("OTEL_" + s.name + "_EXPORTER"); exporter {
+	case "none":
+		return false, nil
+	case "", "otlp":
+	default:
+		return false, fmt.Errorf("unsupported OTEL_%s_EXPORTER %s: only otlp and none are supported", s.name, exporter)
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_"+s.name+"_ENDPOINT") != "", nil
+}
+
+// protocol returns the OTLP protocol for the signal, by default
+// http/protobuf, as in the OpenTelemetry specification
+func (s signal) protocol() (string, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + s.name + "_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "":
+		return ProtocolHTTPProtobuf, nil
+	case ProtocolHTTPProtobuf, ProtocolGRPC:
+		return protocol, nil
+	}
+	return "", fmt.Errorf("unsupported OTLP protocol %s: only %s and %s are supported", protocol, ProtocolHTTPProtobuf, ProtocolGRPC)
+}
+
+// Tracing holds the providers set up by Start.
+type Tracing struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+}
+
+// Start sets up the global tracer and meter providers from the OTEL_*
+// environment variables, and returns them so they can be shut down. It
+// returns nil if OTEL_SDK_DISABLED is true, or no endpoint is set.
+//
+// The exporters read the endpoint, headers, timeout, compression and TLS
+// settings themselves, and the SDK reads OTEL_BSP_* and
+// OTEL_METRIC_EXPORT_INTERVAL, so every standard variable for those applies.
+func Start(ctx context.Context) (*Tracing, error) {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return nil, nil
+	}
+	tracesEnabled, err := traces.enabled()
+	if err != nil {
+		return nil, err
+	}
+	metricsEnabled, err := metrics.enabled()
+	if err != nil {
+		return nil, err
+	}
+	if !tracesEnabled && !metricsEnabled {
+		return nil, nil
+	}
+	// export errors are logged at debug level, rather than to stderr, since
+	// the collector being unreachable shouldn't clutter chamber's output
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Debug(fmt.Sprintf("otel: %s", err))
+	}))
+
+	// later detectors take precedence, so the environment can rename the
+	// service
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", DefaultServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource: %w", err)
+	}
+
+	t := &Tracing{}
+	if tracesEnabled {
+		exporter, err := newTraceExporter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.tracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(res),
+		)
+		otel.SetTracerProvider(t.tracerProvider)
+	}
+	if metricsEnabled {
+		exporter, err := newMetricExporter(ctx)
+		if err != nil {
+			return nil, errors.Join(err, t.Shutdown(ctx))
+		}
+		t.meterProvider = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+			sdkmetric.WithResource(res),
+		)
+		otel.SetMeterProvider(t.meterProvider)
+	}
+	return t, nil
+}
+
+func newTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol, err := traces.protocol()
+	if err != nil {
+		return nil, err
+	}
+	if protocol == ProtocolGRPC {
+		return otlptracegrpc.New(ctx)
+	}
+	return otlptracehttp.New(ctx)
+}
+
+func newMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	protocol, err := metrics.protocol()
+	if err != nil {
+		return nil, err
+	}
+	if protocol == ProtocolGRPC {
+		return otlpmetricgrpc.New(ctx)
+	}
+	return otlpmetrichttp.New(ctx)
+}
+
+// Shutdown exports everything recorded and stops the providers. It does
+// nothing on a nil Tracing.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	var errs []error
+	if t.tracerProvider != nil {
+		if err := t.tracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to export spans: %w", err))
+		}
+	}
+	if t.meterProvider != nil {
+		if err := t.meterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to export metrics: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process OTLP/HTTP collector
+type collector struct {
+	*httptest.Server
+	mu      sync.Mutex
+	traces  []*collectortrace.ExportTraceServiceRequest
+	metrics []*collectormetrics.ExportMetricsServiceRequest
+	headers []http.Header
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		c.headers = append(c.headers, r.Header)
+		switch r.URL.Path {
+		case "/v1/traces":
+			traces := &collectortrace.ExportTraceServiceRequest{}
+			assert.NoError(t, proto.Unmarshal(body, traces))
+			c.traces = append(c.traces, traces)
+		case "/v1/metrics":
+			metrics := &collectormetrics.ExportMetricsServiceRequest{}
+			assert.NoError(t, proto.Unmarshal(body, metrics))
+			c.metrics = append(c.metrics, metrics)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// resetGlobals restores the global providers after a test
+func resetGlobals(t *testing.T) {
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracenoop.NewTracerProvider())
+		otel.SetMeterProvider(noop.NewMeterProvider())
+	})
+}
+
+func TestStart(t *testing.T) {
+	t.Run("exports to an OTLP collector", func(t *testing.T) {
+		resetGlobals(t)
+		c := newCollector(t)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", c.URL)
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=secret%20key")
+		t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=test")
+
+		tr, err := Start(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, tr)
+
+		ctx, span := otel.Tracer("test").Start(context.Background(), "chamber exec")
+		counter, err := otel.Meter("test").Int64Counter("chamber.test")
+		require.NoError(t, err)
+		counter.Add(ctx, 1)
+		span.End()
+		require.NoError(t, tr.Shutdown(context.Background()))
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		require.Len(t, c.traces, 1)
+		resourceSpans := c.traces[0].ResourceSpans[0]
+		resource := map[string]string{}
+		for _, kv := range resourceSpans.Resource.Attributes {
+			resource[kv.Key] = kv.Value.GetStringValue()
+		}
+		assert.Equal(t, DefaultServiceName, resource["service.name"])
+		assert.Equal(t, "test", resource["deployment.environment"])
+		assert.Equal(t, "chamber exec", resourceSpans.ScopeSpans[0].Spans[0].Name)
+
+		require.Len(t, c.metrics, 1)
+		assert.Equal(t, "chamber.test", c.metrics[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+		for _, header := range c.headers {
+			assert.Equal(t, "secret key", header.Get("x-api-key"))
+		}
+	})
+
+	t.Run("signals can be turned off", func(t *testing.T) {
+		resetGlobals(t)
+		c := newCollector(t)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", c.URL)
+		t.Setenv("OTEL_METRICS_EXPORTER", "none")
+		t.Setenv("OTEL_SERVICE_NAME", "web")
+
+		tr, err := Start(context.Background())
+		require.NoError(t, err)
+		_, span := otel.Tracer("test").Start(context.Background(), "chamber read")
+		span.End()
+		require.NoError(t, tr.Shutdown(context.Background()))
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		assert.Len(t, c.traces, 1)
+		assert.Empty(t, c.metrics)
+	})
+
+	t.Run("off without an endpoint, or when disabled", func(t *testing.T) {
+		resetGlobals(t)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		tr, err := Start(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, tr)
+		assert.NoError(t, tr.Shutdown(context.Background()))
+
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+		t.Setenv("OTEL_SDK_DISABLED", "true")
+		tr, err = Start(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, tr)
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		resetGlobals(t)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4317")
+		t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+		t.Setenv("OTEL_METRICS_EXPORTER", "none")
+		tr, err := Start(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, tr)
+		// nothing was recorded, so nothing is sent
+		assert.NoError(t, tr.Shutdown(context.Background()))
+	})
+
+	t.Run("unsupported settings", func(t *testing.T) {
+		resetGlobals(t)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/json")
+		_, err := Start(context.Background())
+		assert.EqualError(t, err, "unsupported OTLP protocol http/json: only http/protobuf and grpc are supported")
+
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+		t.Setenv("OTEL_TRACES_EXPORTER", "console")
+		_, err = Start(context.Background())
+		assert.EqualError(t, err, "unsupported OTEL_TRACES_EXPORTER console: only otlp and none are supported")
+	})
+}
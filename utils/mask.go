@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sync"
+)
+
+// OutputMask replaces secret values in masked output.
+const OutputMask = "****"
+
+// MaskPatterns returns the values at least minLength long, along with their
+// base64 and URL-encoded forms, for NewMaskingWriter.
+func MaskPatterns(values []string, minLength int) []string {
+	seen := map[string]bool{}
+	var patterns []string
+	for _, value := range values {
+		if len(value) < minLength {
+			continue
+		}
+		for _, pattern := range []string{
+			value,
+			base64.StdEncoding.EncodeToString([]byte(value)),
+			base64.RawStdEncoding.EncodeToString([]byte(value)),
+			base64.URLEncoding.EncodeToString([]byte(value)),
+			base64.RawURLEncoding.EncodeToString([]byte(value)),
+			url.QueryEscape(value),
+			url.PathEscape(value),
+		} {
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	return patterns
+}
+
+// MaskingWriter replaces every occurrence of a set of patterns in what's
+// written through it with OutputMask. Bytes which might be the start of a
+// pattern are held back until the pattern is ruled out, so patterns split
+// across writes are still masked; Flush writes whatever is held back.
+// Overlapping and adjacent matches are masked together.
+type MaskingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	m       *matcher
+	state   int
+	pending []byte
+	// offset is the position in the stream of pending[0]
+	offset int
+	// masks are the merged, ordered regions of the stream to mask which
+	// haven't been written yet
+	masks []span
+	// inMask is whether the last byte written was masked, so that a region
+	// split across flushes is masked once
+	inMask bool
+}
+
+type span struct {
+	start, end int
+}
+
+// NewMaskingWriter returns a MaskingWriter writing to w.
+func NewMaskingWriter(w io.Writer, patterns []string) *MaskingWriter {
+	return &MaskingWriter{w: w, m: newMatcher(patterns)}
+}
+
+// Write masks p and writes what it can to the underlying writer.
+func (mw *MaskingWriter) Write(p []byte) (int, error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	for _, b := range p {
+		mw.state = mw.m.next(mw.state, b)
+		mw.pending = append(mw.pending, b)
+		end := mw.offset + len(mw.pending)
+		for _, length := range mw.m.nodes[mw.state].matches {
+			mw.addMask(end-length, end)
+		}
+	}
+
+	// anything before the longest partial match can be written
+	end := mw.offset + len(mw.pending)
+	if err := mw.flush(end - mw.m.nodes[mw.state].depth); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes everything held back, ending any partial match.
+func (mw *MaskingWriter) Flush() error {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	mw.state = 0
+	return mw.flush(mw.offset + len(mw.pending))
+}
+
+func (mw *MaskingWriter) addMask(start, end int) {
+	for len(mw.masks) > 0 && mw.masks[len(mw.masks)-1].end >= start {
+		last := mw.masks[len(mw.masks)-1]
+		start = min(start, last.start)
+		end = max(end, last.end)
+		mw.masks = mw.masks[:len(mw.masks)-1]
+	}
+	mw.masks = append(mw.masks, span{start: start, end: end})
+}
+
+// flush writes the pending bytes before upto, masking them as needed
+func (mw *MaskingWriter) flush(upto int) error {
+	if upto <= mw.offset {
+		return nil
+	}
+
+	out := make([]byte, 0, upto-mw.offset)
+	for i := mw.offset; i < upto; i++ {
+		for len(mw.masks) > 0 && mw.masks[0].end <= i {
+			mw.masks = mw.masks[1:]
+		}
+		if len(mw.masks) > 0 && mw.masks[0].start <= i {
+			if !mw.inMask {
+				out = append(out, OutputMask...)
+				mw.inMask = true
+			}
+			continue
+		}
+		mw.inMask = false
+		out = append(out, mw.pending[i-mw.offset])
+	}
+
+	mw.pending = append(mw.pending[:0], mw.pending[upto-mw.offset:]...)
+	mw.offset = upto
+	_, err := mw.w.Write(out)
+	return err
+}
+
+// matcher is an Aho-Corasick automaton, which finds every occurrence of a set
+// of patterns in one pass over its input
+type matcher struct {
+	nodes []matcherNode
+}
+
+type matcherNode struct {
+	children map[byte]int
+	fail     int
+	// depth is the length of the prefix this node matches
+	depth int
+	// matches are the lengths of the patterns ending at this node
+	matches []int
+}
+
+func newMatcher(patterns []string) *matcher {
+	m := &matcher{nodes: []matcherNode{{children: map[byte]int{}}}}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		node := 0
+		for i := 0; i < len(pattern); i++ {
+			child, ok := m.nodes[node].children[pattern[i]]
+			if !ok {
+				child = len(m.nodes)
+				m.nodes = append(m.nodes, matcherNode{children: map[byte]int{}, depth: i + 1})
+				m.nodes[node].children[pattern[i]] = child
+			}
+			node = child
+		}
+		m.nodes[node].matches = append(m.nodes[node].matches, len(pattern))
+	}
+
+	// link each node to the longest proper suffix of its prefix which is also
+	// a prefix, breadth first so that shorter prefixes are linked first
+	queue := []int{}
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range m.nodes[node].children {
+			m.nodes[child].fail = m.next(m.nodes[node].fail, b)
+			m.nodes[child].matches = append(m.nodes[child].matches, m.nodes[m.nodes[child].fail].matches...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// next returns the node reached from node on b
+func (m *matcher) next(node int, b byte) int {
+	for {
+		if child, ok := m.nodes[node].children[b]; ok {
+			return child
+		}
+		if node == 0 {
+			return 0
+		}
+		node = m.nodes[node].fail
+	}
+}
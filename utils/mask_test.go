@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func maskAll(t *testing.T, patterns []string, writes ...string) string {
+	var buf bytes.Buffer
+	mw := NewMaskingWriter(&buf, patterns)
+	for _, w := range writes {
+		n, err := mw.Write([]byte(w))
+		require.NoError(t, err)
+		assert.Equal(t, len(w), n)
+	}
+	require.NoError(t, mw.Flush())
+	return buf.String()
+}
+
+func TestMaskingWriter(t *testing.T) {
+	patterns := []string{"hunter22", "hunter22-longer", "abcabd"}
+
+	cases := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"no match", []string{"hello world\n"}, "hello world\n"},
+		{"match", []string{"password=hunter22\n"}, "password=****\n"},
+		{"many matches", []string{"hunter22 and hunter22"}, "**** and ****"},
+		{"split across writes", []string{"pass", "word=hun", "ter", "22\n"}, "password=****\n"},
+		{"byte at a time", []string{"x", "h", "u", "n", "t", "e", "r", "2", "2", "y"}, "x****y"},
+		{"longer pattern wins", []string{"hunter22-longer!"}, "****!"},
+		{"partial match is written", []string{"hunter2", "3"}, "hunter23"},
+		{"partial match is flushed", []string{"hunte"}, "hunte"},
+		{"overlapping prefix", []string{"abcabcabd"}, "abc****"},
+		{"adjacent matches", []string{"hunter22hunter22"}, "****"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, maskAll(t, patterns, tc.writes...))
+		})
+	}
+}
+
+func TestMaskingWriterHoldsBackPartialMatches(t *testing.T) {
+	var buf bytes.Buffer
+	mw := NewMaskingWriter(&buf, []string{"hunter22"})
+	_, err := mw.Write([]byte("ok hunt"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok ", buf.String())
+	_, err = mw.Write([]byte("er22 ok"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok **** ok", buf.String())
+}
+
+func TestMaskPatterns(t *testing.T) {
+	patterns := MaskPatterns([]string{"a b/c?=", "abc"}, 4)
+	assert.Contains(t, patterns, "a b/c?=")
+	assert.Contains(t, patterns, base64.StdEncoding.EncodeToString([]byte("a b/c?=")))
+	assert.Contains(t, patterns, base64.RawURLEncoding.EncodeToString([]byte("a b/c?=")))
+	assert.Contains(t, patterns, url.QueryEscape("a b/c?="))
+	assert.Contains(t, patterns, url.PathEscape("a b/c?="))
+	assert.NotContains(t, patterns, "abc")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("a b/c?="))
+	assert.Equal(t, "token ****\n", maskAll(t, patterns, "token "+encoded[:3], encoded[3:]+"\n"))
+}
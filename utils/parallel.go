@@ -0,0 +1,30 @@
+package utils
+
+import "sync"
+
+// DefaultConcurrency is the default number of calls Parallel makes at once
+const DefaultConcurrency = 4
+
+// Parallel calls fn once for every i in [0, n), with at most concurrency calls
+// running at once, and returns when they have all finished. A concurrency
+// below 1 runs the calls one at a time.
+func Parallel(n int, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallel(t *testing.T) {
+	testCases := []struct {
+		n           int
+		concurrency int
+		maxRunning  int32
+	}{
+		{10, 3, 3},
+		{10, 1, 1},
+		{10, 0, 1},
+		{2, 5, 2},
+		{0, 5, 0},
+	}
+
+	for _, testCase := range testCases {
+		var running, maxRunning int32
+		results := make([]int, testCase.n)
+		Parallel(testCase.n, testCase.concurrency, func(i int) {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if now <= max || atomic.CompareAndSwapInt32(&maxRunning, max, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			results[i] = i * i
+			atomic.AddInt32(&running, -1)
+		})
+
+		assert.LessOrEqual(t, maxRunning, testCase.maxRunning)
+		for i, result := range results {
+			assert.Equal(t, i*i, result)
+		}
+	}
+}